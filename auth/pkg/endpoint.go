@@ -9,6 +9,14 @@ import (
 )
 
 func makeVerifyEndpoint(svc Service) endpoint.Endpoint {
+	return verifyEndpoint(svc.Verify)
+}
+
+func makeVerifyFeedEndpoint(svc Service) endpoint.Endpoint {
+	return verifyEndpoint(svc.VerifyFeed)
+}
+
+func verifyEndpoint(verify func(string) (*User, Error)) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
 		token, ok := ctx.Value(jwt.JWTContextKey).(string)
 		if !ok {
@@ -18,7 +26,7 @@ func makeVerifyEndpoint(svc Service) endpoint.Endpoint {
 				"could not find token in authorization header",
 			)}, nil
 		}
-		user, err := svc.Verify(token)
+		user, err := verify(token)
 		return VerifyResponse{user, err}, nil
 	}
 }
@@ -59,3 +67,27 @@ type LoginResponse struct {
 	Token   string `json:"token,omitempty"`
 	Refresh string `json:"refresh_token,omitempty"`
 }
+
+func makeFeedTokenEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		token, ok := ctx.Value(jwt.JWTContextKey).(string)
+		if !ok {
+			return nil, NewError(
+				http.StatusUnauthorized,
+				"Empty authorization token",
+				"could not find token in authorization header",
+			)
+		}
+
+		feed, err := svc.IssueFeedToken(token)
+		if err != nil {
+			return nil, err
+		}
+
+		return FeedTokenResponse{feed}, nil
+	}
+}
+
+type FeedTokenResponse struct {
+	Token string `json:"token"`
+}
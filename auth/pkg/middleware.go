@@ -37,3 +37,25 @@ func (l *loggingService) Verify(token string) (user *User, err Error) {
 	}()
 	return l.next.Verify(token)
 }
+
+func (l *loggingService) IssueFeedToken(token string) (feed string, err Error) {
+	defer func() {
+		l.logger.Log(
+			"method", "IssueFeedToken",
+			"token", token,
+			"err", err,
+		)
+	}()
+	return l.next.IssueFeedToken(token)
+}
+
+func (l *loggingService) VerifyFeed(token string) (user *User, err Error) {
+	defer func() {
+		l.logger.Log(
+			"method", "VerifyFeed",
+			"user", user,
+			"err", err,
+		)
+	}()
+	return l.next.VerifyFeed(token)
+}
@@ -17,9 +17,14 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 )
 
+// RoleManager may authorize what goes beyond the rules, like renting over
+// the credit limit of a customer
+const RoleManager = "manager"
+
 type User struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Roles []string `json:"roles,omitempty"`
 }
 
 type AuthResponse struct {
@@ -34,6 +39,12 @@ type Service interface {
 
 	// Validates and verifies token
 	Verify(token string) (*User, Error)
+
+	// Issues a long-lived token that can only read the occupancy calendar
+	IssueFeedToken(token string) (string, Error)
+
+	// Validates and verifies feed token
+	VerifyFeed(token string) (*User, Error)
 }
 
 type service struct {
@@ -53,10 +64,11 @@ func (s *service) Login(username, pass string) (*AuthResponse, Error) {
 		)
 	}
 
-	user := &User{ID: "aK0o3", Name: "John Doe"}
+	user := &User{ID: "aK0o3", Name: "John Doe", Roles: []string{RoleManager}}
 
 	token, err := s.tokenGen.Sign(
 		user,
+		AudienceRenting,
 		time.Now().Add(time.Hour),
 		os.Getenv(JWT_SIGN_SECRET_ENV),
 	)
@@ -71,6 +83,7 @@ func (s *service) Login(username, pass string) (*AuthResponse, Error) {
 
 	refreshToken, err := s.tokenGen.Sign(
 		user,
+		AudienceRenting,
 		time.Now().AddDate(1, 0, 0),
 		os.Getenv(JWT_REFRESH_SECRET_ENV),
 	)
@@ -87,6 +100,40 @@ func (s *service) Login(username, pass string) (*AuthResponse, Error) {
 }
 
 func (s *service) Verify(tokenStr string) (*User, Error) {
+	return s.verify(tokenStr, AudienceRenting)
+}
+
+// Feed tokens are meant for calendar apps, which keep them in the feed url,
+// so they are only accepted by the calendar feed
+func (s *service) IssueFeedToken(tokenStr string) (string, Error) {
+	user, err := s.Verify(tokenStr)
+	if err != nil {
+		return "", err
+	}
+
+	token, signErr := s.tokenGen.Sign(
+		user,
+		AudienceFeed,
+		time.Now().AddDate(1, 0, 0),
+		os.Getenv(JWT_SIGN_SECRET_ENV),
+	)
+
+	if signErr != nil {
+		return "", NewError(
+			http.StatusInternalServerError,
+			"could not generate token",
+			"something went wrong while generating token, please try again",
+		)
+	}
+
+	return token, nil
+}
+
+func (s *service) VerifyFeed(tokenStr string) (*User, Error) {
+	return s.verify(tokenStr, AudienceFeed)
+}
+
+func (s *service) verify(tokenStr, audience string) (*User, Error) {
 	token, err := s.tokenGen.Verify(tokenStr, os.Getenv(JWT_SIGN_SECRET_ENV))
 	if err != nil {
 		return nil, NewError(
@@ -96,7 +143,7 @@ func (s *service) Verify(tokenStr string) (*User, Error) {
 		)
 	}
 
-	if !token.IsValid() {
+	if !token.IsValid(audience) {
 		return nil, NewError(
 			http.StatusUnauthorized,
 			"invalid token",
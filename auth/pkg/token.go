@@ -9,17 +9,25 @@ import (
 
 var ErrTokenExpired = jwt.ErrTokenExpired
 
+const (
+	// Audience of the tokens that access renting
+	AudienceRenting = "renting"
+
+	// Audience of the tokens that only read the occupancy calendar
+	AudienceFeed = "renting.feed"
+)
+
 type Token interface {
-	// Validates token
-	IsValid() bool
+	// Validates token for audience
+	IsValid(audience string) bool
 
 	// Get token's associated user
 	GetUser() *User
 }
 
 type TokenGenerator interface {
-	// Generates a token
-	Sign(user *User, exp time.Time, secret string) (string, error)
+	// Generates a token for audience
+	Sign(user *User, audience string, exp time.Time, secret string) (string, error)
 
 	// Validates token
 	Verify(token, secret string) (Token, error)
@@ -31,9 +39,9 @@ type jwtToken struct {
 }
 
 // Validates token issuer and audience
-func (t *jwtToken) IsValid() bool {
+func (t *jwtToken) IsValid(audience string) bool {
 	claims := t.token.Claims.(jwt.MapClaims)
-	return claims.VerifyAudience("renting", true) && claims.VerifyIssuer("auth", true)
+	return claims.VerifyAudience(audience, true) && claims.VerifyIssuer("auth", true)
 }
 
 // Returns token payload
@@ -60,9 +68,9 @@ func NewTokenGenerator() TokenGenerator {
 	return &jwtGenerator{}
 }
 
-func (t *jwtGenerator) Sign(user *User, exp time.Time, secret string) (string, error) {
+func (t *jwtGenerator) Sign(user *User, audience string, exp time.Time, secret string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"aud":  "renting",
+		"aud":  audience,
 		"sub":  user.ID,
 		"exp":  exp.Unix(),
 		"iss":  "auth",
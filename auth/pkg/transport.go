@@ -22,6 +22,13 @@ func NewHTTPHandler(svc Service) http.Handler {
 		httptransport.EncodeJSONResponse,
 	))
 
+	router.Handler(http.MethodPost, "/feed", httptransport.NewServer(
+		makeFeedTokenEndpoint(svc),
+		httptransport.NopRequestDecoder,
+		httptransport.EncodeJSONResponse,
+		httptransport.ServerBefore(jwt.HTTPToContext()),
+	))
+
 	return router
 }
 
@@ -39,7 +46,8 @@ func decodeLoginRequest(ctx context.Context, r *http.Request) (any, error) {
 
 type grpcServer struct {
 	proto.UnimplementedAuthServer
-	verify grpc.Handler
+	verify     grpc.Handler
+	verifyFeed grpc.Handler
 }
 
 func NewGRPCServer(svc Service) proto.AuthServer {
@@ -50,6 +58,12 @@ func NewGRPCServer(svc Service) proto.AuthServer {
 			encodeVerifyResponse,
 			grpc.ServerBefore(jwt.GRPCToContext()),
 		),
+		verifyFeed: grpc.NewServer(
+			makeVerifyFeedEndpoint(svc),
+			nopGRPCRequestDecoder,
+			encodeVerifyResponse,
+			grpc.ServerBefore(jwt.GRPCToContext()),
+		),
 	}
 }
 
@@ -61,6 +75,14 @@ func (s *grpcServer) Verify(ctx context.Context, r *emptypb.Empty) (*proto.Verif
 	return reply.(*proto.VerifyReply), nil
 }
 
+func (s *grpcServer) VerifyFeed(ctx context.Context, r *emptypb.Empty) (*proto.VerifyReply, error) {
+	_, reply, err := s.verifyFeed.ServeGRPC(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return reply.(*proto.VerifyReply), nil
+}
+
 // NopGRCPRequestDecoder is a DecodeRequestFunc that can be used for requests
 // that do not need to be decoded, and simply returns nil, nil.
 func nopGRPCRequestDecoder(ctx context.Context, r any) (any, error) {
@@ -72,6 +94,6 @@ func encodeVerifyResponse(ctx context.Context, r any) (any, error) {
 	if reply.Err != nil {
 		return &proto.VerifyReply{Err: reply.Err.AsError()}, nil
 	}
-	user := &proto.User{Id: reply.User.ID, Name: reply.User.Name}
+	user := &proto.User{Id: reply.User.ID, Name: reply.User.Name, Roles: reply.User.Roles}
 	return &proto.VerifyReply{User: user}, nil
 }
@@ -8,20 +8,22 @@ import (
 )
 
 type Set struct {
-	Get    endpoint.Endpoint
-	List   endpoint.Endpoint
-	Create endpoint.Endpoint
-	Update endpoint.Endpoint
-	Delete endpoint.Endpoint
+	Get      endpoint.Endpoint
+	GetByIDs endpoint.Endpoint
+	List     endpoint.Endpoint
+	Create   endpoint.Endpoint
+	Update   endpoint.Endpoint
+	Delete   endpoint.Endpoint
 }
 
 func NewSet(svc Service) Set {
 	return Set{
-		Get:    makeGetEndpoint(svc),
-		List:   makeListEndpoint(svc),
-		Create: makeCreateEndpoint(svc),
-		Update: makeUpdateEndpoint(svc),
-		Delete: makeDeleteEndpoint(svc),
+		Get:      makeGetEndpoint(svc),
+		GetByIDs: makeGetByIDsEndpoint(svc),
+		List:     makeListEndpoint(svc),
+		Create:   makeCreateEndpoint(svc),
+		Update:   makeUpdateEndpoint(svc),
+		Delete:   makeDeleteEndpoint(svc),
 	}
 }
 
@@ -37,6 +39,12 @@ func makeGetEndpoint(svc Service) endpoint.Endpoint {
 	}
 }
 
+func makeGetByIDsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.GetByIDs(r.([]string))
+	}
+}
+
 func makeListEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
 		pagination := r.(Pagination)
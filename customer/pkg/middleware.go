@@ -23,6 +23,18 @@ func (l *loggingService) Get(id string) (customer *Customer, err error) {
 	return l.next.Get(id)
 }
 
+func (l *loggingService) GetByIDs(ids []string) (customers []*Customer, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetByIDs",
+			"input", ids,
+			"output", len(customers),
+			"err", err,
+		)
+	}()
+	return l.next.GetByIDs(ids)
+}
+
 func (l *loggingService) List(page, perPage int64) (result *ListResult, err error) {
 	defer func() {
 		l.logger.Log(
@@ -14,11 +14,12 @@ func NewVerifySet(set Set, cc *grpc.ClientConn) Set {
 	verify := verifyMiddleware(cc)
 
 	return Set{
-		Get:    verify(set.Get),
-		List:   verify(set.List),
-		Create: verify(set.Create),
-		Update: verify(set.Update),
-		Delete: verify(set.Delete),
+		Get:      verify(set.Get),
+		GetByIDs: verify(set.GetByIDs),
+		List:     verify(set.List),
+		Create:   verify(set.Create),
+		Update:   verify(set.Update),
+		Delete:   verify(set.Delete),
 	}
 }
 
@@ -13,6 +13,7 @@ import (
 type Repository interface {
 	List(curPage, perPage int64) ([]*Customer, int64, error)
 	Get(id string) (*Customer, error)
+	GetByIDs(ids []string) ([]*Customer, error)
 	Create(Customer) (*Customer, error)
 	Update(string, Customer) (*Customer, error)
 	Delete(string) error
@@ -98,6 +99,23 @@ func (r *mongoRepository) Get(id string) (*Customer, error) {
 	return customer, err
 }
 
+func (r *mongoRepository) GetByIDs(ids []string) ([]*Customer, error) {
+	ctx := context.Background()
+	collection := r.database.Collection("customers")
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+
+	customers := make([]*Customer, 0)
+	if err := cursor.All(ctx, &customers); err != nil {
+		return nil, err
+	}
+
+	return customers, nil
+}
+
 func (r *mongoRepository) Delete(id string) error {
 	collection := r.database.Collection("customers")
 	_, err := collection.DeleteOne(context.Background(), bson.M{"_id": id})
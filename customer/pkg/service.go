@@ -6,6 +6,8 @@ import (
 	"time"
 )
 
+// Customer rents equipment. The credit limit caps what the customer may owe in
+// open rents and unpaid invoices, and customers without one have no limit.
 type Customer struct {
 	ID           string    `bson:"_id,omitempty" json:"id,omitempty"`
 	Name         string    `bson:"name" json:"name" validate:"required"`
@@ -18,6 +20,7 @@ type Customer struct {
 	Ocupation    string    `bson:"ocupation" json:"ocupation"`
 	Address      Address   `bson:"inline" json:"address" validate:"required"`
 	Observations string    `bson:"observations" json:"observations"`
+	CreditLimit  float64   `bson:"credit_limit" json:"credit_limit" validate:"gte=0"`
 }
 
 type Address struct {
@@ -38,6 +41,7 @@ type ListResult struct {
 
 type Service interface {
 	Get(string) (*Customer, error)
+	GetByIDs(ids []string) ([]*Customer, error)
 	List(page, perPage int64) (*ListResult, error)
 	Create(Customer) (*Customer, error)
 	Update(id string, data Customer) (*Customer, error)
@@ -65,6 +69,20 @@ func (s *service) Get(id string) (*Customer, error) {
 	return customer, nil
 }
 
+// GetByIDs returns the customers found among the ids, leaving out the ones
+// that do not exist.
+func (s *service) GetByIDs(ids []string) ([]*Customer, error) {
+	customers, err := s.repository.GetByIDs(ids)
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error fetching customers",
+			"something went wrong while fetching customers",
+		)
+	}
+	return customers, nil
+}
+
 func (s *service) List(page, perPage int64) (*ListResult, error) {
 	customers, total, err := s.repository.List(page, perPage)
 	if err != nil {
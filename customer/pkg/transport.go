@@ -15,7 +15,8 @@ import (
 
 type grpcServer struct {
 	proto.UnimplementedCustomerServer
-	get grpctransport.Handler
+	get      grpctransport.Handler
+	getByIDs grpctransport.Handler
 }
 
 func NewGRPCServer(endpoints Set) proto.CustomerServer {
@@ -25,6 +26,11 @@ func NewGRPCServer(endpoints Set) proto.CustomerServer {
 			decodeGRPCGetRequest,
 			encodeGRPCGetResponse,
 		),
+		getByIDs: grpctransport.NewServer(
+			endpoints.GetByIDs,
+			decodeGRPCGetByIDsRequest,
+			encodeGRPCGetByIDsResponse,
+		),
 	}
 }
 
@@ -34,17 +40,36 @@ func decodeGRPCGetRequest(ctx context.Context, r any) (any, error) {
 }
 
 func encodeGRPCGetResponse(ctx context.Context, r any) (any, error) {
-	customer := r.(*Customer)
+	return encodeClient(r.(*Customer)), nil
+}
+
+func decodeGRPCGetByIDsRequest(ctx context.Context, r any) (any, error) {
+	req := r.(*proto.GetByIDsRequest)
+	return req.GetIds(), nil
+}
 
+func encodeGRPCGetByIDsResponse(ctx context.Context, r any) (any, error) {
+	customers := r.([]*Customer)
+	clients := make([]*proto.Client, len(customers))
+
+	for i, customer := range customers {
+		clients[i] = encodeClient(customer)
+	}
+
+	return &proto.ClientsReply{Clients: clients}, nil
+}
+
+func encodeClient(customer *Customer) *proto.Client {
 	return &proto.Client{
-		Id:        customer.ID,
-		Name:      customer.Name,
-		Email:     customer.Email,
-		CpfCnpj:   customer.CpfCnpj,
-		RgInscEst: customer.RgInscEst,
-		Phone:     customer.Phone,
-		Cellphone: customer.Cellphone,
-	}, nil
+		Id:          customer.ID,
+		Name:        customer.Name,
+		Email:       customer.Email,
+		CpfCnpj:     customer.CpfCnpj,
+		RgInscEst:   customer.RgInscEst,
+		Phone:       customer.Phone,
+		Cellphone:   customer.Cellphone,
+		CreditLimit: customer.CreditLimit,
+	}
 }
 
 func (s *grpcServer) Get(ctx context.Context, r *proto.GetRequest) (*proto.Client, error) {
@@ -55,6 +80,14 @@ func (s *grpcServer) Get(ctx context.Context, r *proto.GetRequest) (*proto.Clien
 	return reply.(*proto.Client), nil
 }
 
+func (s *grpcServer) GetByIDs(ctx context.Context, r *proto.GetByIDsRequest) (*proto.ClientsReply, error) {
+	_, reply, err := s.getByIDs.ServeGRPC(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return reply.(*proto.ClientsReply), nil
+}
+
 func NewHTTPHandler(set Set) http.Handler {
 	router := httprouter.New()
 
@@ -1,25 +1,61 @@
 package main
 
 import (
+	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/streadway/amqp"
 	"google.golang.org/grpc"
 	"reconcip.com.br/microservices/delivery/pkg"
 	"reconcip.com.br/microservices/delivery/proto"
 )
 
 func main() {
+	repository, err := pkg.NewMongoRepository(
+		os.Getenv("MONGODB_URL"),
+		os.Getenv("MONGODB_USER"),
+		os.Getenv("MONGODB_PASSWORD"),
+		os.Getenv("MONGODB_DATABASE"),
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	windowHours, err := strconv.Atoi(os.Getenv("DELIVERY_WINDOW_HOURS"))
+	if err != nil {
+		windowHours = 4
+	}
+
 	svc := pkg.NewService([]pkg.Carrier{
 		pkg.NewLocalCarrier(5, 7, pkg.NewMapeiaRouter(), pkg.NewMapeiaCoordinator()),
-	})
+	}, repository, time.Duration(windowHours)*time.Hour)
 
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(3)
 
 	endpoints := pkg.CreateEndpoints(svc)
 
+	go func(endpoints pkg.Set) {
+		defer wg.Done()
+
+		user := os.Getenv("BROKER_USER")
+		pass := os.Getenv("BROKER_PASSWORD")
+		url := os.Getenv("BROKER_SERVICE_URL")
+
+		conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s/", user, pass, url))
+		if err != nil {
+			panic(err)
+		}
+
+		pkg.NewSubscriber(endpoints, conn)
+	}(endpoints)
+
 	go func(endpoints pkg.Set) {
 		defer wg.Done()
 
@@ -1,7 +1,5 @@
 package pkg
 
-import "math"
-
 type Carrier interface {
 	String() string
 	GetQuote(origin, destination string, items []Item) (*Quote, error)
@@ -40,14 +38,16 @@ func (c *localCarrier) GetQuote(from, to string, items []Item) (*Quote, error) {
 		return nil, err
 	}
 
-	quote := &Quote{Carrier: c.name, Value: math.Inf(0)}
+	if len(routes) == 0 {
+		return nil, ErrNoRoute
+	}
 
+	var quote *Quote
 	for _, route := range routes {
-		baseValue := route.Distance / 1000 / c.distLiter * c.gasPrice * 1.15
-		value := math.Round(baseValue*100) / 100
+		value := NewMoney(route.Distance / 1000 / c.distLiter * c.gasPrice * 1.15)
 
-		if value < quote.Value {
-			quote.Value = value
+		if quote == nil || value < quote.Value {
+			quote = &Quote{Carrier: c.name, Value: value, Duration: route.Duration}
 		}
 	}
 
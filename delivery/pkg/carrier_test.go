@@ -64,7 +64,7 @@ func TestCarrier(t *testing.T) {
 				t.Errorf("expected carrier local, got %v", quote.Carrier)
 			}
 
-			expectedValue := 6.9
+			expectedValue := pkg.NewMoney(6.9)
 			if quote.Value != expectedValue {
 				t.Errorf("expected value %v, got %v", expectedValue, quote.Value)
 			}
@@ -98,10 +98,19 @@ func TestCarrier(t *testing.T) {
 				t.Errorf("expected carrier local, got %v", quote.Carrier)
 			}
 
-			expectedValue := 5.75
+			expectedValue := pkg.NewMoney(5.75)
 			if quote.Value != expectedValue {
 				t.Errorf("expected value %v, got %v", expectedValue, quote.Value)
 			}
 		})
+
+		t.Run("no route", func(t *testing.T) {
+			carrier := pkg.NewLocalCarrier(5, 10, &fakeRouter{}, &fakeCoordinator{})
+			items := []pkg.Item{{Qty: 1, Weight: 1, Width: 1, Height: 1, Depth: 1}}
+
+			if _, err := carrier.GetQuote("santos, São Paulo, SP", "santos, São Paulo, SP", items); err != pkg.ErrNoRoute {
+				t.Errorf("expected error %v, got %v", pkg.ErrNoRoute, err)
+			}
+		})
 	})
 }
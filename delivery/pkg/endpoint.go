@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
 )
@@ -9,12 +10,22 @@ import (
 type Set struct {
 	GetQuote  endpoint.Endpoint
 	GetQuotes endpoint.Endpoint
+
+	ScheduleRent      endpoint.Endpoint
+	CancelRent        endpoint.Endpoint
+	ListOrders        endpoint.Endpoint
+	UpdateOrderStatus endpoint.Endpoint
 }
 
 func CreateEndpoints(svc Service) Set {
 	return Set{
 		GetQuote:  makeGetQuoteEndpoint(svc),
 		GetQuotes: makeGetQuotesEndpoint(svc),
+
+		ScheduleRent:      makeScheduleRentEndpoint(svc),
+		CancelRent:        makeCancelRentEndpoint(svc),
+		ListOrders:        makeListOrdersEndpoint(svc),
+		UpdateOrderStatus: makeUpdateOrderStatusEndpoint(svc),
 	}
 }
 
@@ -31,3 +42,28 @@ func makeGetQuotesEndpoint(svc Service) endpoint.Endpoint {
 		return svc.GetQuotes(req.Origin, req.Dest, req.Items)
 	}
 }
+
+func makeScheduleRentEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.ScheduleRent(r.(RentEvent))
+	}
+}
+
+func makeCancelRentEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return nil, svc.CancelRent(r.(string))
+	}
+}
+
+func makeListOrdersEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.ListOrders(r.(time.Time))
+	}
+}
+
+func makeUpdateOrderStatusEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(UpdateOrderStatusRequest)
+		return svc.UpdateOrderStatus(req.ID, req.Status)
+	}
+}
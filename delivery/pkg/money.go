@@ -0,0 +1,59 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in cents. Amounts are added up as integers so totals do
+// not collect floating point errors, but they are still read and written as
+// decimal numbers, keeping the shape of the API.
+type Money int64
+
+// NewMoney rounds value to the cent.
+func NewMoney(value float64) Money {
+	return Money(math.Round(value * 100))
+}
+
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// Times multiplies the amount by a quantity.
+func (m Money) Times(qty int) Money {
+	return m * Money(qty)
+}
+
+// Mul multiplies the amount by a factor, like a rate, rounding to the cent.
+func (m Money) Mul(factor float64) Money {
+	return Money(math.Round(float64(m) * factor))
+}
+
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, m/100, m%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" || text == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid money %s", data)
+	}
+
+	*m = NewMoney(value)
+	return nil
+}
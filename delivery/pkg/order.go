@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrInvalidTransition = errors.New("invalid order status transition")
+)
+
+type OrderType string
+
+const (
+	OrderDropoff OrderType = "dropoff"
+	OrderPickup  OrderType = "pickup"
+)
+
+type OrderStatus string
+
+const (
+	OrderScheduled OrderStatus = "scheduled"
+	OrderEnRoute   OrderStatus = "en_route"
+	OrderDone      OrderStatus = "done"
+	OrderFailed    OrderStatus = "failed"
+)
+
+// transitions lists the statuses an order can go to from each status. A
+// failed order can be scheduled again for another attempt.
+var transitions = map[OrderStatus][]OrderStatus{
+	OrderScheduled: {OrderEnRoute, OrderFailed},
+	OrderEnRoute:   {OrderDone, OrderFailed},
+	OrderFailed:    {OrderScheduled},
+}
+
+func (s OrderStatus) CanTransitionTo(status OrderStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Order is a trip of a carrier to drop off the equipment of a rent at the
+// delivery address or to pick it up at the end of the rent.
+type Order struct {
+	ID          string      `json:"id" bson:"_id,omitempty"`
+	RentID      string      `json:"rent_id" bson:"rent_id"`
+	Type        OrderType   `json:"type"`
+	CarrierID   string      `json:"carrier_id" bson:"carrier_id"`
+	Address     string      `json:"address"`
+	WindowStart time.Time   `json:"window_start" bson:"window_start"`
+	WindowEnd   time.Time   `json:"window_end" bson:"window_end"`
+	Status      OrderStatus `json:"status"`
+	CreatedAt   time.Time   `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" bson:"updated_at"`
+}
+
+// RentEvent is the part of the events published by the renting service that
+// is needed to schedule the deliveries of a rent.
+type RentEvent struct {
+	RentID          string    `json:"rent_id"`
+	Status          string    `json:"status"`
+	CarrierID       string    `json:"carrier_id"`
+	DeliveryAddress string    `json:"delivery_address"`
+	StartDate       time.Time `json:"start_date"`
+	EndDate         time.Time `json:"end_date"`
+}
+
+// schedules tells whether the rent needs an order of the given type. The
+// equipment is only dropped off while the rent is reserved, after that it is
+// already with the customer and only the pickup is left.
+func (e RentEvent) schedules(orderType OrderType) bool {
+	if e.CarrierID == "" || e.DeliveryAddress == "" {
+		return false
+	}
+
+	switch e.Status {
+	case "reserved":
+		return true
+	case "active", "partially_returned":
+		return orderType == OrderPickup
+	default:
+		return false
+	}
+}
+
+// window returns the period in which the carrier should get to the address.
+// Drop offs must arrive before the rent starts and pickups after it ends.
+func (e RentEvent) window(orderType OrderType, length time.Duration) (time.Time, time.Time) {
+	if orderType == OrderDropoff {
+		return e.StartDate.Add(-length), e.StartDate
+	}
+	return e.EndDate, e.EndDate.Add(length)
+}
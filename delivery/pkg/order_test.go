@@ -0,0 +1,241 @@
+package pkg_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/delivery/pkg"
+)
+
+type fakeRepository struct {
+	orders map[string]*pkg.Order
+	nextID int
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{orders: make(map[string]*pkg.Order)}
+}
+
+func (r *fakeRepository) CreateOrder(data pkg.Order) (*pkg.Order, error) {
+	r.nextID++
+	data.ID = fmt.Sprintf("order%d", r.nextID)
+	r.orders[data.ID] = &data
+	return &data, nil
+}
+
+func (r *fakeRepository) GetOrder(id string) (*pkg.Order, error) {
+	if order, ok := r.orders[id]; ok {
+		copied := *order
+		return &copied, nil
+	}
+	return nil, pkg.ErrOrderNotFound
+}
+
+func (r *fakeRepository) UpdateOrder(id string, data pkg.Order) (*pkg.Order, error) {
+	r.orders[id] = &data
+	return &data, nil
+}
+
+func (r *fakeRepository) DeleteOrder(id string) error {
+	delete(r.orders, id)
+	return nil
+}
+
+func (r *fakeRepository) ListRentOrders(rentID string) ([]*pkg.Order, error) {
+	orders := make([]*pkg.Order, 0)
+	for _, order := range r.orders {
+		if order.RentID == rentID {
+			copied := *order
+			orders = append(orders, &copied)
+		}
+	}
+	return orders, nil
+}
+
+func (r *fakeRepository) ListOrders(from, to time.Time) ([]*pkg.Order, error) {
+	orders := make([]*pkg.Order, 0)
+	for _, order := range r.orders {
+		if order.WindowStart.Before(to) && !order.WindowEnd.Before(from) {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *fakeRepository) find(orderType pkg.OrderType) *pkg.Order {
+	for _, order := range r.orders {
+		if order.Type == orderType {
+			return order
+		}
+	}
+	return nil
+}
+
+func TestOrders(t *testing.T) {
+	start := time.Date(2022, 11, 10, 8, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 11, 20, 18, 0, 0, 0, time.UTC)
+
+	rent := pkg.RentEvent{
+		RentID:          "rent",
+		Status:          "reserved",
+		CarrierID:       "local",
+		DeliveryAddress: "rua monte alegre do sul, mogi guaçu",
+		StartDate:       start,
+		EndDate:         end,
+	}
+
+	t.Run("schedules dropoff and pickup", func(t *testing.T) {
+		repository := newFakeRepository()
+		svc := pkg.NewService([]pkg.Carrier{}, repository, 4*time.Hour)
+
+		orders, err := svc.ScheduleRent(rent)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if len(orders) != 2 {
+			t.Fatalf("expected 2 orders, got %v", len(orders))
+		}
+
+		dropoff := repository.find(pkg.OrderDropoff)
+		if !dropoff.WindowStart.Equal(start.Add(-4*time.Hour)) || !dropoff.WindowEnd.Equal(start) {
+			t.Errorf("wrong dropoff window: %v - %v", dropoff.WindowStart, dropoff.WindowEnd)
+		}
+
+		pickup := repository.find(pkg.OrderPickup)
+		if !pickup.WindowStart.Equal(end) || !pickup.WindowEnd.Equal(end.Add(4*time.Hour)) {
+			t.Errorf("wrong pickup window: %v - %v", pickup.WindowStart, pickup.WindowEnd)
+		}
+
+		if pickup.Status != pkg.OrderScheduled {
+			t.Errorf("expected status %v, got %v", pkg.OrderScheduled, pickup.Status)
+		}
+	})
+
+	t.Run("follows rent changes", func(t *testing.T) {
+		repository := newFakeRepository()
+		svc := pkg.NewService([]pkg.Carrier{}, repository, 4*time.Hour)
+
+		if _, err := svc.ScheduleRent(rent); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		dropoff := repository.find(pkg.OrderDropoff)
+		if _, err := svc.UpdateOrderStatus(dropoff.ID, pkg.OrderEnRoute); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		changed := rent
+		changed.Status = "active"
+		changed.DeliveryAddress = "santos"
+		changed.EndDate = end.AddDate(0, 0, 5)
+
+		if _, err := svc.ScheduleRent(changed); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if len(repository.orders) != 2 {
+			t.Fatalf("expected 2 orders, got %v", len(repository.orders))
+		}
+
+		dropoff = repository.find(pkg.OrderDropoff)
+		if dropoff.Address != rent.DeliveryAddress {
+			t.Errorf("expected dropoff en route to keep its address, got %v", dropoff.Address)
+		}
+
+		pickup := repository.find(pkg.OrderPickup)
+		if pickup.Address != "santos" || !pickup.WindowStart.Equal(changed.EndDate) {
+			t.Errorf("expected pickup to be rescheduled, got %v at %v", pickup.Address, pickup.WindowStart)
+		}
+	})
+
+	t.Run("no carrier", func(t *testing.T) {
+		repository := newFakeRepository()
+		svc := pkg.NewService([]pkg.Carrier{}, repository, 4*time.Hour)
+
+		if _, err := svc.ScheduleRent(rent); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		changed := rent
+		changed.CarrierID = ""
+
+		if _, err := svc.ScheduleRent(changed); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if len(repository.orders) != 0 {
+			t.Errorf("expected orders to be removed, got %v", len(repository.orders))
+		}
+	})
+
+	t.Run("cancel rent", func(t *testing.T) {
+		repository := newFakeRepository()
+		svc := pkg.NewService([]pkg.Carrier{}, repository, 4*time.Hour)
+
+		if _, err := svc.ScheduleRent(rent); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		dropoff := repository.find(pkg.OrderDropoff)
+		if _, err := svc.UpdateOrderStatus(dropoff.ID, pkg.OrderFailed); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if err := svc.CancelRent(rent.RentID); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if len(repository.orders) != 1 || repository.find(pkg.OrderDropoff) == nil {
+			t.Errorf("expected only the failed dropoff to be kept, got %v orders", len(repository.orders))
+		}
+	})
+
+	t.Run("list orders of the day", func(t *testing.T) {
+		repository := newFakeRepository()
+		svc := pkg.NewService([]pkg.Carrier{}, repository, 4*time.Hour)
+
+		if _, err := svc.ScheduleRent(rent); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		orders, err := svc.ListOrders(end)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if len(orders) != 1 || orders[0].Type != pkg.OrderPickup {
+			t.Errorf("expected only the pickup, got %v orders", len(orders))
+		}
+	})
+
+	t.Run("status transitions", func(t *testing.T) {
+		repository := newFakeRepository()
+		svc := pkg.NewService([]pkg.Carrier{}, repository, 4*time.Hour)
+
+		if _, err := svc.ScheduleRent(rent); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		pickup := repository.find(pkg.OrderPickup)
+		if _, err := svc.UpdateOrderStatus(pickup.ID, pkg.OrderDone); err != pkg.ErrInvalidTransition {
+			t.Errorf("expected error %v, got %v", pkg.ErrInvalidTransition, err)
+		}
+
+		for _, status := range []pkg.OrderStatus{pkg.OrderEnRoute, pkg.OrderDone} {
+			order, err := svc.UpdateOrderStatus(pickup.ID, status)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if order.Status != status {
+				t.Errorf("expected status %v, got %v", status, order.Status)
+			}
+		}
+
+		if _, err := svc.UpdateOrderStatus("missing", pkg.OrderEnRoute); err != pkg.ErrOrderNotFound {
+			t.Errorf("expected error %v, got %v", pkg.ErrOrderNotFound, err)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	CreateOrder(Order) (*Order, error)
+	GetOrder(string) (*Order, error)
+	UpdateOrder(string, Order) (*Order, error)
+	DeleteOrder(string) error
+	ListRentOrders(rentID string) ([]*Order, error)
+	ListOrders(from, to time.Time) ([]*Order, error)
+}
+
+type mongoRepository struct {
+	database *mongo.Database
+}
+
+func NewMongoRepository(url, user, pass, db string) (*mongoRepository, error) {
+	options := options.Client().ApplyURI(fmt.Sprintf("mongodb://%s:%s@%s/", user, pass, url))
+	conn, err := mongo.Connect(context.Background(), options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	database := conn.Database(db)
+	return &mongoRepository{database}, nil
+}
+
+func (r *mongoRepository) CreateOrder(data Order) (*Order, error) {
+	collection := r.database.Collection("delivery_orders")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	data.ID = primitive.NewObjectID().Hex()
+	result, err := collection.InsertOne(ctx, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetOrder(result.InsertedID.(string))
+}
+
+func (r *mongoRepository) GetOrder(id string) (*Order, error) {
+	collection := r.database.Collection("delivery_orders")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	result := collection.FindOne(ctx, bson.M{"_id": id})
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, ErrOrderNotFound
+	}
+
+	var order *Order
+	if err := result.Decode(&order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (r *mongoRepository) UpdateOrder(id string, data Order) (*Order, error) {
+	collection := r.database.Collection("delivery_orders")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": id}, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetOrder(id)
+}
+
+func (r *mongoRepository) DeleteOrder(id string) error {
+	collection := r.database.Collection("delivery_orders")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+
+	return err
+}
+
+func (r *mongoRepository) ListRentOrders(rentID string) ([]*Order, error) {
+	return r.findOrders(bson.M{"rent_id": rentID})
+}
+
+// ListOrders returns the orders whose window overlaps the given period.
+func (r *mongoRepository) ListOrders(from, to time.Time) ([]*Order, error) {
+	return r.findOrders(bson.M{
+		"window_start": bson.M{"$lt": to},
+		"window_end":   bson.M{"$gte": from},
+	})
+}
+
+func (r *mongoRepository) findOrders(filter bson.M) ([]*Order, error) {
+	collection := r.database.Collection("delivery_orders")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "window_start", Value: 1}})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]*Order, 0)
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
@@ -3,17 +3,20 @@ package pkg
 import (
 	"errors"
 	"log"
+	"time"
 )
 
 var (
 	ErrNoItems         = errors.New("no items for delivery")
 	ErrNoCarriers      = errors.New("no carriers")
 	ErrCarrierNotFound = errors.New("carrier not found")
+	ErrNoRoute         = errors.New("carrier could not find a route")
 )
 
 type Quote struct {
-	Carrier string  `json:"company"`
-	Value   float64 `json:"value"`
+	Carrier  string  `json:"company"`
+	Value    Money   `json:"value"`
+	Duration float64 `json:"duration"`
 }
 
 type Item struct {
@@ -27,15 +30,24 @@ type Item struct {
 type Service interface {
 	GetQuote(origin, dest, carrier string, items []Item) (*Quote, error)
 	GetQuotes(origin, destination string, items []Item) ([]*Quote, error)
+
+	ScheduleRent(rent RentEvent) ([]*Order, error)
+	CancelRent(rentID string) error
+	ListOrders(day time.Time) ([]*Order, error)
+	UpdateOrderStatus(id string, status OrderStatus) (*Order, error)
 }
 
 type service struct {
-	carriers map[string]Carrier
+	carriers   map[string]Carrier
+	repository Repository
+	window     time.Duration
 }
 
-func NewService(carriers []Carrier) Service {
+func NewService(carriers []Carrier, repository Repository, window time.Duration) Service {
 	service := &service{
-		carriers: make(map[string]Carrier),
+		carriers:   make(map[string]Carrier),
+		repository: repository,
+		window:     window,
 	}
 	for _, carrier := range carriers {
 		service.carriers[carrier.String()] = carrier
@@ -77,3 +89,112 @@ func (s *service) GetQuotes(origin, destination string, items []Item) ([]*Quote,
 
 	return quotes, nil
 }
+
+// ScheduleRent keeps the orders of a rent in sync with it. Orders that did not
+// leave yet follow the changes of the rent, the others are left as they are.
+func (s *service) ScheduleRent(rent RentEvent) ([]*Order, error) {
+	orders, err := s.repository.ListRentOrders(rent.RentID)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := make([]*Order, 0)
+	for _, orderType := range []OrderType{OrderDropoff, OrderPickup} {
+		order := findOrder(orders, orderType)
+
+		if !rent.schedules(orderType) {
+			if order != nil && order.Status == OrderScheduled {
+				if err := s.repository.DeleteOrder(order.ID); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		if order != nil && order.Status != OrderScheduled {
+			continue
+		}
+
+		now := time.Now()
+		windowStart, windowEnd := rent.window(orderType, s.window)
+
+		if order == nil {
+			order, err = s.repository.CreateOrder(Order{
+				RentID:      rent.RentID,
+				Type:        orderType,
+				CarrierID:   rent.CarrierID,
+				Address:     rent.DeliveryAddress,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+				Status:      OrderScheduled,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			})
+		} else {
+			order.CarrierID = rent.CarrierID
+			order.Address = rent.DeliveryAddress
+			order.WindowStart = windowStart
+			order.WindowEnd = windowEnd
+			order.UpdatedAt = now
+
+			order, err = s.repository.UpdateOrder(order.ID, *order)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		scheduled = append(scheduled, order)
+	}
+
+	return scheduled, nil
+}
+
+// CancelRent removes the orders of a cancelled rent that did not leave yet.
+func (s *service) CancelRent(rentID string) error {
+	orders, err := s.repository.ListRentOrders(rentID)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if order.Status == OrderScheduled {
+			if err := s.repository.DeleteOrder(order.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ListOrders returns the orders whose window falls in the given day.
+func (s *service) ListOrders(day time.Time) ([]*Order, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return s.repository.ListOrders(from, from.AddDate(0, 0, 1))
+}
+
+func (s *service) UpdateOrderStatus(id string, status OrderStatus) (*Order, error) {
+	order, err := s.repository.GetOrder(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !order.Status.CanTransitionTo(status) {
+		return nil, ErrInvalidTransition
+	}
+
+	order.Status = status
+	order.UpdatedAt = time.Now()
+
+	return s.repository.UpdateOrder(id, *order)
+}
+
+func findOrder(orders []*Order, orderType OrderType) *Order {
+	for _, order := range orders {
+		if order.Type == orderType {
+			return order
+		}
+	}
+	return nil
+}
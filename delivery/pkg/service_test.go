@@ -14,7 +14,7 @@ func TestService(t *testing.T) {
 
 			svc := pkg.NewService([]pkg.Carrier{
 				pkg.NewLocalCarrier(5, 12, router, coordinator),
-			})
+			}, nil, 0)
 
 			origin := "rua monte alegre do sul, mogi guaçu, São Paulo, SP"
 			dest := "santos, São Paulo, SP"
@@ -35,14 +35,14 @@ func TestService(t *testing.T) {
 				t.Errorf("expected carrier local, got %v", quotes[0].Carrier)
 			}
 
-			expectedValue := 4.79
+			expectedValue := pkg.NewMoney(4.79)
 			if quotes[0].Value != expectedValue {
 				t.Errorf("expected value %v, got %v", expectedValue, quotes[0].Value)
 			}
 		})
 
 		t.Run("no carriers", func(t *testing.T) {
-			svc := pkg.NewService([]pkg.Carrier{})
+			svc := pkg.NewService([]pkg.Carrier{}, nil, 0)
 
 			origin := "rua monte alegre do sul, mogi guaçu, São Paulo, SP"
 			dest := "santos, São Paulo, SP"
@@ -56,7 +56,7 @@ func TestService(t *testing.T) {
 		})
 
 		t.Run("no items", func(t *testing.T) {
-			svc := pkg.NewService([]pkg.Carrier{})
+			svc := pkg.NewService([]pkg.Carrier{}, nil, 0)
 
 			origin := "rua monte alegre do sul, mogi guaçu, São Paulo, SP"
 			dest := "santos, São Paulo, SP"
@@ -75,7 +75,7 @@ func TestService(t *testing.T) {
 
 			svc := pkg.NewService([]pkg.Carrier{
 				pkg.NewLocalCarrier(5, 12, router, coordinator),
-			})
+			}, nil, 0)
 
 			origin := "rua monte alegre do sul, mogi guaçu, São Paulo, SP"
 			dest := "santos, São Paulo, SP"
@@ -94,7 +94,7 @@ func TestService(t *testing.T) {
 
 			svc := pkg.NewService([]pkg.Carrier{
 				pkg.NewLocalCarrier(5, 12, router, coordinator),
-			})
+			}, nil, 0)
 
 			origin := "rua monte alegre do sul, mogi guaçu, São Paulo, SP"
 			dest := "santos, São Paulo, SP"
@@ -110,7 +110,7 @@ func TestService(t *testing.T) {
 
 			svc := pkg.NewService([]pkg.Carrier{
 				pkg.NewLocalCarrier(5, 12, router, coordinator),
-			})
+			}, nil, 0)
 
 			origin := "rua monte alegre do sul, mogi guaçu, São Paulo, SP"
 			dest := "santos, São Paulo, SP"
@@ -131,7 +131,7 @@ func TestService(t *testing.T) {
 				t.Errorf("expected carrier local, got %v", quote.Carrier)
 			}
 
-			expectedValue := 4.79
+			expectedValue := pkg.NewMoney(4.79)
 			if quote.Value != expectedValue {
 				t.Errorf("expected value %v, got %v", expectedValue, quote.Value)
 			}
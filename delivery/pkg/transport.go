@@ -3,17 +3,26 @@ package pkg
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
+	amqptransport "github.com/go-kit/kit/transport/amqp"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/julienschmidt/httprouter"
+	"github.com/streadway/amqp"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"reconcip.com.br/microservices/delivery/proto"
 )
 
+var ErrInvalidDate = errors.New("invalid date, expected YYYY-MM-DD")
+
 type grpcServer struct {
 	proto.UnimplementedDeliveryServer
-	getQuote grpctransport.Handler
+	getQuote   grpctransport.Handler
+	getQuotes  grpctransport.Handler
+	listOrders grpctransport.Handler
 }
 
 func NewGRPCServer(endpoints Set) proto.DeliveryServer {
@@ -23,6 +32,16 @@ func NewGRPCServer(endpoints Set) proto.DeliveryServer {
 			decodeGetQuoteRequest,
 			encodeGetQuoteResponse,
 		),
+		getQuotes: grpctransport.NewServer(
+			endpoints.GetQuotes,
+			decodeGRPCGetQuotesRequest,
+			encodeGetQuotesResponse,
+		),
+		listOrders: grpctransport.NewServer(
+			endpoints.ListOrders,
+			decodeGRPCListOrdersRequest,
+			encodeListOrdersResponse,
+		),
 	}
 }
 
@@ -34,11 +53,47 @@ func (s *grpcServer) GetQuote(ctx context.Context, r *proto.GetQuoteRequest) (*p
 	return reply.(*proto.Quote), nil
 }
 
+func (s *grpcServer) GetQuotes(ctx context.Context, r *proto.GetQuotesRequest) (*proto.GetQuotesReply, error) {
+	_, reply, err := s.getQuotes.ServeGRPC(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return reply.(*proto.GetQuotesReply), nil
+}
+
+func (s *grpcServer) ListOrders(ctx context.Context, r *proto.ListOrdersRequest) (*proto.ListOrdersReply, error) {
+	_, reply, err := s.listOrders.ServeGRPC(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return reply.(*proto.ListOrdersReply), nil
+}
+
 func decodeGetQuoteRequest(ctx context.Context, r any) (any, error) {
 	req := r.(*proto.GetQuoteRequest)
-	items := make([]Item, len(req.Items))
 
-	for i, item := range req.GetItems() {
+	return GetQuoteRequest{
+		Origin:  req.GetOrigin(),
+		Dest:    req.GetDestination(),
+		Carrier: req.GetCarrier(),
+		Items:   decodeItems(req.GetItems()),
+	}, nil
+}
+
+func decodeGRPCGetQuotesRequest(ctx context.Context, r any) (any, error) {
+	req := r.(*proto.GetQuotesRequest)
+
+	return GetQuotesRequest{
+		Origin: req.GetOrigin(),
+		Dest:   req.GetDestination(),
+		Items:  decodeItems(req.GetItems()),
+	}, nil
+}
+
+func decodeItems(protoItems []*proto.Item) []Item {
+	items := make([]Item, len(protoItems))
+
+	for i, item := range protoItems {
 		items[i] = Item{
 			Qty:    int(item.GetQty()),
 			Weight: item.GetWeight(),
@@ -48,21 +103,58 @@ func decodeGetQuoteRequest(ctx context.Context, r any) (any, error) {
 		}
 	}
 
-	return GetQuoteRequest{
-		Origin:  req.GetOrigin(),
-		Dest:    req.GetDestination(),
-		Carrier: req.GetCarrier(),
-		Items:   items,
-	}, nil
+	return items
 }
 
 func encodeGetQuoteResponse(ctx context.Context, res any) (any, error) {
-	reply := res.(*Quote)
+	return encodeQuote(res.(*Quote)), nil
+}
+
+func encodeGetQuotesResponse(ctx context.Context, res any) (any, error) {
+	quotes := res.([]*Quote)
+	reply := &proto.GetQuotesReply{
+		Quotes: make([]*proto.Quote, len(quotes)),
+	}
+
+	for i, quote := range quotes {
+		reply.Quotes[i] = encodeQuote(quote)
+	}
+
+	return reply, nil
+}
+
+func decodeGRPCListOrdersRequest(ctx context.Context, r any) (any, error) {
+	return r.(*proto.ListOrdersRequest).GetDate().AsTime().Local(), nil
+}
+
+func encodeListOrdersResponse(ctx context.Context, res any) (any, error) {
+	orders := res.([]*Order)
+	reply := &proto.ListOrdersReply{
+		Orders: make([]*proto.Order, len(orders)),
+	}
+
+	for i, order := range orders {
+		reply.Orders[i] = &proto.Order{
+			Id:          order.ID,
+			RentId:      order.RentID,
+			Type:        string(order.Type),
+			CarrierId:   order.CarrierID,
+			Address:     order.Address,
+			WindowStart: timestamppb.New(order.WindowStart),
+			WindowEnd:   timestamppb.New(order.WindowEnd),
+			Status:      string(order.Status),
+		}
+	}
+
+	return reply, nil
+}
 
+func encodeQuote(quote *Quote) *proto.Quote {
 	return &proto.Quote{
-		Carrier: reply.Carrier,
-		Value:   reply.Value,
-	}, nil
+		Carrier:  quote.Carrier,
+		Value:    quote.Value.Float64(),
+		Duration: quote.Duration,
+	}
 }
 
 type GetQuoteRequest struct {
@@ -81,9 +173,73 @@ func NewHTTPServer(endpoints Set) http.Handler {
 		httptransport.EncodeJSONResponse,
 	))
 
+	router.Handler(http.MethodGet, "/orders", httptransport.NewServer(
+		endpoints.ListOrders,
+		decodeListOrdersRequest,
+		httptransport.EncodeJSONResponse,
+		httptransport.ServerErrorEncoder(encodeError),
+	))
+
+	router.Handler(http.MethodPut, "/orders/:id/status", httptransport.NewServer(
+		endpoints.UpdateOrderStatus,
+		decodeUpdateOrderStatusRequest,
+		httptransport.EncodeJSONResponse,
+		httptransport.ServerErrorEncoder(encodeError),
+	))
+
 	return router
 }
 
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	status := http.StatusInternalServerError
+
+	switch err {
+	case ErrOrderNotFound:
+		status = http.StatusNotFound
+	case ErrInvalidTransition:
+		status = http.StatusUnprocessableEntity
+	case ErrInvalidDate:
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// decodeListOrdersRequest reads the day from the date query parameter,
+// defaulting to today.
+func decodeListOrdersRequest(ctx context.Context, r *http.Request) (any, error) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		return time.Now(), nil
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return nil, ErrInvalidDate
+	}
+
+	return day, nil
+}
+
+func decodeUpdateOrderStatusRequest(ctx context.Context, r *http.Request) (any, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	req := UpdateOrderStatusRequest{ID: params.ByName("id")}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+type UpdateOrderStatusRequest struct {
+	ID     string      `json:"-"`
+	Status OrderStatus `json:"status"`
+}
+
 func decodeGetQuotesRequest(ctx context.Context, r *http.Request) (any, error) {
 	var req GetQuotesRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -97,3 +253,86 @@ type GetQuotesRequest struct {
 	Dest   string `json:"dest"`
 	Items  []Item `json:"items"`
 }
+
+// NewSubscriber schedules the deliveries of the rents by consuming the events
+// published by the renting service.
+func NewSubscriber(endpoints Set, conn *amqp.Connection) {
+	channel, err := conn.Channel()
+	if err != nil {
+		panic(err)
+	}
+
+	defer channel.Close()
+
+	if err := channel.ExchangeDeclare("renting", "direct", true, false, false, false, nil); err != nil {
+		panic(err)
+	}
+
+	subscribe(channel, "delivery.schedule_rent", []string{"rent.created", "rent.updated"}, amqptransport.NewSubscriber(
+		endpoints.ScheduleRent,
+		decodeScheduleRentAMQPRequest,
+		amqptransport.EncodeNopResponse,
+		amqptransport.SubscriberResponsePublisher(ackResponse),
+	))
+
+	subscribe(channel, "delivery.cancel_rent", []string{"rent.cancelled"}, amqptransport.NewSubscriber(
+		endpoints.CancelRent,
+		decodeCancelRentAMQPRequest,
+		amqptransport.EncodeNopResponse,
+		amqptransport.SubscriberResponsePublisher(ackResponse),
+	))
+
+	var forever chan any
+	<-forever
+}
+
+func subscribe(channel *amqp.Channel, name string, keys []string, subscriber *amqptransport.Subscriber) {
+	queue, err := channel.QueueDeclare(name, true, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, key := range keys {
+		if err := channel.QueueBind(queue.Name, key, "renting", false, nil); err != nil {
+			panic(err)
+		}
+	}
+
+	handler := subscriber.ServeDelivery(channel)
+	messages, err := channel.Consume(queue.Name, "", false, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	go func(<-chan amqp.Delivery) {
+		for message := range messages {
+			handler(&message)
+		}
+	}(messages)
+}
+
+// ackResponse acknowledges the events once they are handled, as nobody waits
+// for a reply.
+func ackResponse(ctx context.Context, d *amqp.Delivery, ch amqptransport.Channel, p *amqp.Publishing) error {
+	return d.Ack(false)
+}
+
+func decodeScheduleRentAMQPRequest(ctx context.Context, d *amqp.Delivery) (any, error) {
+	var rent RentEvent
+	if err := json.Unmarshal(d.Body, &rent); err != nil {
+		return nil, err
+	}
+	return rent, nil
+}
+
+func decodeCancelRentAMQPRequest(ctx context.Context, d *amqp.Delivery) (any, error) {
+	var rent struct {
+		RentID string `json:"rent_id"`
+	}
+
+	if err := json.Unmarshal(d.Body, &rent); err != nil {
+		return nil, err
+	}
+
+	return rent.RentID, nil
+}
@@ -3,29 +3,38 @@ package pkg
 import (
 	"context"
 	"math"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
 )
 
 type Set struct {
 	Get          endpoint.Endpoint
+	GetByIDs     endpoint.Endpoint
 	Create       endpoint.Endpoint
 	List         endpoint.Endpoint
 	Update       endpoint.Endpoint
 	Delete       endpoint.Endpoint
 	ReduceStock  endpoint.Endpoint
 	RestoreStock endpoint.Endpoint
+	Availability endpoint.Endpoint
+	Extend       endpoint.Endpoint
+	WriteOff     endpoint.Endpoint
 }
 
 func NewSet(svc Service) Set {
 	return Set{
 		Get:          makeGetEndpoint(svc),
+		GetByIDs:     makeGetByIDsEndpoint(svc),
 		Create:       makeCreateEndpoint(svc),
 		List:         makeListEndpoint(svc),
 		Update:       makeUpdateEndpoint(svc),
 		Delete:       makeDeleteEndpoint(svc),
 		ReduceStock:  makeReduceStockEndpoint(svc),
 		RestoreStock: makeRestoreStockEndpoint(svc),
+		Availability: makeAvailabilityEndpoint(svc),
+		Extend:       makeExtendEndpoint(svc),
+		WriteOff:     makeWriteOffEndpoint(svc),
 	}
 }
 
@@ -84,16 +93,65 @@ func makeGetEndpoint(svc Service) endpoint.Endpoint {
 	}
 }
 
+func makeGetByIDsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.GetEquipmentByIDs(r.([]string))
+	}
+}
+
 func makeReduceStockEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
 		req := r.(ReduceStockRequest)
-		return nil, svc.ReduceStock(req.Equip, req.Qty)
+
+		var booking *Booking
+		if req.Rent != "" {
+			booking = &Booking{
+				RentID:    req.Rent,
+				StartDate: req.StartDate,
+				EndDate:   req.EndDate,
+			}
+		}
+
+		return nil, svc.ReduceStock(req.Equip, req.Qty, booking)
 	}
 }
 
 func makeRestoreStockEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
 		req := r.(RestoreStockRequest)
-		return nil, svc.RestoreStock(req.Equip, req.Qty)
+		return nil, svc.RestoreStock(req.Equip, req.Qty, req.Rent)
+	}
+}
+
+func makeWriteOffEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(RestoreStockRequest)
+		return nil, svc.WriteOffStock(req.Equip, req.Qty, req.Rent)
 	}
 }
+
+func makeAvailabilityEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(AvailabilityRequest)
+		return svc.GetAvailability(req.Equip, req.StartDate, req.EndDate, req.Rent)
+	}
+}
+
+type AvailabilityRequest struct {
+	Equip     string    `json:"equip_id"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Rent      string    `json:"rent_id"`
+}
+
+func makeExtendEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(ExtendRequest)
+		return nil, svc.ExtendBooking(req.Rent, req.EndDate)
+	}
+}
+
+type ExtendRequest struct {
+	Rent    string    `json:"rent_id"`
+	EndDate time.Time `json:"end_date"`
+}
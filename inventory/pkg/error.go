@@ -2,10 +2,17 @@ package pkg
 
 import (
 	"encoding/json"
+	"net/http"
 
 	"reconcip.com.br/microservices/inventory/proto"
 )
 
+var errVersionConflict = NewError(
+	http.StatusPreconditionFailed,
+	"equipment was changed",
+	"someone else changed the equipment, reload it and try again",
+)
+
 type Error struct {
 	Status int    `json:"status"`
 	Title  string `json:"title"`
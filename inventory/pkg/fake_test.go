@@ -0,0 +1,99 @@
+package pkg_test
+
+import (
+	"errors"
+	"time"
+
+	"reconcip.com.br/microservices/inventory/pkg"
+)
+
+type fakeValidator struct{}
+
+func (v *fakeValidator) Validate(any) error {
+	return nil
+}
+
+// fakeRepository keeps the equipment by id, checking versions on update.
+// beforeUpdate runs once before the next update, to change the equipment in
+// the meantime, and bookErr fails the bookings.
+type fakeRepository struct {
+	equipment    map[string]*pkg.Equipment
+	bookings     []*pkg.Booking
+	beforeUpdate func()
+	bookErr      error
+}
+
+func newFakeRepository(equipment ...*pkg.Equipment) *fakeRepository {
+	r := &fakeRepository{equipment: make(map[string]*pkg.Equipment)}
+	for _, e := range equipment {
+		r.equipment[e.ID] = e
+	}
+	return r
+}
+
+func (r *fakeRepository) Get(id string) (*pkg.Equipment, error) {
+	if equipment, ok := r.equipment[id]; ok {
+		copied := *equipment
+		return &copied, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (r *fakeRepository) GetByIDs(ids []string) ([]*pkg.Equipment, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) Create(data pkg.Equipment) (*pkg.Equipment, error) {
+	r.equipment[data.ID] = &data
+	return &data, nil
+}
+
+func (r *fakeRepository) List(page, perPage int) ([]*pkg.Equipment, int, error) {
+	return nil, 0, nil
+}
+
+func (r *fakeRepository) Update(id string, data pkg.Equipment) (*pkg.Equipment, error) {
+	if hook := r.beforeUpdate; hook != nil {
+		r.beforeUpdate = nil
+		hook()
+	}
+
+	if r.equipment[id].Version != data.Version {
+		return nil, pkg.ErrVersionConflict
+	}
+
+	data.Version++
+	r.equipment[id] = &data
+	return &data, nil
+}
+
+func (r *fakeRepository) Delete(id string) error {
+	delete(r.equipment, id)
+	return nil
+}
+
+func (r *fakeRepository) Book(booking pkg.Booking) error {
+	if r.bookErr != nil {
+		return r.bookErr
+	}
+	r.bookings = append(r.bookings, &booking)
+	return nil
+}
+
+func (r *fakeRepository) Release(equipmentID, rentID string, qty int) error {
+	return nil
+}
+
+func (r *fakeRepository) ListBookings(equipmentID string) ([]*pkg.Booking, error) {
+	bookings := make([]*pkg.Booking, 0)
+	for _, booking := range r.bookings {
+		if booking.EquipmentID == equipmentID {
+			bookings = append(bookings, booking)
+		}
+	}
+	return bookings, nil
+}
+
+func (r *fakeRepository) ExtendBookings(rentID string, endDate time.Time) error {
+	return nil
+}
@@ -1,6 +1,10 @@
 package pkg
 
-import "github.com/go-kit/log"
+import (
+	"time"
+
+	"github.com/go-kit/log"
+)
 
 type loggingService struct {
 	next   Service
@@ -23,6 +27,18 @@ func (l *loggingService) GetEquipment(id string) (equipment *Equipment, err erro
 	return l.next.GetEquipment(id)
 }
 
+func (l *loggingService) GetEquipmentByIDs(ids []string) (equipment []*Equipment, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetEquipmentByIDs",
+			"ids", ids,
+			"equipment", equipment,
+			"err", err,
+		)
+	}()
+	return l.next.GetEquipmentByIDs(ids)
+}
+
 func (l *loggingService) CreateEquipment(data Equipment) (equipment *Equipment, err error) {
 	defer func() {
 		l.logger.Log(
@@ -73,26 +89,68 @@ func (l *loggingService) ListEquipment(page, perPage int) (equipment []*Equipmen
 	return l.next.ListEquipment(page, perPage)
 }
 
-func (l *loggingService) ReduceStock(id string, qty int64) (err error) {
+func (l *loggingService) ReduceStock(id string, qty int64, booking *Booking) (err error) {
 	defer func() {
 		l.logger.Log(
 			"method", "ReduceStock",
 			"id", id,
 			"qty", qty,
+			"booking", booking,
 			"err", err,
 		)
 	}()
-	return l.next.ReduceStock(id, qty)
+	return l.next.ReduceStock(id, qty, booking)
 }
 
-func (l *loggingService) RestoreStock(id string, qty int64) (err error) {
+func (l *loggingService) RestoreStock(id string, qty int64, rentID string) (err error) {
 	defer func() {
 		l.logger.Log(
 			"method", "RestoreStock",
 			"id", id,
 			"qty", qty,
+			"rentID", rentID,
+			"err", err,
+		)
+	}()
+	return l.next.RestoreStock(id, qty, rentID)
+}
+
+func (l *loggingService) WriteOffStock(id string, qty int64, rentID string) (err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "WriteOffStock",
+			"id", id,
+			"qty", qty,
+			"rentID", rentID,
+			"err", err,
+		)
+	}()
+	return l.next.WriteOffStock(id, qty, rentID)
+}
+
+func (l *loggingService) GetAvailability(id string, from, to time.Time, rentID string) (qty int, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetAvailability",
+			"id", id,
+			"from", from,
+			"to", to,
+			"rentID", rentID,
+			"qty", qty,
+			"err", err,
+		)
+	}()
+	return l.next.GetAvailability(id, from, to, rentID)
+}
+
+func (l *loggingService) ExtendBooking(rentID string, endDate time.Time) (err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "ExtendBooking",
+			"rentID", rentID,
+			"endDate", endDate,
 			"err", err,
 		)
 	}()
-	return l.next.RestoreStock(id, qty)
+	return l.next.ExtendBooking(rentID, endDate)
 }
@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Money is an amount in cents. Amounts are added up as integers so totals do
+// not collect floating point errors, but they are still read and written as
+// decimal numbers, keeping the shape of the API and of stored documents.
+type Money int64
+
+// NewMoney rounds value to the cent.
+func NewMoney(value float64) Money {
+	return Money(math.Round(value * 100))
+}
+
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// Times multiplies the amount by a quantity.
+func (m Money) Times(qty int) Money {
+	return m * Money(qty)
+}
+
+// Mul multiplies the amount by a factor, like a rate, rounding to the cent.
+func (m Money) Mul(factor float64) Money {
+	return Money(math.Round(float64(m) * factor))
+}
+
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, m/100, m%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" || text == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid money %s", data)
+	}
+
+	*m = NewMoney(value)
+	return nil
+}
+
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(m.Float64())
+}
+
+// UnmarshalBSONValue reads the amounts stored as doubles, as well as the ones
+// stored as integers by hand.
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: t, Value: data}
+
+	switch t {
+	case bsontype.Double:
+		*m = NewMoney(value.Double())
+	case bsontype.Int32:
+		*m = Money(value.Int32()) * 100
+	case bsontype.Int64:
+		*m = Money(value.Int64()) * 100
+	case bsontype.Null, bsontype.Undefined:
+		*m = 0
+	default:
+		return fmt.Errorf("cannot decode %s into money", t)
+	}
+
+	return nil
+}
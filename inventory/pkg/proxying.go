@@ -16,12 +16,16 @@ func CreateAuthEndpoints(endpoints Set, cc *grpc.ClientConn) Set {
 
 	return Set{
 		Get:          verify(endpoints.Get),
+		GetByIDs:     endpoints.GetByIDs,
 		List:         verify(endpoints.List),
 		Create:       verify(endpoints.Create),
 		Update:       verify(endpoints.Update),
 		Delete:       verify(endpoints.Delete),
 		ReduceStock:  endpoints.ReduceStock,
 		RestoreStock: endpoints.RestoreStock,
+		Availability: endpoints.Availability,
+		Extend:       endpoints.Extend,
+		WriteOff:     endpoints.WriteOff,
 	}
 }
 
@@ -76,12 +80,16 @@ func FetchSupplierEndpoints(endpoints Set, cc *grpc.ClientConn) Set {
 
 	return Set{
 		Get:          fetchSupplier(endpoints.Get),
+		GetByIDs:     endpoints.GetByIDs,
 		Create:       fetchSupplier(endpoints.Create),
 		List:         fetchSuppliers(endpoints.List),
 		Update:       fetchSupplier(endpoints.Update),
 		Delete:       endpoints.Delete,
 		ReduceStock:  endpoints.ReduceStock,
 		RestoreStock: endpoints.RestoreStock,
+		Availability: endpoints.Availability,
+		Extend:       endpoints.Extend,
+		WriteOff:     endpoints.WriteOff,
 	}
 }
 
@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,12 +12,20 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrVersionConflict = errors.New("version conflict")
+
 type Repository interface {
 	Get(string) (*Equipment, error)
+	GetByIDs([]string) ([]*Equipment, error)
 	Create(Equipment) (*Equipment, error)
 	List(page, perPage int) ([]*Equipment, int, error)
 	Update(string, Equipment) (*Equipment, error)
 	Delete(string) error
+
+	Book(Booking) error
+	Release(equipmentID, rentID string, qty int) error
+	ListBookings(equipmentID string) ([]*Booking, error)
+	ExtendBookings(rentID string, endDate time.Time) error
 }
 
 type mongoRepository struct {
@@ -67,6 +76,25 @@ func (r *mongoRepository) Get(id string) (*Equipment, error) {
 	return equipment, err
 }
 
+func (r *mongoRepository) GetByIDs(ids []string) ([]*Equipment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	collection := r.database.Collection("equipment")
+
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+
+	equipment := make([]*Equipment, 0)
+	if err := cursor.All(ctx, &equipment); err != nil {
+		return nil, err
+	}
+
+	return equipment, nil
+}
+
 func (r *mongoRepository) List(page, perPage int) ([]*Equipment, int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	collection := r.database.Collection("equipment")
@@ -101,14 +129,33 @@ func (r *mongoRepository) Update(id string, data Equipment) (*Equipment, error)
 
 	defer cancel()
 
-	_, err := collection.ReplaceOne(ctx, bson.M{"_id": id}, data)
+	filter := versionFilter(id, data.Version)
+	data.Version++
+
+	result, err := collection.ReplaceOne(ctx, filter, data)
 	if err != nil {
 		return nil, err
 	}
 
+	if result.MatchedCount == 0 {
+		return nil, ErrVersionConflict
+	}
+
 	return r.Get(id)
 }
 
+// versionFilter matches the document only if it is still at version.
+// Documents saved before versions existed have none and are at version 0.
+func versionFilter(id string, version int) bson.M {
+	if version == 0 {
+		return bson.M{"_id": id, "$or": bson.A{
+			bson.M{"version": 0},
+			bson.M{"version": bson.M{"$exists": false}},
+		}}
+	}
+	return bson.M{"_id": id, "version": version}
+}
+
 func (r *mongoRepository) Delete(id string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	collection := r.database.Collection("equipment")
@@ -122,3 +169,74 @@ func (r *mongoRepository) Delete(id string) error {
 
 	return nil
 }
+
+func (r *mongoRepository) Book(booking Booking) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	collection := r.database.Collection("bookings")
+
+	defer cancel()
+
+	filter := bson.M{"equipment_id": booking.EquipmentID, "rent_id": booking.RentID}
+	update := bson.M{
+		"$inc":         bson.M{"qty": booking.Qty},
+		"$set":         bson.M{"start_date": booking.StartDate, "end_date": booking.EndDate},
+		"$setOnInsert": bson.M{"_id": primitive.NewObjectID().Hex()},
+	}
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *mongoRepository) Release(equipmentID, rentID string, qty int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	collection := r.database.Collection("bookings")
+
+	defer cancel()
+
+	filter := bson.M{"equipment_id": equipmentID, "rent_id": rentID}
+	if _, err := collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"qty": -qty}}); err != nil {
+		return err
+	}
+
+	_, err := collection.DeleteMany(ctx, bson.M{
+		"equipment_id": equipmentID,
+		"rent_id":      rentID,
+		"qty":          bson.M{"$lte": 0},
+	})
+
+	return err
+}
+
+func (r *mongoRepository) ListBookings(equipmentID string) ([]*Booking, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	collection := r.database.Collection("bookings")
+
+	defer cancel()
+
+	result, err := collection.Find(ctx, bson.M{"equipment_id": equipmentID})
+	if err != nil {
+		return nil, err
+	}
+
+	bookings := make([]*Booking, 0)
+	if err := result.All(ctx, &bookings); err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+func (r *mongoRepository) ExtendBookings(rentID string, endDate time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	collection := r.database.Collection("bookings")
+
+	defer cancel()
+
+	_, err := collection.UpdateMany(
+		ctx,
+		bson.M{"rent_id": rentID},
+		bson.M{"$set": bson.M{"end_date": endDate}},
+	)
+
+	return err
+}
@@ -1,6 +1,11 @@
 package pkg
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
 
 type Equipment struct {
 	ID             string          `json:"id" bson:"_id,omitempty" validate:"omitempty,required"`
@@ -8,13 +13,23 @@ type Equipment struct {
 	Stock          int             `json:"in_stock" validate:"omitempty,number"`
 	EffectiveStock int             `json:"effective_qty" validate:"omitempty,number"`
 	Weight         float64         `json:"weight" validate:"omitempty,numeric"`
-	UnitValue      float64         `json:"unit_value" validate:"omitempty,numeric"`
-	PurchaseValue  float64         `json:"purchase_value" validate:"omitempty,numeric"`
-	ReplaceValue   float64         `json:"replace_value" validate:"omitempty,numeric"`
+	UnitValue      Money           `json:"unit_value" validate:"omitempty,numeric"`
+	PurchaseValue  Money           `json:"purchase_value" validate:"omitempty,numeric"`
+	ReplaceValue   Money           `json:"replace_value" validate:"omitempty,numeric"`
 	MinQty         int             `json:"min_qty" validate:"omitempty,number"`
+	LateFeeRate    float64         `json:"late_fee_rate" validate:"omitempty,min=0"`
 	SupplierID     string          `json:"supplier_id,omitempty" validate:"omitempty,supplier"`
 	Supplier       *Supplier       `json:"supplier"`
 	RentingValues  []*RentingValue `json:"renting_values" validate:"required,dive"`
+	Version        int             `json:"version"`
+}
+
+// Headers sends the version of the equipment as its ETag, so that clients can
+// send it back in If-Match when updating it.
+func (e *Equipment) Headers() http.Header {
+	headers := http.Header{}
+	headers.Set("ETag", fmt.Sprintf("\"%d\"", e.Version))
+	return headers
 }
 
 type Supplier struct {
@@ -42,7 +57,7 @@ type Address struct {
 type RentingValue struct {
 	PeriodID string  `json:"period_id" validate:"required"`
 	Period   *Period `json:"period,omitempty"`
-	Value    float64 `json:"value"`
+	Value    Money   `json:"value"`
 }
 
 type Period struct {
@@ -51,14 +66,29 @@ type Period struct {
 	QtyDays int32  `json:"qty_days"`
 }
 
+// Booking keeps the pieces of an equipment held by a rent, so that the stock
+// can be checked against the rent periods instead of only today's counter.
+type Booking struct {
+	ID          string    `json:"id" bson:"_id,omitempty"`
+	EquipmentID string    `json:"equipment_id" bson:"equipment_id"`
+	RentID      string    `json:"rent_id" bson:"rent_id"`
+	Qty         int       `json:"qty" bson:"qty"`
+	StartDate   time.Time `json:"start_date" bson:"start_date"`
+	EndDate     time.Time `json:"end_date" bson:"end_date"`
+}
+
 type Service interface {
 	CreateEquipment(Equipment) (*Equipment, error)
 	ListEquipment(page, perPage int) ([]*Equipment, int, error)
 	UpdateEquipment(string, Equipment) (*Equipment, error)
 	DeleteEquipment(string) error
 	GetEquipment(string) (*Equipment, error)
-	ReduceStock(string, int64) error
-	RestoreStock(string, int64) error
+	GetEquipmentByIDs([]string) ([]*Equipment, error)
+	ReduceStock(id string, qty int64, booking *Booking) error
+	RestoreStock(id string, qty int64, rentID string) error
+	GetAvailability(id string, from, to time.Time, rentID string) (int, error)
+	ExtendBooking(rentID string, endDate time.Time) error
+	WriteOffStock(id string, qty int64, rentID string) error
 }
 
 type service struct {
@@ -82,7 +112,8 @@ func (s *service) ListEquipment(page, perPage int) ([]*Equipment, int, error) {
 }
 
 func (s *service) UpdateEquipment(id string, data Equipment) (*Equipment, error) {
-	if _, err := s.repository.Get(id); err != nil {
+	curr, err := s.repository.Get(id)
+	if err != nil {
 		return nil, NewError(
 			http.StatusNotFound,
 			"equipment not found",
@@ -90,11 +121,19 @@ func (s *service) UpdateEquipment(id string, data Equipment) (*Equipment, error)
 		)
 	}
 
+	if data.Version != curr.Version {
+		return nil, errVersionConflict
+	}
+
 	if err := s.validator.Validate(data); err != nil {
 		return nil, err
 	}
 
 	equipment, err := s.repository.Update(id, data)
+	if err == ErrVersionConflict {
+		return nil, errVersionConflict
+	}
+
 	if err != nil {
 		return nil, NewError(
 			http.StatusInternalServerError,
@@ -138,24 +177,232 @@ func (s *service) GetEquipment(id string) (*Equipment, error) {
 	return equipment, nil
 }
 
-func (s *service) ReduceStock(id string, qty int64) error {
-	equipment, err := s.repository.Get(id)
+func (s *service) GetEquipmentByIDs(ids []string) ([]*Equipment, error) {
+	equipment, err := s.repository.GetByIDs(ids)
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error fetching equipment",
+			"something went wrong while fetching equipment",
+		)
+	}
+	return equipment, nil
+}
 
-	if err == nil {
-		equipment.EffectiveStock -= int(qty)
-		_, err = s.repository.Update(id, *equipment)
+// ReduceStock takes the pieces out of the effective stock, booking them for
+// the rent period. The pieces must be free for the period, which is checked
+// against the same version of the equipment being changed, so that concurrent
+// rents cannot take the same pieces.
+func (s *service) ReduceStock(id string, qty int64, booking *Booking) error {
+	err := s.changeStock(id, 0, -int(qty), func(equipment *Equipment) error {
+		return s.checkAvailable(equipment, int(qty), booking)
+	})
+
+	if err != nil || booking == nil {
+		return err
+	}
+
+	booking.EquipmentID = id
+	booking.Qty = int(qty)
+	if err := s.repository.Book(*booking); err != nil {
+		// the pieces were taken out for a booking that does not exist
+		s.changeStock(id, 0, int(qty), nil)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) RestoreStock(id string, qty int64, rentID string) error {
+	err := s.changeStock(id, 0, int(qty), nil)
+
+	if err == nil && rentID != "" {
+		err = s.repository.Release(id, rentID, int(qty))
 	}
 
 	return err
 }
 
-func (s *service) RestoreStock(id string, qty int64) error {
-	equipment, err := s.repository.Get(id)
+// WriteOffStock removes pieces lost by a rent from the stock for good. They
+// were already taken out of the effective stock when rented, so only the
+// stock and the booking change.
+func (s *service) WriteOffStock(id string, qty int64, rentID string) error {
+	err := s.changeStock(id, -int(qty), 0, nil)
 
-	if err == nil {
-		equipment.EffectiveStock += int(qty)
-		_, err = s.repository.Update(id, *equipment)
+	if err == nil && rentID != "" {
+		err = s.repository.Release(id, rentID, int(qty))
 	}
 
 	return err
 }
+
+// changeStock adds the deltas to the stock and effective stock of the
+// equipment, once check accepts the version read. Stock changes do not come
+// from someone editing the equipment, so they are retried when the equipment
+// is changed in the meantime instead of failing.
+func (s *service) changeStock(id string, stock, effective int, check func(*Equipment) error) error {
+	for attempt := 0; attempt < 3; attempt++ {
+		equipment, err := s.repository.Get(id)
+		if err != nil {
+			return err
+		}
+
+		if check != nil {
+			if err := check(equipment); err != nil {
+				return err
+			}
+		}
+
+		equipment.Stock += stock
+		equipment.EffectiveStock += effective
+		if _, err = s.repository.Update(id, *equipment); err != ErrVersionConflict {
+			return err
+		}
+	}
+
+	return ErrVersionConflict
+}
+
+// checkAvailable fails when qty pieces are not free for the booking period.
+// Pieces reduced without a booking must be in the effective stock. Bookings
+// written after the equipment changed are still counted, as their pieces are
+// already out of the effective stock until then.
+func (s *service) checkAvailable(equipment *Equipment, qty int, booking *Booking) error {
+	available := equipment.EffectiveStock
+
+	if booking != nil {
+		bookings, err := s.repository.ListBookings(equipment.ID)
+		if err != nil {
+			return NewError(
+				http.StatusInternalServerError,
+				"error fetching bookings",
+				"something went wrong while checking availability",
+			)
+		}
+
+		available = getAvailable(equipment, bookings, booking.StartDate, booking.EndDate, "")
+	}
+
+	if available < qty {
+		return NewError(
+			http.StatusConflict,
+			"not enough stock",
+			fmt.Sprintf("only %d pieces of %s are available", available, equipment.Description),
+		)
+	}
+
+	return nil
+}
+
+// GetAvailability returns how many pieces of the equipment are free during the
+// given period. The bookings of rentID are left out, so a rent can be checked
+// against the stock it already holds.
+func (s *service) GetAvailability(id string, from, to time.Time, rentID string) (int, error) {
+	equipment, err := s.repository.Get(id)
+	if err != nil {
+		return 0, NewError(
+			http.StatusNotFound,
+			"equipment not found",
+			"could not find the equipment you're looking for",
+		)
+	}
+
+	if !to.After(from) {
+		return 0, NewError(
+			http.StatusBadRequest,
+			"invalid period",
+			"the end of the period must be after its start",
+		)
+	}
+
+	bookings, err := s.repository.ListBookings(id)
+	if err != nil {
+		return 0, NewError(
+			http.StatusInternalServerError,
+			"error fetching bookings",
+			"something went wrong while checking availability",
+		)
+	}
+
+	return getAvailable(equipment, bookings, from, to, rentID), nil
+}
+
+func getAvailable(equipment *Equipment, bookings []*Booking, from, to time.Time, rentID string) int {
+	booked := 0
+	others := make([]*Booking, 0, len(bookings))
+	for _, booking := range bookings {
+		booked += booking.Qty
+		if rentID == "" || booking.RentID != rentID {
+			others = append(others, booking)
+		}
+	}
+
+	// pieces taken out of the stock without a booking, like rents made
+	// before bookings existed, or booked by a rent still being saved, are
+	// unavailable for any period
+	untracked := equipment.Stock - equipment.EffectiveStock - booked
+	if untracked < 0 {
+		untracked = 0
+	}
+
+	available := equipment.Stock - untracked - PeakBooked(others, from, to, time.Now())
+	if available < 0 {
+		available = 0
+	}
+
+	return available
+}
+
+func (s *service) ExtendBooking(rentID string, endDate time.Time) error {
+	if err := s.repository.ExtendBookings(rentID, endDate); err != nil {
+		return NewError(
+			http.StatusInternalServerError,
+			"error extending booking",
+			"something went wrong while extending the rent bookings",
+		)
+	}
+	return nil
+}
+
+// PeakBooked returns the highest amount of pieces booked at the same time
+// within the given period. Bookings whose end date is past now are still held,
+// so they are considered to last until now.
+func PeakBooked(bookings []*Booking, from, to, now time.Time) int {
+	type event struct {
+		at  time.Time
+		qty int
+	}
+
+	events := make([]event, 0, len(bookings)*2)
+	for _, booking := range bookings {
+		start, end := booking.StartDate, booking.EndDate
+		if end.Before(now) {
+			end = now
+		}
+
+		if !start.Before(to) || !end.After(from) {
+			continue
+		}
+
+		events = append(events, event{start, booking.Qty}, event{end, -booking.Qty})
+	}
+
+	// ends come before starts at the same instant, so back to back
+	// bookings do not overlap
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].qty < events[j].qty
+		}
+		return events[i].at.Before(events[j].at)
+	})
+
+	peak, curr := 0, 0
+	for _, event := range events {
+		curr += event.qty
+		if curr > peak {
+			peak = curr
+		}
+	}
+
+	return peak
+}
@@ -0,0 +1,119 @@
+package pkg_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/inventory/pkg"
+)
+
+func TestPeakBooked(t *testing.T) {
+	now := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time {
+		return now.AddDate(0, 0, d)
+	}
+
+	bookings := []*pkg.Booking{
+		{Qty: 10, StartDate: day(0), EndDate: day(10)},
+		{Qty: 5, StartDate: day(5), EndDate: day(15)},
+		{Qty: 7, StartDate: day(15), EndDate: day(20)},
+	}
+
+	t.Run("overlapping bookings", func(t *testing.T) {
+		if got := pkg.PeakBooked(bookings, day(0), day(20), now); got != 15 {
+			t.Errorf("expected 15 booked pieces, got %d", got)
+		}
+	})
+
+	t.Run("back to back bookings", func(t *testing.T) {
+		if got := pkg.PeakBooked(bookings, day(12), day(20), now); got != 7 {
+			t.Errorf("expected 7 booked pieces, got %d", got)
+		}
+	})
+
+	t.Run("period without bookings", func(t *testing.T) {
+		if got := pkg.PeakBooked(bookings, day(20), day(30), now); got != 0 {
+			t.Errorf("expected no booked pieces, got %d", got)
+		}
+	})
+
+	t.Run("overdue bookings are still held", func(t *testing.T) {
+		overdue := []*pkg.Booking{
+			{Qty: 3, StartDate: day(-10), EndDate: day(-2)},
+		}
+		if got := pkg.PeakBooked(overdue, day(-1), day(1), now); got != 3 {
+			t.Errorf("expected 3 booked pieces, got %d", got)
+		}
+	})
+}
+
+func TestReduceStock(t *testing.T) {
+	start := time.Now().AddDate(0, 0, 1)
+	booking := func(rentID string, days int) *pkg.Booking {
+		return &pkg.Booking{RentID: rentID, StartDate: start, EndDate: start.AddDate(0, 0, days)}
+	}
+
+	setup := func() (pkg.Service, *fakeRepository) {
+		repository := newFakeRepository(&pkg.Equipment{ID: "equipment", Description: "Andaime", Stock: 10, EffectiveStock: 10})
+		return pkg.NewService(&fakeValidator{}, repository), repository
+	}
+
+	t.Run("books free pieces", func(t *testing.T) {
+		svc, repository := setup()
+
+		if err := svc.ReduceStock("equipment", 6, booking("first", 7)); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if repository.equipment["equipment"].EffectiveStock != 4 || len(repository.bookings) != 1 {
+			t.Errorf("expected 6 pieces booked, got %+v", repository.equipment["equipment"])
+		}
+	})
+
+	t.Run("refuses pieces already booked", func(t *testing.T) {
+		svc, repository := setup()
+		svc.ReduceStock("equipment", 6, booking("first", 7))
+
+		err := svc.ReduceStock("equipment", 6, booking("second", 7))
+		if e, ok := err.(pkg.Error); !ok || e.StatusCode() != http.StatusConflict {
+			t.Fatalf("expected conflict, got %v", err)
+		}
+
+		if repository.equipment["equipment"].EffectiveStock != 4 {
+			t.Errorf("expected stock to be left alone, got %d", repository.equipment["equipment"].EffectiveStock)
+		}
+	})
+
+	t.Run("refuses pieces taken in the meantime", func(t *testing.T) {
+		svc, repository := setup()
+		repository.beforeUpdate = func() {
+			if err := svc.ReduceStock("equipment", 6, booking("first", 7)); err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		}
+
+		err := svc.ReduceStock("equipment", 6, booking("second", 7))
+		if e, ok := err.(pkg.Error); !ok || e.StatusCode() != http.StatusConflict {
+			t.Fatalf("expected conflict, got %v", err)
+		}
+
+		if repository.equipment["equipment"].EffectiveStock != 4 {
+			t.Errorf("expected the stock of the first rent only, got %d", repository.equipment["equipment"].EffectiveStock)
+		}
+	})
+
+	t.Run("gives the pieces back when the booking fails", func(t *testing.T) {
+		svc, repository := setup()
+		repository.bookErr = errors.New("connection lost")
+
+		if err := svc.ReduceStock("equipment", 6, booking("first", 7)); err == nil {
+			t.Fatal("expected error")
+		}
+
+		if repository.equipment["equipment"].EffectiveStock != 10 {
+			t.Errorf("expected stock to be restored, got %d", repository.equipment["equipment"].EffectiveStock)
+		}
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-kit/kit/auth/jwt"
 	amqptransport "github.com/go-kit/kit/transport/amqp"
@@ -104,6 +106,10 @@ func decodeUpdateRequest(ctx context.Context, r *http.Request) (any, error) {
 		)
 	}
 
+	if version, ok := ifMatchVersion(r); ok {
+		equipment.Version = version
+	}
+
 	return UpdateRequest{
 		ID:   params.ByName("id"),
 		Data: equipment,
@@ -115,6 +121,22 @@ type UpdateRequest struct {
 	Data Equipment `json:"data"`
 }
 
+// ifMatchVersion reads the version sent in the If-Match header. Without it,
+// or with a wildcard, the version in the body is used.
+func ifMatchVersion(r *http.Request) (int, bool) {
+	etag := strings.TrimPrefix(r.Header.Get("If-Match"), "W/")
+	if etag == "" || etag == "*" {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(strings.Trim(etag, `"`))
+	if err != nil {
+		return -1, true
+	}
+
+	return version, true
+}
+
 func URLParamDecoder(param string) httptransport.DecodeRequestFunc {
 	return func(ctx context.Context, r *http.Request) (any, error) {
 		params := httprouter.ParamsFromContext(r.Context())
@@ -131,7 +153,11 @@ type grpcServer struct {
 	proto.UnimplementedInventoryServer
 	reduceStock  grpc.Handler
 	getEquipment grpc.Handler
+	getByIDs     grpc.Handler
 	restoreStock grpc.Handler
+	availability grpc.Handler
+	extend       grpc.Handler
+	writeOff     grpc.Handler
 }
 
 func NewGRPCServer(endpoints Set) proto.InventoryServer {
@@ -146,11 +172,31 @@ func NewGRPCServer(endpoints Set) proto.InventoryServer {
 			decodeGetRequest,
 			encodeEquipmentResponse,
 		),
+		getByIDs: grpc.NewServer(
+			endpoints.GetByIDs,
+			decodeGetByIDsRequest,
+			encodeEquipmentListResponse,
+		),
 		restoreStock: grpc.NewServer(
 			endpoints.RestoreStock,
 			decodeRestoreStockRequest,
 			NopGRPCEncoder,
 		),
+		availability: grpc.NewServer(
+			endpoints.Availability,
+			decodeAvailabilityRequest,
+			encodeAvailabilityResponse,
+		),
+		extend: grpc.NewServer(
+			endpoints.Extend,
+			decodeExtendRequest,
+			NopGRPCEncoder,
+		),
+		writeOff: grpc.NewServer(
+			endpoints.WriteOff,
+			decodeWriteOffStockRequest,
+			NopGRPCEncoder,
+		),
 	}
 }
 
@@ -162,8 +208,19 @@ func (s *grpcServer) GetEquipment(ctx context.Context, r *proto.GetRequest) (*pr
 	return reply.(*proto.Equipment), nil
 }
 
+func (s *grpcServer) GetEquipmentByIDs(ctx context.Context, r *proto.GetByIDsRequest) (*proto.EquipmentReply, error) {
+	_, reply, err := s.getByIDs.ServeGRPC(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return reply.(*proto.EquipmentReply), nil
+}
+
 func (s *grpcServer) ReduceStock(ctx context.Context, req *proto.ReduceStockRequest) (*proto.ReduceStockReply, error) {
 	_, _, err := s.reduceStock.ServeGRPC(ctx, req)
+	if e, ok := err.(Error); ok {
+		return &proto.ReduceStockReply{Err: e.Detail, Status: uint32(e.StatusCode())}, nil
+	}
 	if err != nil {
 		return &proto.ReduceStockReply{Err: err.Error()}, nil
 	}
@@ -178,12 +235,39 @@ func (s *grpcServer) RestoreStock(ctx context.Context, req *proto.RestoreStockRe
 	return &proto.RestoreStockReply{}, nil
 }
 
+func (s *grpcServer) GetAvailability(ctx context.Context, req *proto.AvailabilityRequest) (*proto.AvailabilityReply, error) {
+	_, reply, err := s.availability.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return reply.(*proto.AvailabilityReply), nil
+}
+
+func (s *grpcServer) ExtendBooking(ctx context.Context, req *proto.ExtendBookingRequest) (*proto.ExtendBookingReply, error) {
+	_, _, err := s.extend.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.ExtendBookingReply{}, nil
+}
+
+func (s *grpcServer) WriteOffStock(ctx context.Context, req *proto.WriteOffStockRequest) (*proto.WriteOffStockReply, error) {
+	_, _, err := s.writeOff.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.WriteOffStockReply{}, nil
+}
+
 func decodeReduceStockRequest(ctx context.Context, req any) (any, error) {
 	item := req.(*proto.ReduceStockRequest)
 
 	return ReduceStockRequest{
-		Equip: item.GetId(),
-		Qty:   item.GetQty(),
+		Equip:     item.GetId(),
+		Qty:       item.GetQty(),
+		Rent:      item.GetRentId(),
+		StartDate: item.GetStartDate().AsTime(),
+		EndDate:   item.GetEndDate().AsTime(),
 	}, nil
 }
 
@@ -193,21 +277,60 @@ func decodeRestoreStockRequest(ctx context.Context, r any) (any, error) {
 	return RestoreStockRequest{
 		Equip: item.GetId(),
 		Qty:   item.GetQty(),
+		Rent:  item.GetRentId(),
 	}, nil
 }
 
+func decodeWriteOffStockRequest(ctx context.Context, r any) (any, error) {
+	item := r.(*proto.WriteOffStockRequest)
+
+	return RestoreStockRequest{
+		Equip: item.GetId(),
+		Qty:   item.GetQty(),
+		Rent:  item.GetRentId(),
+	}, nil
+}
+
+func decodeAvailabilityRequest(ctx context.Context, r any) (any, error) {
+	req := r.(*proto.AvailabilityRequest)
+
+	return AvailabilityRequest{
+		Equip:     req.GetId(),
+		StartDate: req.GetStartDate().AsTime(),
+		EndDate:   req.GetEndDate().AsTime(),
+		Rent:      req.GetRentId(),
+	}, nil
+}
+
+func decodeExtendRequest(ctx context.Context, r any) (any, error) {
+	req := r.(*proto.ExtendBookingRequest)
+
+	return ExtendRequest{
+		Rent:    req.GetRentId(),
+		EndDate: req.GetEndDate().AsTime(),
+	}, nil
+}
+
+func encodeAvailabilityResponse(ctx context.Context, r any) (any, error) {
+	return &proto.AvailabilityReply{Qty: int64(r.(int))}, nil
+}
+
 func NopGRPCEncoder(ctx context.Context, res any) (any, error) {
 	return nil, nil
 }
 
 type ReduceStockRequest struct {
-	Equip string `json:"equip_id"`
-	Qty   int64  `json:"qty"`
+	Equip     string    `json:"equip_id"`
+	Qty       int64     `json:"qty"`
+	Rent      string    `json:"rent_id"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
 }
 
 type RestoreStockRequest struct {
 	Equip string `json:"equip_id"`
 	Qty   int64  `json:"qty"`
+	Rent  string `json:"rent_id"`
 }
 
 func decodeGetRequest(ctx context.Context, r any) (any, error) {
@@ -215,6 +338,26 @@ func decodeGetRequest(ctx context.Context, r any) (any, error) {
 	return req.GetId(), nil
 }
 
+func decodeGetByIDsRequest(ctx context.Context, r any) (any, error) {
+	req := r.(*proto.GetByIDsRequest)
+	return req.GetIds(), nil
+}
+
+func encodeEquipmentListResponse(ctx context.Context, r any) (any, error) {
+	equipment := r.([]*Equipment)
+	reply := &proto.EquipmentReply{Equipment: make([]*proto.Equipment, len(equipment))}
+
+	for i, item := range equipment {
+		encoded, err := encodeEquipmentResponse(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		reply.Equipment[i] = encoded.(*proto.Equipment)
+	}
+
+	return reply, nil
+}
+
 func encodeEquipmentResponse(ctx context.Context, r any) (any, error) {
 	equipment := r.(*Equipment)
 	supplier := &proto.Supplier{}
@@ -233,13 +376,17 @@ func encodeEquipmentResponse(ctx context.Context, r any) (any, error) {
 	rentingValues := make([]*proto.RentingValue, len(equipment.RentingValues))
 	for i, value := range equipment.RentingValues {
 		rentingValues[i] = &proto.RentingValue{
-			Value: value.Value,
+			Value: value.Value.Float64(),
 			Period: &proto.Period{
-				Id:      value.PeriodID,
-				Name:    value.PeriodID,
-				QtyDays: 55,
+				Id:   value.PeriodID,
+				Name: value.PeriodID,
 			},
 		}
+
+		if value.Period != nil {
+			rentingValues[i].Period.Name = value.Period.Name
+			rentingValues[i].Period.QtyDays = value.Period.QtyDays
+		}
 	}
 
 	return &proto.Equipment{
@@ -248,12 +395,13 @@ func encodeEquipmentResponse(ctx context.Context, r any) (any, error) {
 		Stock:          int64(equipment.Stock),
 		EffectiveStock: int64(equipment.EffectiveStock),
 		Weight:         equipment.Weight,
-		UnitValue:      equipment.UnitValue,
-		PurchaseValue:  equipment.PurchaseValue,
-		ReplaceValue:   equipment.ReplaceValue,
+		UnitValue:      equipment.UnitValue.Float64(),
+		PurchaseValue:  equipment.PurchaseValue.Float64(),
+		ReplaceValue:   equipment.ReplaceValue.Float64(),
 		MinQty:         int64(equipment.MinQty),
 		Supplier:       supplier,
 		RentingValues:  rentingValues,
+		LateFeeRate:    equipment.LateFeeRate,
 	}, nil
 }
 
@@ -299,8 +447,11 @@ func NewSubscriber(endpoints Set, conn *amqp.Connection) {
 
 func decodeReduceStockAMQPRequest(ctx context.Context, d *amqp.Delivery) (any, error) {
 	var item struct {
-		EquipmentID string `json:"equipment_id"`
-		Qty         int    `json:"qty"`
+		EquipmentID string    `json:"equipment_id"`
+		Qty         int       `json:"qty"`
+		RentID      string    `json:"rent_id"`
+		StartDate   time.Time `json:"start_date"`
+		EndDate     time.Time `json:"end_date"`
 	}
 
 	if err := json.Unmarshal(d.Body, &item); err != nil {
@@ -308,7 +459,10 @@ func decodeReduceStockAMQPRequest(ctx context.Context, d *amqp.Delivery) (any, e
 	}
 
 	return ReduceStockRequest{
-		Equip: item.EquipmentID,
-		Qty:   int64(item.Qty),
+		Equip:     item.EquipmentID,
+		Qty:       int64(item.Qty),
+		Rent:      item.RentID,
+		StartDate: item.StartDate,
+		EndDate:   item.EndDate,
 	}, nil
 }
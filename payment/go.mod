@@ -2,7 +2,11 @@ module reconcip.com.br/microservices/payment
 
 go 1.19
 
-require github.com/go-kit/kit v0.12.0
+require (
+	github.com/go-kit/kit v0.12.0
+	google.golang.org/grpc v1.40.0
+	google.golang.org/protobuf v1.27.1
+)
 
 require (
 	github.com/go-playground/locales v0.14.0 // indirect
@@ -24,14 +28,13 @@ require (
 	golang.org/x/sys v0.0.0-20210917161153-d61c044b1678 // indirect
 	golang.org/x/text v0.3.7 // indirect
 	google.golang.org/genproto v0.0.0-20210917145530-b395a37504d4 // indirect
-	google.golang.org/grpc v1.40.0 // indirect
-	google.golang.org/protobuf v1.27.1 // indirect
 )
 
 require (
-	github.com/go-kit/log v0.2.0 // indirect
+	github.com/go-kit/log v0.2.0
 	github.com/go-logfmt/logfmt v0.5.1 // indirect
 	github.com/go-playground/validator v9.31.0+incompatible
 	github.com/julienschmidt/httprouter v1.3.0
+	github.com/streadway/amqp v1.0.0
 	go.mongodb.org/mongo-driver v1.11.1
 )
@@ -3,6 +3,7 @@ package pkg
 import (
 	"context"
 	"math"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
 )
@@ -14,23 +15,36 @@ type Set struct {
 	DeletePaymentMethod endpoint.Endpoint
 	GetPaymentMethod    endpoint.Endpoint
 
+	GetPaymentMethodsByIDs endpoint.Endpoint
+
 	CreatePaymentType endpoint.Endpoint
 	ListPaymentTypes  endpoint.Endpoint
 	UpdatePaymentType endpoint.Endpoint
 	DeletePaymentType endpoint.Endpoint
 	GetPaymentType    endpoint.Endpoint
 
+	GetPaymentTypesByIDs endpoint.Endpoint
+
 	CreatePaymentCondition endpoint.Endpoint
 	ListPaymentConditions  endpoint.Endpoint
 	UpdatePaymentCondition endpoint.Endpoint
 	DeletePaymentCondition endpoint.Endpoint
 	GetPaymentCondition    endpoint.Endpoint
+	GetSchedule            endpoint.Endpoint
+
+	GetPaymentConditionsByIDs endpoint.Endpoint
 
 	CreateInvoice endpoint.Endpoint
 	ListInvoices  endpoint.Endpoint
 	UpdateInvoice endpoint.Endpoint
 	DeleteInvoice endpoint.Endpoint
 	GetInvoice    endpoint.Endpoint
+	PayInvoice    endpoint.Endpoint
+
+	ListUnpaidInvoices endpoint.Endpoint
+
+	InvoiceRent       endpoint.Endpoint
+	CancelRentInvoice endpoint.Endpoint
 }
 
 func CreateEndpoints(svc Service) Set {
@@ -42,23 +56,36 @@ func CreateEndpoints(svc Service) Set {
 		DeletePaymentMethod: makeDeletePaymentMethodEndpoint(svc),
 		GetPaymentMethod:    makeGetPaymentMethodEndpoint(svc),
 
+		GetPaymentMethodsByIDs: makeGetPaymentMethodsByIDsEndpoint(svc),
+
 		CreatePaymentType: makeCreatePaymentTypeEndpoint(svc),
 		ListPaymentTypes:  makeListPaymentTypesEndpoint(svc),
 		UpdatePaymentType: makeUpdatePaymentTypeEndpoint(svc),
 		DeletePaymentType: makeDeletePaymentTypeEndpoint(svc),
 		GetPaymentType:    makeGetPaymentTypeEndpoint(svc),
 
+		GetPaymentTypesByIDs: makeGetPaymentTypesByIDsEndpoint(svc),
+
 		CreatePaymentCondition: getType(makeCreatePaymentConditionEndpoint(svc)),
 		ListPaymentConditions:  getType(makeListPaymentConditionsEndpoint(svc)),
 		UpdatePaymentCondition: getType(makeUpdatePaymentConditionEndpoint(svc)),
 		DeletePaymentCondition: getType(makeDeletePaymentConditionEndpoint(svc)),
 		GetPaymentCondition:    getType(makeGetPaymentConditionEndpoint(svc)),
+		GetSchedule:            makeGetScheduleEndpoint(svc),
+
+		GetPaymentConditionsByIDs: getType(makeGetPaymentConditionsByIDsEndpoint(svc)),
 
 		CreateInvoice: makeCreateInvoiceEndpoint(svc),
 		ListInvoices:  makeListInvoicesEndpoint(svc),
 		UpdateInvoice: makeUpdateInvoiceEndpoint(svc),
 		DeleteInvoice: makeDeleteInvoiceEndpoint(svc),
 		GetInvoice:    makeGetInvoiceEndpoint(svc),
+		PayInvoice:    makePayInvoiceEndpoint(svc),
+
+		ListUnpaidInvoices: makeListUnpaidInvoicesEndpoint(svc),
+
+		InvoiceRent:       makeInvoiceRentEndpoint(svc),
+		CancelRentInvoice: makeCancelRentInvoiceEndpoint(svc),
 	}
 }
 
@@ -94,6 +121,12 @@ func makeGetPaymentMethodEndpoint(svc Service) endpoint.Endpoint {
 	}
 }
 
+func makeGetPaymentMethodsByIDsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.GetPaymentMethodsByIDs(r.([]string))
+	}
+}
+
 func makeCreatePaymentTypeEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
 		data := r.(Type)
@@ -126,6 +159,12 @@ func makeGetPaymentTypeEndpoint(svc Service) endpoint.Endpoint {
 	}
 }
 
+func makeGetPaymentTypesByIDsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.GetPaymentTypesByIDs(r.([]string))
+	}
+}
+
 func makeCreatePaymentConditionEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
 		condition := r.(Condition)
@@ -158,6 +197,25 @@ func makeGetPaymentConditionEndpoint(svc Service) endpoint.Endpoint {
 	}
 }
 
+func makeGetPaymentConditionsByIDsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.GetPaymentConditionsByIDs(r.([]string))
+	}
+}
+
+func makeGetScheduleEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(ScheduleRequest)
+		return svc.GetSchedule(req.ConditionID, req.Total, req.StartDate)
+	}
+}
+
+type ScheduleRequest struct {
+	ConditionID string    `json:"condition_id"`
+	Total       Money     `json:"total"`
+	StartDate   time.Time `json:"start_date"`
+}
+
 func makeCreateInvoiceEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
 		return svc.CreateInvoice(r.(Invoice))
@@ -221,3 +279,33 @@ func makeGetInvoiceEndpoint(svc Service) endpoint.Endpoint {
 		return svc.GetInvoice(r.(string))
 	}
 }
+
+func makePayInvoiceEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(PayInvoiceRequest)
+		return svc.PayInvoice(req.ID, req.PaidAt)
+	}
+}
+
+type PayInvoiceRequest struct {
+	ID     string    `json:"-"`
+	PaidAt time.Time `json:"paid_at"`
+}
+
+func makeListUnpaidInvoicesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.ListUnpaidInvoices(r.(string))
+	}
+}
+
+func makeInvoiceRentEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.InvoiceRent(r.(RentInvoice))
+	}
+}
+
+func makeCancelRentInvoiceEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return nil, svc.CancelRentInvoice(r.(string))
+	}
+}
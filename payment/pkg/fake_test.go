@@ -0,0 +1,85 @@
+package pkg_test
+
+import (
+	"errors"
+
+	"reconcip.com.br/microservices/payment/pkg"
+)
+
+type fakeValidator struct{}
+
+func (v *fakeValidator) Validate(any) error {
+	return nil
+}
+
+// fakeRepository keeps invoices in memory, with a single payment condition.
+// The rest of the repository is left unimplemented.
+type fakeRepository struct {
+	pkg.Repository
+	condition *pkg.Condition
+	invoices  map[string]*pkg.Invoice
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		condition: &pkg.Condition{ID: "condition"},
+		invoices:  make(map[string]*pkg.Invoice),
+	}
+}
+
+func (r *fakeRepository) GetPaymentCondition(id string) (*pkg.Condition, error) {
+	return r.condition, nil
+}
+
+func (r *fakeRepository) CreateInvoice(data pkg.Invoice) (*pkg.Invoice, error) {
+	data.ID = data.RentID
+	r.invoices[data.ID] = &data
+	return &data, nil
+}
+
+func (r *fakeRepository) UpdateInvoice(id string, data pkg.Invoice) (*pkg.Invoice, error) {
+	r.invoices[id] = &data
+	return &data, nil
+}
+
+func (r *fakeRepository) GetInvoice(id string) (*pkg.Invoice, error) {
+	if invoice, ok := r.invoices[id]; ok {
+		copied := *invoice
+		return &copied, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (r *fakeRepository) GetInvoiceByRent(rentID string, cycle int) (*pkg.Invoice, error) {
+	for _, invoice := range r.invoices {
+		if invoice.RentID == rentID && invoice.Cycle == cycle {
+			copied := *invoice
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+// ListUnpaidInvoices filters the invoices as the mongo repository does.
+func (r *fakeRepository) ListUnpaidInvoices(customerID string) ([]*pkg.Invoice, error) {
+	invoices := make([]*pkg.Invoice, 0)
+	for _, invoice := range r.invoices {
+		if invoice.CustomerID == customerID && !invoice.Cancelled && invoice.PaidAt.IsZero() {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+type fakeGateway struct {
+	processed []*pkg.Invoice
+	fail      bool
+}
+
+func (g *fakeGateway) ProcessPayment(invoice *pkg.Invoice) error {
+	if g.fail {
+		return errors.New("payment declined")
+	}
+	g.processed = append(g.processed, invoice)
+	return nil
+}
@@ -25,6 +25,10 @@ func NewStripeGateway(apiKey string) *StripeGateway {
 func (g *StripeGateway) ProcessPayment(invoice *Invoice) error {
 	customerId, err := g.GetCustomer(invoice.CustomerID)
 	if err != nil {
+		if invoice.Customer == nil {
+			return errors.New("could not find customer")
+		}
+
 		_, err = g.CreateCustomer(invoice.Customer)
 		if err != nil {
 			return err
@@ -0,0 +1,107 @@
+package pkg_test
+
+import (
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/payment/pkg"
+)
+
+func TestInvoiceRent(t *testing.T) {
+	rent := func(status string) pkg.RentInvoice {
+		return pkg.RentInvoice{
+			RentID:      "rent",
+			Status:      status,
+			CustomerID:  "customer",
+			ConditionID: "condition",
+			StartDate:   time.Now(),
+			Total:       pkg.NewMoney(100),
+			Items:       []pkg.Item{{Description: "2 x Andaime", Total: pkg.NewMoney(100)}},
+		}
+	}
+
+	t.Run("leaves drafts out", func(t *testing.T) {
+		gateway := &fakeGateway{}
+		svc := pkg.NewService(&fakeValidator{}, newFakeRepository(), gateway)
+
+		invoice, err := svc.InvoiceRent(rent("draft"))
+		if err != nil || invoice != nil {
+			t.Fatalf("expected no invoice, got %v %v", invoice, err)
+		}
+
+		if len(gateway.processed) != 0 {
+			t.Errorf("expected nothing to be charged, got %d invoices", len(gateway.processed))
+		}
+	})
+
+	t.Run("invoices reserved rents", func(t *testing.T) {
+		gateway := &fakeGateway{}
+		svc := pkg.NewService(&fakeValidator{}, newFakeRepository(), gateway)
+
+		invoice, err := svc.InvoiceRent(rent("reserved"))
+		if err != nil || invoice == nil {
+			t.Fatalf("expected invoice, got %v %v", invoice, err)
+		}
+
+		if len(gateway.processed) != 1 || invoice.Total != pkg.NewMoney(100) {
+			t.Errorf("expected 100 to be charged, got %s in %d invoices", invoice.Total, len(gateway.processed))
+		}
+	})
+
+	t.Run("paid invoices are not owed", func(t *testing.T) {
+		gateway := &fakeGateway{fail: true}
+		svc := pkg.NewService(&fakeValidator{}, newFakeRepository(), gateway)
+
+		if _, err := svc.InvoiceRent(rent("reserved")); err == nil {
+			t.Fatal("expected the payment to fail")
+		}
+
+		unpaid, _ := svc.ListUnpaidInvoices("customer")
+		if len(unpaid) != 1 {
+			t.Fatalf("expected the invoice to be owed, got %d invoices", len(unpaid))
+		}
+
+		paid, err := svc.PayInvoice(unpaid[0].ID, time.Time{})
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if paid.PaidAt.IsZero() {
+			t.Error("expected the payment to be recorded")
+		}
+
+		if unpaid, _ := svc.ListUnpaidInvoices("customer"); len(unpaid) != 0 {
+			t.Errorf("expected nothing owed, got %d invoices", len(unpaid))
+		}
+
+		if _, err := svc.PayInvoice(paid.ID, time.Time{}); err == nil {
+			t.Error("expected the invoice not to be paid twice")
+		}
+	})
+
+	t.Run("new invoices are owed until paid", func(t *testing.T) {
+		svc := pkg.NewService(&fakeValidator{}, newFakeRepository(), &fakeGateway{})
+
+		invoice, err := svc.InvoiceRent(rent("reserved"))
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if !invoice.PaidAt.IsZero() {
+			t.Error("expected the invoice not to be paid")
+		}
+
+		unpaid, _ := svc.ListUnpaidInvoices("customer")
+		if len(unpaid) != 1 || unpaid[0].ID != invoice.ID {
+			t.Fatalf("expected the invoice to be owed, got %d invoices", len(unpaid))
+		}
+
+		if _, err := svc.PayInvoice(invoice.ID, time.Time{}); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if unpaid, _ := svc.ListUnpaidInvoices("customer"); len(unpaid) != 0 {
+			t.Errorf("expected nothing owed, got %d invoices", len(unpaid))
+		}
+	})
+}
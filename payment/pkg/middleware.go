@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/log"
@@ -16,12 +17,7 @@ func getTypeMiddleware(svc Service) endpoint.Middleware {
 			}
 
 			if conditions, ok := res.([]*Condition); ok {
-				for _, condition := range conditions {
-					paymentType, err := svc.GetPaymentType(condition.PaymentTypeID)
-					if err == nil {
-						condition.PaymentType = paymentType
-					}
-				}
+				appendTypes(svc, conditions)
 				return conditions, nil
 			}
 
@@ -38,6 +34,30 @@ func getTypeMiddleware(svc Service) endpoint.Middleware {
 	}
 }
 
+// appendTypes fetches the payment types of all the conditions at once.
+func appendTypes(svc Service, conditions []*Condition) {
+	ids := make([]string, 0, len(conditions))
+	for _, condition := range conditions {
+		ids = append(ids, condition.PaymentTypeID)
+	}
+
+	paymentTypes, err := svc.GetPaymentTypesByIDs(ids)
+	if err != nil {
+		return
+	}
+
+	byID := make(map[string]*Type, len(paymentTypes))
+	for _, paymentType := range paymentTypes {
+		byID[paymentType.ID] = paymentType
+	}
+
+	for _, condition := range conditions {
+		if paymentType, ok := byID[condition.PaymentTypeID]; ok {
+			condition.PaymentType = paymentType
+		}
+	}
+}
+
 type loggingService struct {
 	next   Service
 	logger log.Logger
@@ -59,6 +79,18 @@ func (l *loggingService) GetPaymentMethod(id string) (method *Method, err error)
 	return l.next.GetPaymentMethod(id)
 }
 
+func (l *loggingService) GetPaymentMethodsByIDs(ids []string) (methods []*Method, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetPaymentMethodsByIDs",
+			"ids", ids,
+			"methods", methods,
+			"err", err,
+		)
+	}()
+	return l.next.GetPaymentMethodsByIDs(ids)
+}
+
 func (l *loggingService) ListPaymentMethods() (methods []*Method, err error) {
 	defer func() {
 		l.logger.Log(
@@ -165,6 +197,18 @@ func (l *loggingService) GetPaymentType(id string) (paymentType *Type, err error
 	return l.next.GetPaymentType(id)
 }
 
+func (l *loggingService) GetPaymentTypesByIDs(ids []string) (paymentTypes []*Type, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetPaymentTypesByIDs",
+			"ids", ids,
+			"paymentTypes", paymentTypes,
+			"err", err,
+		)
+	}()
+	return l.next.GetPaymentTypesByIDs(ids)
+}
+
 func (l *loggingService) CreatePaymentCondition(data Condition) (condition *Condition, err error) {
 	defer func() {
 		l.logger.Log(
@@ -224,6 +268,32 @@ func (l *loggingService) GetPaymentCondition(id string) (condition *Condition, e
 	return l.next.GetPaymentCondition(id)
 }
 
+func (l *loggingService) GetPaymentConditionsByIDs(ids []string) (conditions []*Condition, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetPaymentConditionsByIDs",
+			"ids", ids,
+			"conditions", conditions,
+			"err", err,
+		)
+	}()
+	return l.next.GetPaymentConditionsByIDs(ids)
+}
+
+func (l *loggingService) GetSchedule(conditionID string, total Money, start time.Time) (installments []*Installment, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetSchedule",
+			"conditionID", conditionID,
+			"total", total,
+			"start", start,
+			"installments", installments,
+			"err", err,
+		)
+	}()
+	return l.next.GetSchedule(conditionID, total, start)
+}
+
 func (l *loggingService) CreateInvoice(data Invoice) (invoice *Invoice, err error) {
 	defer func() {
 		l.logger.Log(
@@ -285,3 +355,51 @@ func (l *loggingService) GetInvoice(id string) (invoice *Invoice, err error) {
 	}()
 	return l.next.GetInvoice(id)
 }
+
+func (l *loggingService) PayInvoice(id string, paidAt time.Time) (invoice *Invoice, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "PayInvoice",
+			"id", id,
+			"paidAt", paidAt,
+			"invoice", invoice,
+			"err", err,
+		)
+	}()
+	return l.next.PayInvoice(id, paidAt)
+}
+
+func (l *loggingService) ListUnpaidInvoices(customerID string) (invoices []*Invoice, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "ListUnpaidInvoices",
+			"customerID", customerID,
+			"invoices", invoices,
+			"err", err,
+		)
+	}()
+	return l.next.ListUnpaidInvoices(customerID)
+}
+
+func (l *loggingService) InvoiceRent(data RentInvoice) (invoice *Invoice, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "InvoiceRent",
+			"data", data,
+			"invoice", invoice,
+			"err", err,
+		)
+	}()
+	return l.next.InvoiceRent(data)
+}
+
+func (l *loggingService) CancelRentInvoice(rentID string) (err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "CancelRentInvoice",
+			"rentID", rentID,
+			"err", err,
+		)
+	}()
+	return l.next.CancelRentInvoice(rentID)
+}
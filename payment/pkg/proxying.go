@@ -21,23 +21,36 @@ func VerifyEndpoints(cc *grpc.ClientConn, endpoints Set) Set {
 		DeletePaymentMethod: verify(endpoints.DeletePaymentMethod),
 		GetPaymentMethod:    verify(endpoints.GetPaymentMethod),
 
+		GetPaymentMethodsByIDs: endpoints.GetPaymentMethodsByIDs,
+
 		CreatePaymentType: verify(endpoints.CreatePaymentType),
 		ListPaymentTypes:  verify(endpoints.ListPaymentTypes),
 		UpdatePaymentType: verify(endpoints.UpdatePaymentType),
 		DeletePaymentType: verify(endpoints.DeletePaymentType),
 		GetPaymentType:    verify(endpoints.GetPaymentType),
 
+		GetPaymentTypesByIDs: endpoints.GetPaymentTypesByIDs,
+
 		CreatePaymentCondition: verify(endpoints.CreatePaymentCondition),
 		ListPaymentConditions:  verify(endpoints.ListPaymentConditions),
 		UpdatePaymentCondition: verify(endpoints.UpdatePaymentCondition),
 		DeletePaymentCondition: verify(endpoints.DeletePaymentCondition),
 		GetPaymentCondition:    verify(endpoints.GetPaymentCondition),
+		GetSchedule:            verify(endpoints.GetSchedule),
+
+		GetPaymentConditionsByIDs: endpoints.GetPaymentConditionsByIDs,
 
 		CreateInvoice: verify(endpoints.CreateInvoice),
 		ListInvoices:  verify(endpoints.ListInvoices),
 		UpdateInvoice: verify(endpoints.UpdateInvoice),
 		DeleteInvoice: verify(endpoints.DeleteInvoice),
 		GetInvoice:    verify(endpoints.GetInvoice),
+		PayInvoice:    verify(endpoints.PayInvoice),
+
+		ListUnpaidInvoices: endpoints.ListUnpaidInvoices,
+
+		InvoiceRent:       endpoints.InvoiceRent,
+		CancelRentInvoice: endpoints.CancelRentInvoice,
 	}
 }
 
@@ -108,12 +121,19 @@ func CustomerEndpoints(cc *grpc.ClientConn, endpoints Set) Set {
 		UpdatePaymentCondition: endpoints.UpdatePaymentCondition,
 		DeletePaymentCondition: endpoints.DeletePaymentCondition,
 		GetPaymentCondition:    endpoints.GetPaymentCondition,
+		GetSchedule:            endpoints.GetSchedule,
 
 		CreateInvoice: withCustomer(endpoints.CreateInvoice),
 		ListInvoices:  withCustomer(endpoints.ListInvoices),
 		UpdateInvoice: withCustomer(endpoints.UpdateInvoice),
 		DeleteInvoice: withCustomer(endpoints.DeleteInvoice),
 		GetInvoice:    withCustomer(endpoints.GetInvoice),
+		PayInvoice:    withCustomer(endpoints.PayInvoice),
+
+		ListUnpaidInvoices: endpoints.ListUnpaidInvoices,
+
+		InvoiceRent:       withCustomer(endpoints.InvoiceRent),
+		CancelRentInvoice: endpoints.CancelRentInvoice,
 	}
 }
 
@@ -13,6 +13,7 @@ import (
 
 type Repository interface {
 	GetPaymentMethod(string) (*Method, error)
+	GetPaymentMethodsByIDs([]string) ([]*Method, error)
 	CreatePaymentMethod(Method) (*Method, error)
 	ListPaymentMethods() ([]*Method, error)
 	UpdatePaymentMethod(string, Method) (*Method, error)
@@ -20,12 +21,14 @@ type Repository interface {
 
 	CreatePaymentType(Type) (*Type, error)
 	GetPaymentType(string) (*Type, error)
+	GetPaymentTypesByIDs([]string) ([]*Type, error)
 	ListPaymentTypes() ([]*Type, error)
 	UpdatePaymentType(string, Type) (*Type, error)
 	DeletePaymentType(string) error
 
 	CreatePaymentCondition(Condition) (*Condition, error)
 	GetPaymentCondition(string) (*Condition, error)
+	GetPaymentConditionsByIDs([]string) ([]*Condition, error)
 	ListPaymentConditions() ([]*Condition, error)
 	UpdatePaymentCondition(string, Condition) (*Condition, error)
 	DeletePaymentCondition(string) error
@@ -35,6 +38,8 @@ type Repository interface {
 	UpdateInvoice(string, Invoice) (*Invoice, error)
 	GetInvoice(string) (*Invoice, error)
 	DeleteInvoice(string) error
+	GetInvoiceByRent(rentID string, cycle int) (*Invoice, error)
+	ListUnpaidInvoices(customerID string) ([]*Invoice, error)
 }
 
 type mongoRepository struct {
@@ -88,6 +93,25 @@ func (r *mongoRepository) GetPaymentMethod(id string) (*Method, error) {
 	return method, nil
 }
 
+func (r *mongoRepository) GetPaymentMethodsByIDs(ids []string) ([]*Method, error) {
+	collection := r.database.Collection("payment_methods")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+
+	methods := make([]*Method, 0)
+	if err := cursor.All(ctx, &methods); err != nil {
+		return nil, err
+	}
+
+	return methods, nil
+}
+
 func (r *mongoRepository) ListPaymentMethods() ([]*Method, error) {
 	collection := r.database.Collection("payment_methods")
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
@@ -160,6 +184,25 @@ func (r *mongoRepository) GetPaymentType(id string) (*Type, error) {
 	return paymentType, err
 }
 
+func (r *mongoRepository) GetPaymentTypesByIDs(ids []string) ([]*Type, error) {
+	collection := r.database.Collection("payment_types")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+
+	paymentTypes := make([]*Type, 0)
+	if err := cursor.All(ctx, &paymentTypes); err != nil {
+		return nil, err
+	}
+
+	return paymentTypes, nil
+}
+
 func (r *mongoRepository) ListPaymentTypes() ([]*Type, error) {
 	collection := r.database.Collection("payment_types")
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
@@ -240,6 +283,25 @@ func (r *mongoRepository) GetPaymentCondition(id string) (*Condition, error) {
 	return condition, err
 }
 
+func (r *mongoRepository) GetPaymentConditionsByIDs(ids []string) ([]*Condition, error) {
+	collection := r.database.Collection("payment_conditions")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := make([]*Condition, 0)
+	if err := cursor.All(ctx, &conditions); err != nil {
+		return nil, err
+	}
+
+	return conditions, nil
+}
+
 func (r *mongoRepository) ListPaymentConditions() ([]*Condition, error) {
 	collection := r.database.Collection("payment_conditions")
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
@@ -366,3 +428,45 @@ func (r *mongoRepository) DeleteInvoice(id string) error {
 
 	return err
 }
+
+// GetInvoiceByRent finds the invoice of a cycle of the rent. Invoices of the
+// rent itself are at cycle zero, which is not stored.
+func (r *mongoRepository) GetInvoiceByRent(rentID string, cycle int) (*Invoice, error) {
+	collection := r.database.Collection("invoices")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	filter := bson.M{"rent_id": rentID, "cycle": cycle}
+	if cycle == 0 {
+		filter["cycle"] = bson.M{"$exists": false}
+	}
+
+	result := collection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var invoice *Invoice
+	return invoice, result.Decode(&invoice)
+}
+
+func (r *mongoRepository) ListUnpaidInvoices(customerID string) ([]*Invoice, error) {
+	collection := r.database.Collection("invoices")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	result, err := collection.Find(ctx, bson.M{
+		"customerid": customerID,
+		"cancelled":  bson.M{"$ne": true},
+		"paid_at":    bson.M{"$exists": false},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	invoices := make([]*Invoice, 0)
+	return invoices, result.All(ctx, &invoices)
+}
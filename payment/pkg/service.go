@@ -22,21 +22,82 @@ type Condition struct {
 	Increment     float32 `json:"increment" validate:"min=0"`
 	PaymentTypeID string  `json:"payment_type_id" validate:"required,paymenttype"`
 	PaymentType   *Type   `json:"payment_type,omitempty"`
-	Installments  []int32 `json:"installments,dive,gt=0"`
+	Installments  []int32 `json:"installments" validate:"dive,gt=0"`
+}
+
+// Schedule splits the total, plus the condition increment, into installments
+// due the amount of days after start given by the condition. Values are split
+// in cents and the remainder goes to the last installment, so they always sum
+// up to the incremented total. Conditions without installments are paid at
+// once on start.
+func (c *Condition) Schedule(total Money, start time.Time) []*Installment {
+	offsets := c.Installments
+	if len(offsets) == 0 {
+		offsets = []int32{0}
+	}
+
+	incremented := total.Mul(1 + float64(c.Increment)/100)
+	each := incremented / Money(len(offsets))
+
+	installments := make([]*Installment, len(offsets))
+	for i, offset := range offsets {
+		value := each
+		if i == len(offsets)-1 {
+			value = incremented - each.Times(len(offsets)-1)
+		}
+
+		installments[i] = &Installment{
+			Number:  i + 1,
+			DueDate: start.AddDate(0, 0, int(offset)),
+			Value:   value,
+		}
+	}
+
+	return installments
+}
+
+type Installment struct {
+	Number  int       `json:"number"`
+	DueDate time.Time `json:"due_date"`
+	Value   Money     `json:"value"`
 }
 
 type Invoice struct {
-	ID         string    `json:"id" bson:"_id,omitempty"`
-	CustomerID string    `json:"customer_id" validate:"required"`
-	Customer   *Customer `json:"customer,omitempty"`
-	DueDate    time.Time `json:"due_date" validate:"required,gt"`
-	Total      float64   `json:"total" validate:"required,gt=0"`
-	Items      []Item    `json:"items" validate:"required,dive"`
+	ID           string         `json:"id" bson:"_id,omitempty"`
+	CustomerID   string         `json:"customer_id" validate:"required"`
+	Customer     *Customer      `json:"customer,omitempty"`
+	DueDate      time.Time      `json:"due_date" validate:"required,gt"`
+	Total        Money          `json:"total" validate:"required,gt=0"`
+	Items        []Item         `json:"items" validate:"required,dive"`
+	RentID       string         `json:"rent_id,omitempty" bson:"rent_id,omitempty"`
+	Cycle        int            `json:"cycle,omitempty" bson:"cycle,omitempty"`
+	ConditionID  string         `json:"condition_id,omitempty" bson:"condition_id,omitempty"`
+	Cancelled    bool           `json:"cancelled" bson:"cancelled"`
+	PaidAt       time.Time      `json:"paid_at,omitempty" bson:"paid_at,omitempty"`
+	Installments []*Installment `json:"installments" bson:"installments"`
 }
 
 type Item struct {
-	Description string  `json:"description" validate:"required"`
-	Total       float64 `json:"total" validate:"required,gt=0"`
+	Description string `json:"description" validate:"required"`
+	Total       Money  `json:"total" validate:"required,gt=0"`
+}
+
+// rentDraft is the status of rents still being put together, which are not
+// billed yet. Billing events of cycles carry no status.
+const rentDraft = "draft"
+
+// RentInvoice carries what the renting service publishes about a rent in
+// order to bill it. Rents billed by cycle publish each cycle on its own, and
+// the cycle is zero for the rent itself.
+type RentInvoice struct {
+	RentID      string    `json:"rent_id"`
+	Status      string    `json:"status"`
+	Cycle       int       `json:"cycle"`
+	CustomerID  string    `json:"customer_id"`
+	ConditionID string    `json:"payment_condition_id"`
+	StartDate   time.Time `json:"start_date"`
+	Total       Money     `json:"total"`
+	Items       []Item    `json:"items"`
 }
 
 type Customer struct {
@@ -55,24 +116,32 @@ type Service interface {
 	UpdatePaymentMethod(string, Method) (*Method, error)
 	DeletePaymentMethod(string) error
 	GetPaymentMethod(string) (*Method, error)
+	GetPaymentMethodsByIDs([]string) ([]*Method, error)
 
 	CreatePaymentType(Type) (*Type, error)
 	ListPaymentTypes() ([]*Type, error)
 	UpdatePaymentType(string, Type) (*Type, error)
 	DeletePaymentType(string) error
 	GetPaymentType(string) (*Type, error)
+	GetPaymentTypesByIDs([]string) ([]*Type, error)
 
 	CreatePaymentCondition(Condition) (*Condition, error)
 	ListPaymentConditions() ([]*Condition, error)
 	UpdatePaymentCondition(string, Condition) (*Condition, error)
 	DeletePaymentCondition(string) error
 	GetPaymentCondition(string) (*Condition, error)
+	GetPaymentConditionsByIDs([]string) ([]*Condition, error)
+	GetSchedule(conditionID string, total Money, start time.Time) ([]*Installment, error)
 
 	CreateInvoice(Invoice) (*Invoice, error)
 	ListInvoices(page, perPage int64) ([]*Invoice, int64, error)
 	UpdateInvoice(string, Invoice) (*Invoice, error)
 	DeleteInvoice(string) error
 	GetInvoice(string) (*Invoice, error)
+	PayInvoice(id string, paidAt time.Time) (*Invoice, error)
+	ListUnpaidInvoices(customerID string) ([]*Invoice, error)
+	InvoiceRent(RentInvoice) (*Invoice, error)
+	CancelRentInvoice(rentID string) error
 }
 
 type service struct {
@@ -155,6 +224,18 @@ func (s *service) GetPaymentMethod(id string) (*Method, error) {
 	return method, nil
 }
 
+func (s *service) GetPaymentMethodsByIDs(ids []string) ([]*Method, error) {
+	methods, err := s.repository.GetPaymentMethodsByIDs(ids)
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error fetching payment methods",
+			"something went wrong while fetching payment methods",
+		)
+	}
+	return methods, nil
+}
+
 func (s *service) CreatePaymentType(data Type) (*Type, error) {
 	if err := s.validator.Validate(data); err != nil {
 		return nil, err
@@ -225,6 +306,18 @@ func (s *service) GetPaymentType(id string) (*Type, error) {
 	return paymentType, nil
 }
 
+func (s *service) GetPaymentTypesByIDs(ids []string) ([]*Type, error) {
+	paymentTypes, err := s.repository.GetPaymentTypesByIDs(ids)
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error fetching payment types",
+			"something went wrong while fetching payment types",
+		)
+	}
+	return paymentTypes, nil
+}
+
 func (s *service) CreatePaymentCondition(data Condition) (*Condition, error) {
 	if err := s.validator.Validate(data); err != nil {
 		return nil, err
@@ -294,6 +387,39 @@ func (s *service) GetPaymentCondition(id string) (*Condition, error) {
 	return condition, nil
 }
 
+func (s *service) GetPaymentConditionsByIDs(ids []string) ([]*Condition, error) {
+	conditions, err := s.repository.GetPaymentConditionsByIDs(ids)
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error fetching conditions",
+			"something went wrong while fetching conditions",
+		)
+	}
+	return conditions, nil
+}
+
+func (s *service) GetSchedule(conditionID string, total Money, start time.Time) ([]*Installment, error) {
+	condition, err := s.repository.GetPaymentCondition(conditionID)
+	if err != nil {
+		return nil, NewError(
+			http.StatusNotFound,
+			"condition not found",
+			"could not find condition",
+		)
+	}
+
+	if total <= 0 {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid total",
+			"the total must be greater than zero",
+		)
+	}
+
+	return condition.Schedule(total, start), nil
+}
+
 func (s *service) CreateInvoice(data Invoice) (*Invoice, error) {
 	if err := s.validator.Validate(data); err != nil {
 		return nil, err
@@ -320,7 +446,8 @@ func (s *service) ListInvoices(page, perPage int64) ([]*Invoice, int64, error) {
 }
 
 func (s *service) UpdateInvoice(id string, data Invoice) (*Invoice, error) {
-	if _, err := s.repository.GetInvoice(id); err != nil {
+	curr, err := s.repository.GetInvoice(id)
+	if err != nil {
 		return nil, NewError(
 			http.StatusNotFound,
 			"invoice not found",
@@ -328,6 +455,9 @@ func (s *service) UpdateInvoice(id string, data Invoice) (*Invoice, error) {
 		)
 	}
 
+	// payments are only recorded through PayInvoice
+	data.PaidAt = curr.PaidAt
+
 	invoice, err := s.repository.UpdateInvoice(id, data)
 	if err != nil {
 		return nil, NewError(
@@ -362,3 +492,129 @@ func (s *service) GetInvoice(id string) (*Invoice, error) {
 	}
 	return invoice, nil
 }
+
+// PayInvoice records the payment of an invoice, at paidAt or now when not
+// given. Invoices are owed until then, even when sent to the gateway.
+func (s *service) PayInvoice(id string, paidAt time.Time) (*Invoice, error) {
+	invoice, err := s.repository.GetInvoice(id)
+	if err != nil {
+		return nil, NewError(
+			http.StatusNotFound,
+			"invoice not found",
+			"could not find invoice",
+		)
+	}
+
+	if invoice.Cancelled || !invoice.PaidAt.IsZero() {
+		return nil, NewError(
+			http.StatusConflict,
+			"invoice cannot be paid",
+			"the invoice was either cancelled or already paid",
+		)
+	}
+
+	if paidAt.IsZero() {
+		paidAt = time.Now()
+	}
+
+	return s.payInvoice(invoice, paidAt)
+}
+
+func (s *service) payInvoice(invoice *Invoice, paidAt time.Time) (*Invoice, error) {
+	invoice.PaidAt = paidAt
+
+	invoice, err := s.repository.UpdateInvoice(invoice.ID, *invoice)
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"could not update invoice",
+			"there was an error recording the invoice payment",
+		)
+	}
+
+	return invoice, nil
+}
+
+// ListUnpaidInvoices returns the invoices of the customer that were neither
+// paid nor cancelled.
+func (s *service) ListUnpaidInvoices(customerID string) ([]*Invoice, error) {
+	invoices, err := s.repository.ListUnpaidInvoices(customerID)
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error fetching invoices",
+			"something went wrong while fetching the customer invoices",
+		)
+	}
+	return invoices, nil
+}
+
+// InvoiceRent creates the invoice of a rent, or of one of its cycles, or
+// updates it if it was already invoiced. The invoice is due on the first
+// installment of the payment condition, counted from the start of the rent or
+// cycle. Rents with nothing to bill, like the ones billed by cycle, have no
+// invoice of their own, and neither do drafts until they are reserved.
+func (s *service) InvoiceRent(data RentInvoice) (*Invoice, error) {
+	invoice, err := s.repository.GetInvoiceByRent(data.RentID, data.Cycle)
+	found := err == nil
+
+	if data.Total <= 0 || data.Status == rentDraft {
+		if found && !invoice.Cancelled {
+			invoice.Cancelled = true
+			return s.UpdateInvoice(invoice.ID, *invoice)
+		}
+		return nil, nil
+	}
+
+	installments, err := s.GetSchedule(data.ConditionID, data.Total, data.StartDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return s.CreateInvoice(Invoice{
+			CustomerID:   data.CustomerID,
+			DueDate:      getDueDate(installments),
+			Total:        data.Total,
+			Items:        data.Items,
+			RentID:       data.RentID,
+			Cycle:        data.Cycle,
+			ConditionID:  data.ConditionID,
+			Installments: installments,
+		})
+	}
+
+	invoice.CustomerID = data.CustomerID
+	invoice.ConditionID = data.ConditionID
+	invoice.Total = data.Total
+	invoice.Items = data.Items
+	invoice.Installments = installments
+
+	return s.UpdateInvoice(invoice.ID, *invoice)
+}
+
+// getDueDate returns when the first installment is due, or tomorrow in case
+// it is already past.
+func getDueDate(installments []*Installment) time.Time {
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	if len(installments) == 0 || installments[0].DueDate.Before(tomorrow) {
+		return tomorrow
+	}
+	return installments[0].DueDate
+}
+
+func (s *service) CancelRentInvoice(rentID string) error {
+	invoice, err := s.repository.GetInvoiceByRent(rentID, 0)
+	if err != nil {
+		return NewError(
+			http.StatusNotFound,
+			"invoice not found",
+			"could not find the rent invoice",
+		)
+	}
+
+	invoice.Cancelled = true
+	_, err = s.UpdateInvoice(invoice.ID, *invoice)
+
+	return err
+}
@@ -0,0 +1,55 @@
+package pkg_test
+
+import (
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/payment/pkg"
+)
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("splits total with increment", func(t *testing.T) {
+		condition := &pkg.Condition{Increment: 10, Installments: []int32{30, 60}}
+		installments := condition.Schedule(pkg.NewMoney(200), start)
+
+		if len(installments) != 2 {
+			t.Fatalf("expected 2 installments, got %d", len(installments))
+		}
+
+		for i, installment := range installments {
+			if installment.Value != pkg.NewMoney(110) {
+				t.Errorf("expected installment %d to be 110, got %s", i+1, installment.Value)
+			}
+		}
+
+		if !installments[1].DueDate.Equal(start.AddDate(0, 0, 60)) {
+			t.Errorf("expected due date %v, got %v", start.AddDate(0, 0, 60), installments[1].DueDate)
+		}
+	})
+
+	t.Run("rounding remainder goes to the last installment", func(t *testing.T) {
+		condition := &pkg.Condition{Installments: []int32{30, 60, 90}}
+		installments := condition.Schedule(pkg.NewMoney(100), start)
+
+		if installments[0].Value != pkg.NewMoney(33.33) || installments[1].Value != pkg.NewMoney(33.33) {
+			t.Errorf("expected first installments to be 33.33, got %s and %s", installments[0].Value, installments[1].Value)
+		}
+		if installments[2].Value != pkg.NewMoney(33.34) {
+			t.Errorf("expected last installment to be 33.34, got %s", installments[2].Value)
+		}
+	})
+
+	t.Run("without installments is paid at once", func(t *testing.T) {
+		condition := &pkg.Condition{}
+		installments := condition.Schedule(pkg.NewMoney(100), start)
+
+		if len(installments) != 1 {
+			t.Fatalf("expected 1 installment, got %d", len(installments))
+		}
+		if installments[0].Value != pkg.NewMoney(100) || !installments[0].DueDate.Equal(start) {
+			t.Errorf("expected 100 due on %v, got %s on %v", start, installments[0].Value, installments[0].DueDate)
+		}
+	})
+}
@@ -3,13 +3,19 @@ package pkg
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-kit/kit/auth/jwt"
+	amqptransport "github.com/go-kit/kit/transport/amqp"
 	"github.com/go-kit/kit/transport/grpc"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/julienschmidt/httprouter"
+	"github.com/streadway/amqp"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"reconcip.com.br/microservices/payment/proto"
 )
 
@@ -18,6 +24,13 @@ type grpcServer struct {
 	getMethod    grpc.Handler
 	getType      grpc.Handler
 	getCondition grpc.Handler
+	getSchedule  grpc.Handler
+
+	getMethodsByIDs    grpc.Handler
+	getTypesByIDs      grpc.Handler
+	getConditionsByIDs grpc.Handler
+
+	getUnpaidInvoices grpc.Handler
 }
 
 func NewGRPCServer(endpoints Set) proto.PaymentServer {
@@ -37,6 +50,31 @@ func NewGRPCServer(endpoints Set) proto.PaymentServer {
 			decodeGRPCGetRequest,
 			encodeGRPCConditionReply,
 		),
+		getSchedule: grpc.NewServer(
+			endpoints.GetSchedule,
+			decodeGRPCScheduleRequest,
+			encodeGRPCScheduleReply,
+		),
+		getMethodsByIDs: grpc.NewServer(
+			endpoints.GetPaymentMethodsByIDs,
+			decodeGRPCGetByIDsRequest,
+			encodeGRPCMethodsReply,
+		),
+		getTypesByIDs: grpc.NewServer(
+			endpoints.GetPaymentTypesByIDs,
+			decodeGRPCGetByIDsRequest,
+			encodeGRPCTypesReply,
+		),
+		getConditionsByIDs: grpc.NewServer(
+			endpoints.GetPaymentConditionsByIDs,
+			decodeGRPCGetByIDsRequest,
+			encodeGRPCConditionsReply,
+		),
+		getUnpaidInvoices: grpc.NewServer(
+			endpoints.ListUnpaidInvoices,
+			decodeGRPCGetRequest,
+			encodeGRPCInvoicesReply,
+		),
 	}
 }
 
@@ -64,49 +102,172 @@ func (s *grpcServer) GetCondition(ctx context.Context, r *proto.GetRequest) (*pr
 	return reply.(*proto.ConditionReply), nil
 }
 
+func (s *grpcServer) GetSchedule(ctx context.Context, r *proto.ScheduleRequest) (*proto.ScheduleReply, error) {
+	_, reply, err := s.getSchedule.ServeGRPC(ctx, r)
+	if err != nil {
+		return &proto.ScheduleReply{Err: err.Error()}, nil
+	}
+	return reply.(*proto.ScheduleReply), nil
+}
+
+func (s *grpcServer) GetMethodsByIDs(ctx context.Context, r *proto.GetByIDsRequest) (*proto.MethodsReply, error) {
+	_, reply, err := s.getMethodsByIDs.ServeGRPC(ctx, r.GetIds())
+	if err != nil {
+		return &proto.MethodsReply{Err: err.Error()}, nil
+	}
+	return reply.(*proto.MethodsReply), nil
+}
+
+func (s *grpcServer) GetTypesByIDs(ctx context.Context, r *proto.GetByIDsRequest) (*proto.TypesReply, error) {
+	_, reply, err := s.getTypesByIDs.ServeGRPC(ctx, r.GetIds())
+	if err != nil {
+		return &proto.TypesReply{Err: err.Error()}, nil
+	}
+	return reply.(*proto.TypesReply), nil
+}
+
+func (s *grpcServer) GetConditionsByIDs(ctx context.Context, r *proto.GetByIDsRequest) (*proto.ConditionsReply, error) {
+	_, reply, err := s.getConditionsByIDs.ServeGRPC(ctx, r.GetIds())
+	if err != nil {
+		return &proto.ConditionsReply{Err: err.Error()}, nil
+	}
+	return reply.(*proto.ConditionsReply), nil
+}
+
+func (s *grpcServer) GetUnpaidInvoices(ctx context.Context, r *proto.GetRequest) (*proto.InvoicesReply, error) {
+	_, reply, err := s.getUnpaidInvoices.ServeGRPC(ctx, r.GetId())
+	if err != nil {
+		return &proto.InvoicesReply{Err: err.Error()}, nil
+	}
+	return reply.(*proto.InvoicesReply), nil
+}
+
 func decodeGRPCGetRequest(ctx context.Context, r any) (any, error) {
 	return r.(string), nil
 }
 
+func decodeGRPCGetByIDsRequest(ctx context.Context, r any) (any, error) {
+	return r.([]string), nil
+}
+
 func encodeGRPCMethodReply(ctx context.Context, r any) (any, error) {
-	method := r.(*Method)
+	return &proto.MethodReply{Method: encodeMethod(r.(*Method))}, nil
+}
 
-	return &proto.MethodReply{
-		Method: &proto.Method{
-			Id:   method.ID,
-			Name: method.Name,
-		},
-	}, nil
+func encodeGRPCMethodsReply(ctx context.Context, r any) (any, error) {
+	methods := r.([]*Method)
+	reply := &proto.MethodsReply{Methods: make([]*proto.Method, len(methods))}
+
+	for i, method := range methods {
+		reply.Methods[i] = encodeMethod(method)
+	}
+
+	return reply, nil
+}
+
+func encodeMethod(method *Method) *proto.Method {
+	return &proto.Method{
+		Id:   method.ID,
+		Name: method.Name,
+	}
 }
 
 func encodeGRPCTypeReply(ctx context.Context, r any) (any, error) {
-	paymentType := r.(*Type)
+	return &proto.TypeReply{Type: encodeType(r.(*Type))}, nil
+}
 
-	return &proto.TypeReply{
-		Type: &proto.Type{
-			Id:   paymentType.ID,
-			Name: paymentType.Name,
-		},
-	}, nil
+func encodeGRPCTypesReply(ctx context.Context, r any) (any, error) {
+	paymentTypes := r.([]*Type)
+	reply := &proto.TypesReply{Types: make([]*proto.Type, len(paymentTypes))}
+
+	for i, paymentType := range paymentTypes {
+		reply.Types[i] = encodeType(paymentType)
+	}
+
+	return reply, nil
+}
+
+func encodeType(paymentType *Type) *proto.Type {
+	return &proto.Type{
+		Id:   paymentType.ID,
+		Name: paymentType.Name,
+	}
 }
 
 func encodeGRPCConditionReply(ctx context.Context, r any) (any, error) {
-	condition := r.(*Condition)
-
-	return &proto.ConditionReply{
-		Condition: &proto.Condition{
-			Id:           condition.ID,
-			Name:         condition.Name,
-			Increment:    condition.Increment,
-			Installments: condition.Installments,
-			PaymentType: &proto.Type{
-				Id:   condition.PaymentType.ID,
-				Name: condition.PaymentType.Name,
-			},
-		},
+	return &proto.ConditionReply{Condition: encodeCondition(r.(*Condition))}, nil
+}
+
+func encodeGRPCConditionsReply(ctx context.Context, r any) (any, error) {
+	conditions := r.([]*Condition)
+	reply := &proto.ConditionsReply{Conditions: make([]*proto.Condition, len(conditions))}
+
+	for i, condition := range conditions {
+		reply.Conditions[i] = encodeCondition(condition)
+	}
+
+	return reply, nil
+}
+
+func encodeCondition(condition *Condition) *proto.Condition {
+	reply := &proto.Condition{
+		Id:           condition.ID,
+		Name:         condition.Name,
+		Increment:    condition.Increment,
+		Installments: condition.Installments,
+	}
+
+	if condition.PaymentType != nil {
+		reply.PaymentType = encodeType(condition.PaymentType)
+	}
+
+	return reply
+}
+
+func encodeGRPCInvoicesReply(ctx context.Context, r any) (any, error) {
+	invoices := r.([]*Invoice)
+	reply := &proto.InvoicesReply{Invoices: make([]*proto.Invoice, len(invoices))}
+
+	for i, invoice := range invoices {
+		reply.Invoices[i] = &proto.Invoice{
+			Id:      invoice.ID,
+			RentId:  invoice.RentID,
+			Cycle:   int32(invoice.Cycle),
+			Total:   invoice.Total.Float64(),
+			DueDate: timestamppb.New(invoice.DueDate),
+		}
+	}
+
+	return reply, nil
+}
+
+func decodeGRPCScheduleRequest(ctx context.Context, r any) (any, error) {
+	req := r.(*proto.ScheduleRequest)
+
+	return ScheduleRequest{
+		ConditionID: req.GetConditionId(),
+		Total:       NewMoney(req.GetTotal()),
+		StartDate:   req.GetStartDate().AsTime(),
 	}, nil
 }
 
+func encodeGRPCScheduleReply(ctx context.Context, r any) (any, error) {
+	installments := r.([]*Installment)
+	reply := &proto.ScheduleReply{
+		Installments: make([]*proto.Installment, len(installments)),
+	}
+
+	for i, installment := range installments {
+		reply.Installments[i] = &proto.Installment{
+			Number:  int32(installment.Number),
+			DueDate: timestamppb.New(installment.DueDate),
+			Value:   installment.Value.Float64(),
+		}
+	}
+
+	return reply, nil
+}
+
 func NewHTTPHandler(endpoints Set) http.Handler {
 	router := httprouter.New()
 
@@ -315,6 +476,43 @@ func makePaymentConditionRoutes(prefix string, router *httprouter.Router, endpoi
 		httptransport.EncodeJSONResponse,
 		options,
 	))
+
+	router.Handler(http.MethodGet, prefix+"/:id/schedule", httptransport.NewServer(
+		endpoints.GetSchedule,
+		decodeScheduleRequest,
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+}
+
+func decodeScheduleRequest(ctx context.Context, r *http.Request) (any, error) {
+	params := r.URL.Query()
+
+	total, err := strconv.ParseFloat(params.Get("total"), 64)
+	if err != nil {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid total",
+			"inform the total to be scheduled",
+		)
+	}
+
+	start := time.Now()
+	if date := params.Get("start_date"); date != "" {
+		if start, err = time.Parse("2006-01-02", date); err != nil {
+			return nil, NewError(
+				http.StatusBadRequest,
+				"invalid start date",
+				"the start date must be formatted as YYYY-MM-DD",
+			)
+		}
+	}
+
+	return ScheduleRequest{
+		ConditionID: httprouter.ParamsFromContext(r.Context()).ByName("id"),
+		Total:       NewMoney(total),
+		StartDate:   start,
+	}, nil
 }
 
 func decodeCreatePaymentConditionRequest(ctx context.Context, r *http.Request) (any, error) {
@@ -387,6 +585,13 @@ func makeInvoiceRoutes(prefix string, router *httprouter.Router, endpoints Set,
 		httptransport.EncodeJSONResponse,
 		options,
 	))
+
+	router.Handler(http.MethodPost, prefix+"/:id/payment", httptransport.NewServer(
+		endpoints.PayInvoice,
+		decodePayInvoiceRequest,
+		httptransport.EncodeJSONResponse,
+		options,
+	))
 }
 
 func decodeCreateInvoiceRequest(ctx context.Context, r *http.Request) (any, error) {
@@ -436,3 +641,119 @@ func decodeUpdateInvoiceRequest(ctx context.Context, r *http.Request) (any, erro
 		Data: invoice,
 	}, nil
 }
+
+// decodePayInvoiceRequest reads when the invoice was paid, which is now when
+// the body is empty.
+func decodePayInvoiceRequest(ctx context.Context, r *http.Request) (any, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	var req PayInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid input data",
+			"verify your input and try again",
+		)
+	}
+
+	req.ID = params.ByName("id")
+	return req, nil
+}
+
+// NewSubscriber keeps the invoices in sync with the rents by consuming the
+// events published by the renting service.
+func NewSubscriber(endpoints Set, conn *amqp.Connection) {
+	channel, err := conn.Channel()
+	if err != nil {
+		panic(err)
+	}
+
+	defer channel.Close()
+
+	if err := channel.ExchangeDeclare("renting", "direct", true, false, false, false, nil); err != nil {
+		panic(err)
+	}
+
+	subscribe(channel, "payment.invoice_rent", []string{"rent.created", "rent.updated", "rent.billed"}, amqptransport.NewSubscriber(
+		endpoints.InvoiceRent,
+		decodeRentInvoiceAMQPRequest,
+		amqptransport.EncodeNopResponse,
+		amqptransport.SubscriberResponsePublisher(ackResponse),
+		amqptransport.SubscriberErrorEncoder(nackError),
+	))
+
+	subscribe(channel, "payment.cancel_rent_invoice", []string{"rent.cancelled"}, amqptransport.NewSubscriber(
+		endpoints.CancelRentInvoice,
+		decodeCancelRentInvoiceAMQPRequest,
+		amqptransport.EncodeNopResponse,
+		amqptransport.SubscriberResponsePublisher(ackResponse),
+		amqptransport.SubscriberErrorEncoder(nackError),
+	))
+
+	var forever chan any
+	<-forever
+}
+
+func subscribe(channel *amqp.Channel, name string, keys []string, subscriber *amqptransport.Subscriber) {
+	queue, err := channel.QueueDeclare(name, true, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, key := range keys {
+		if err := channel.QueueBind(queue.Name, key, "renting", false, nil); err != nil {
+			panic(err)
+		}
+	}
+
+	handler := subscriber.ServeDelivery(channel)
+	messages, err := channel.Consume(queue.Name, "", false, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	go func(<-chan amqp.Delivery) {
+		for message := range messages {
+			handler(&message)
+		}
+	}(messages)
+}
+
+// ackResponse acknowledges the events once they are handled, as nobody waits
+// for a reply.
+func ackResponse(ctx context.Context, d *amqp.Delivery, ch amqptransport.Channel, p *amqp.Publishing) error {
+	return d.Ack(false)
+}
+
+// nackError puts the event back in the queue the first time handling it fails,
+// so that passing errors are retried, and drops it when it fails again so that
+// it does not hold the queue.
+func nackError(ctx context.Context, err error, d *amqp.Delivery, ch amqptransport.Channel, p *amqp.Publishing) {
+	if !d.Redelivered {
+		d.Nack(false, true)
+		return
+	}
+
+	log.Printf("dropping %s event %s: %v", d.RoutingKey, d.Body, err)
+	d.Nack(false, false)
+}
+
+func decodeRentInvoiceAMQPRequest(ctx context.Context, d *amqp.Delivery) (any, error) {
+	var invoice RentInvoice
+	if err := json.Unmarshal(d.Body, &invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func decodeCancelRentInvoiceAMQPRequest(ctx context.Context, d *amqp.Delivery) (any, error) {
+	var rent struct {
+		RentID string `json:"rent_id"`
+	}
+
+	if err := json.Unmarshal(d.Body, &rent); err != nil {
+		return nil, err
+	}
+
+	return rent.RentID, nil
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
 	"github.com/go-kit/log"
@@ -12,6 +16,7 @@ import (
 	"github.com/streadway/amqp"
 	"google.golang.org/grpc"
 	"reconcip.com.br/microservices/renting/pkg"
+	"reconcip.com.br/microservices/renting/proto"
 )
 
 func main() {
@@ -26,6 +31,13 @@ func main() {
 		panic(err)
 	}
 
+	authUrl := os.Getenv("AUTH_SERVICE_URL")
+	ac, err := grpc.Dial(authUrl+":8080", grpc.WithInsecure())
+	if err != nil {
+		panic(err)
+	}
+	defer ac.Close()
+
 	paymentUrl := os.Getenv("PAYMENT_SERVICE_URL")
 	pc, err := grpc.Dial(paymentUrl+":8080", grpc.WithInsecure())
 	if err != nil {
@@ -52,7 +64,7 @@ func main() {
 		pkg.NewPaymentMethodRule(pc),
 		pkg.NewPaymentConditionRule(pc),
 		pkg.NewCustomerRule(cc),
-	})
+	}, pkg.NewAvailabilityRule(ic))
 
 	deliveryUrl := os.Getenv("DELIVERY_SERVICE_URL")
 	dc, err := grpc.Dial(deliveryUrl+":8080", grpc.WithInsecure())
@@ -67,23 +79,53 @@ func main() {
 	brokerUser := os.Getenv("BROKER_USER")
 	brokerPass := os.Getenv("BROKER_PASSWORD")
 
-	conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s/", brokerUser, brokerPass, brokerUrl))
-	if err != nil {
-		panic(err)
+	dial := func() (*amqp.Connection, error) {
+		return amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s/", brokerUser, brokerPass, brokerUrl))
 	}
 
 	inventory := pkg.NewInventoryService(
 		pkg.ReduceStockEndpoint(ic),
 		pkg.RestoreStockEndpoint(ic),
-		pkg.ProcessLaterEndpoint(conn),
+		pkg.ExtendBookingEndpoint(ic),
+		pkg.GetEquipmentEndpoint(ic),
+		pkg.WriteOffStockEndpoint(ic),
 	)
 
-	svc := pkg.NewService(validator, repository, delivery, inventory)
+	lateFeeRate, err := strconv.ParseFloat(os.Getenv("LATE_FEE_RATE"), 64)
+	if err != nil {
+		lateFeeRate = 1
+	}
+
+	estimateDays, err := strconv.Atoi(os.Getenv("ESTIMATE_VALIDITY_DAYS"))
+	if err != nil {
+		estimateDays = 15
+	}
+
+	billingMinutes, err := strconv.Atoi(os.Getenv("BILLING_INTERVAL_MINUTES"))
+	if err != nil {
+		billingMinutes = 60
+	}
+
+	svc := pkg.NewService(
+		validator,
+		repository,
+		delivery,
+		inventory,
+		pkg.NewGRPCCreditService(cc, pc),
+		lateFeeRate,
+		time.Duration(estimateDays)*24*time.Hour,
+	)
 
 	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
 	logger = log.WithPrefix(logger, "ts", log.DefaultTimestamp)
 	logger = log.WithPrefix(logger, "caller", log.DefaultCaller)
 
+	relay := pkg.NewOutboxRelay(repository, dial, time.Second, logger)
+	go relay.Run(context.Background())
+
+	scheduler := pkg.NewBillingScheduler(repository, time.Duration(billingMinutes)*time.Minute, logger)
+	go scheduler.Run(context.Background())
+
 	svc = pkg.NewLoggingService(svc, logger)
 
 	reqCounter := kitprometheus.NewCounterFrom(prometheus.CounterOpts{
@@ -96,17 +138,40 @@ func main() {
 
 	svc = pkg.NewInstrumentingService(svc, reqCounter, reqHistogram)
 
-	endpoints := pkg.CreateEndpoints(svc)
+	endpoints := pkg.WithHistoryEndpoints(pkg.CreateEndpoints(svc))
+
+	go func(endpoints pkg.Set) {
+		grpcListener, err := net.Listen("tcp", ":8080")
+		if err != nil {
+			panic(err)
+		}
+
+		defer grpcListener.Close()
+
+		server := grpc.NewServer()
+		proto.RegisterRentingServer(server, pkg.NewGRPCServer(endpoints))
+
+		if err := server.Serve(grpcListener); err != nil {
+			panic(err)
+		}
+	}(endpoints)
+
 	endpoints = pkg.WithEquipmentEndpoints(ic, endpoints)
 	endpoints = pkg.WithPaymentMethodEndpoints(pc, endpoints)
 	endpoints = pkg.WithPaymentTypeEndpoints(pc, endpoints)
 	endpoints = pkg.WithPaymentConditionEndpoints(pc, endpoints)
 	endpoints = pkg.WithCustomerEndpoints(cc, endpoints)
+	endpoints = pkg.WithVerifyEndpoints(ac, endpoints)
 
 	go func() {
 		http.Handle("/metrics", promhttp.Handler())
-		http.ListenAndServe(":8080", nil)
+		http.ListenAndServe(":8081", nil)
 	}()
 
-	http.ListenAndServe(":80", pkg.NewHTTPServer(endpoints))
+	contracts, err := pkg.NewContractRenderer(os.Getenv("CONTRACT_TEMPLATE"))
+	if err != nil {
+		panic(err)
+	}
+
+	http.ListenAndServe(":80", pkg.NewHTTPServer(endpoints, contracts))
 }
@@ -0,0 +1,181 @@
+package pkg
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+const (
+	enrichmentBatchSize = 100
+	enrichmentCacheSize = 10000
+	enrichmentTTL       = time.Minute
+)
+
+// BatchLoader fetches what rents refer to from other services many ids at a
+// time, keeping it cached for a while so that listing rents does not call them
+// once for every rent.
+type BatchLoader struct {
+	fetch    endpoint.Endpoint
+	size     int
+	capacity int
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	order   []cacheKey
+}
+
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+// cacheKey is an entry in the order it was stored. As every entry lives for
+// the same ttl, the oldest are the first to expire.
+type cacheKey struct {
+	id      string
+	expires time.Time
+}
+
+// NewBatchLoader loads through fetch, which takes up to size ids and returns
+// what it found as a map[string]any keyed by id. Found values are cached for
+// ttl, up to capacity of them, while ids not found are asked again on the next
+// load.
+func NewBatchLoader(fetch endpoint.Endpoint, size, capacity int, ttl time.Duration) *BatchLoader {
+	return &BatchLoader{
+		fetch:    fetch,
+		size:     size,
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]cacheEntry),
+	}
+}
+
+// Load returns what was found among the ids, fetching the ones not cached
+// concurrently in batches. When a batch fails, the values found by the others
+// are still returned along with the error.
+func (l *BatchLoader) Load(ctx context.Context, ids []string) (map[string]any, error) {
+	found, missing := l.cached(ids, time.Now())
+	return l.load(ctx, found, missing)
+}
+
+// LoadFresh fetches all the ids, skipping the cache, and caches what it finds
+// in place of what was there.
+func (l *BatchLoader) LoadFresh(ctx context.Context, ids []string) (map[string]any, error) {
+	return l.load(ctx, make(map[string]any, len(ids)), unique(ids))
+}
+
+func (l *BatchLoader) load(ctx context.Context, found map[string]any, missing []string) (map[string]any, error) {
+	fetched := make(map[string]any, len(missing))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		fetchErr error
+	)
+
+	for start := 0; start < len(missing); start += l.size {
+		end := start + l.size
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		wg.Add(1)
+		go func(batch []string) {
+			defer wg.Done()
+
+			res, err := l.fetch(ctx, batch)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				fetchErr = err
+				return
+			}
+
+			for id, value := range res.(map[string]any) {
+				fetched[id] = value
+			}
+		}(missing[start:end])
+	}
+
+	wg.Wait()
+	l.store(fetched, time.Now())
+
+	for id, value := range fetched {
+		found[id] = value
+	}
+
+	return found, fetchErr
+}
+
+// cached splits the ids between the values still cached and the unique ids
+// that have to be fetched.
+func (l *BatchLoader) cached(ids []string, now time.Time) (map[string]any, []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	found := make(map[string]any, len(ids))
+	missing := make([]string, 0)
+
+	for _, id := range unique(ids) {
+		entry, ok := l.entries[id]
+		if ok && now.Before(entry.expires) {
+			found[id] = entry.value
+			continue
+		}
+
+		delete(l.entries, id)
+		missing = append(missing, id)
+	}
+
+	return found, missing
+}
+
+// store caches the values, dropping the expired entries and then the oldest
+// ones while there are more than capacity.
+func (l *BatchLoader) store(values map[string]any, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expires := now.Add(l.ttl)
+	for id, value := range values {
+		l.entries[id] = cacheEntry{value, expires}
+		l.order = append(l.order, cacheKey{id, expires})
+	}
+
+	for len(l.order) > 0 {
+		oldest := l.order[0]
+		entry, ok := l.entries[oldest.id]
+		current := ok && entry.expires.Equal(oldest.expires)
+
+		if current && now.Before(oldest.expires) && len(l.entries) <= l.capacity {
+			break
+		}
+
+		if current {
+			delete(l.entries, oldest.id)
+		}
+		l.order = l.order[1:]
+	}
+}
+
+// unique drops the empty and repeated ids.
+func unique(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+
+	return result
+}
@@ -0,0 +1,141 @@
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+type fakeFetcher struct {
+	mu      sync.Mutex
+	batches [][]string
+	fail    bool
+}
+
+func (f *fakeFetcher) fetch(ctx context.Context, r any) (any, error) {
+	ids := r.([]string)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.batches = append(f.batches, ids)
+	if f.fail {
+		return nil, errors.New("service unavailable")
+	}
+
+	found := make(map[string]any)
+	for _, id := range ids {
+		if id != "missing" {
+			found[id] = &pkg.Customer{ID: id}
+		}
+	}
+
+	return found, nil
+}
+
+func TestBatchLoader(t *testing.T) {
+	ids := []string{"a", "b", "c", "a", "", "d", "e"}
+
+	t.Run("fetches unique ids in batches", func(t *testing.T) {
+		fetcher := &fakeFetcher{}
+		loader := pkg.NewBatchLoader(fetcher.fetch, 2, 100, time.Minute)
+
+		found, err := loader.Load(context.Background(), ids)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if len(found) != 5 {
+			t.Errorf("expected 5 values, got %d", len(found))
+		}
+
+		if len(fetcher.batches) != 3 {
+			t.Errorf("expected 3 batches, got %v", fetcher.batches)
+		}
+	})
+
+	t.Run("serves cached values", func(t *testing.T) {
+		fetcher := &fakeFetcher{}
+		loader := pkg.NewBatchLoader(fetcher.fetch, 10, 100, time.Minute)
+
+		loader.Load(context.Background(), []string{"a", "b"})
+		found, _ := loader.Load(context.Background(), []string{"a", "b", "c"})
+
+		if len(found) != 3 {
+			t.Errorf("expected 3 values, got %d", len(found))
+		}
+
+		if len(fetcher.batches) != 2 || len(fetcher.batches[1]) != 1 {
+			t.Errorf("expected only c to be fetched again, got %v", fetcher.batches)
+		}
+	})
+
+	t.Run("fetches again once expired", func(t *testing.T) {
+		fetcher := &fakeFetcher{}
+		loader := pkg.NewBatchLoader(fetcher.fetch, 10, 100, time.Millisecond)
+
+		loader.Load(context.Background(), []string{"a"})
+		time.Sleep(5 * time.Millisecond)
+		loader.Load(context.Background(), []string{"a"})
+
+		if len(fetcher.batches) != 2 {
+			t.Errorf("expected a to be fetched twice, got %v", fetcher.batches)
+		}
+	})
+
+	t.Run("does not cache what was not found", func(t *testing.T) {
+		fetcher := &fakeFetcher{}
+		loader := pkg.NewBatchLoader(fetcher.fetch, 10, 100, time.Minute)
+
+		found, _ := loader.Load(context.Background(), []string{"a", "missing"})
+		if _, ok := found["missing"]; ok {
+			t.Errorf("expected missing not to be found")
+		}
+
+		loader.Load(context.Background(), []string{"a", "missing"})
+		if len(fetcher.batches) != 2 || fetcher.batches[1][0] != "missing" {
+			t.Errorf("expected missing to be fetched again, got %v", fetcher.batches)
+		}
+	})
+
+	t.Run("fetches fresh values despite the cache", func(t *testing.T) {
+		fetcher := &fakeFetcher{}
+		loader := pkg.NewBatchLoader(fetcher.fetch, 10, 100, time.Minute)
+
+		loader.Load(context.Background(), []string{"a"})
+		found, err := loader.LoadFresh(context.Background(), []string{"a", "a"})
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if len(found) != 1 || len(fetcher.batches) != 2 || len(fetcher.batches[1]) != 1 {
+			t.Errorf("expected a to be fetched again once, got %v", fetcher.batches)
+		}
+	})
+
+	t.Run("keeps up to capacity", func(t *testing.T) {
+		fetcher := &fakeFetcher{}
+		loader := pkg.NewBatchLoader(fetcher.fetch, 10, 2, time.Minute)
+
+		loader.Load(context.Background(), []string{"a"})
+		loader.Load(context.Background(), []string{"b", "c"})
+		loader.Load(context.Background(), []string{"b", "c", "a"})
+
+		if len(fetcher.batches) != 3 || len(fetcher.batches[2]) != 1 || fetcher.batches[2][0] != "a" {
+			t.Errorf("expected only the oldest to be dropped, got %v", fetcher.batches)
+		}
+	})
+
+	t.Run("returns fetch errors", func(t *testing.T) {
+		fetcher := &fakeFetcher{fail: true}
+		loader := pkg.NewBatchLoader(fetcher.fetch, 10, 100, time.Minute)
+
+		if _, err := loader.Load(context.Background(), []string{"a"}); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const EventRentBilled = "rent.billed"
+
+type BillingCycle string
+
+const BillingMonthly BillingCycle = "monthly"
+
+// Billing charges a cycle of a rent billed by cycle. Cycles are counted from
+// one and billed in advance, charging the pieces still out when it starts.
+type Billing struct {
+	ID                 string          `json:"id" bson:"_id,omitempty"`
+	RentID             string          `json:"rent_id" bson:"rent_id"`
+	Cycle              int             `json:"cycle"`
+	CustomerID         string          `json:"customer_id" bson:"customer_id"`
+	PaymentConditionID string          `json:"payment_condition_id" bson:"payment_condition_id"`
+	StartDate          time.Time       `json:"start_date" bson:"start_date"`
+	EndDate            time.Time       `json:"end_date" bson:"end_date"`
+	Total              Money           `json:"total"`
+	Items              []RentEventItem `json:"items"`
+	CreatedAt          time.Time       `json:"created_at" bson:"created_at"`
+}
+
+func (r *Rent) IsBilledByCycle() bool {
+	return r.BillingCycle != ""
+}
+
+// GetNextBilling returns when the next cycle of the rent starts.
+func (r *Rent) GetNextBilling() time.Time {
+	return r.StartDate.AddDate(0, r.BilledCycles, 0)
+}
+
+// NewBilling prices the next cycle of the rent. Each piece is charged the
+// cheapest combination of periods covering the cycle, and the first cycle also
+// carries the delivery and the discount.
+func (r *Rent) NewBilling() *Billing {
+	start := r.GetNextBilling()
+	end := start.AddDate(0, 1, 0)
+
+	billing := &Billing{
+		RentID:             r.ID,
+		Cycle:              r.BilledCycles + 1,
+		CustomerID:         r.CustomerID,
+		PaymentConditionID: r.PaymentConditionID,
+		StartDate:          start,
+		EndDate:            end,
+		Items:              make([]RentEventItem, 0),
+	}
+
+	days := int(end.Sub(start).Hours() / 24)
+	for _, item := range r.GetOutstandingItems() {
+		value := r.chargeDays(item, float64(days))
+		if price := BestPrice(item.Equipment.RentingValues, days); price != nil {
+			value = price.Total
+		}
+
+		billing.addItem(
+			fmt.Sprintf("%d x %s", item.Qty, item.Equipment.Description),
+			value.Times(item.Qty),
+		)
+	}
+
+	if billing.Cycle == 1 {
+		billing.addItem("Delivery", r.DeliveryValue)
+		billing.Total -= r.Discount
+	}
+
+	return billing
+}
+
+func (b *Billing) addItem(description string, total Money) {
+	if total <= 0 {
+		return
+	}
+
+	b.Total += total
+	b.Items = append(b.Items, RentEventItem{description, total})
+}
+
+// NewBillingMessage creates the message sending the billing to payment, to be
+// invoiced on its own.
+func NewBillingMessage(billing *Billing) (*OutboxMessage, error) {
+	body, err := json.Marshal(billing)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &OutboxMessage{
+		ID:          primitive.NewObjectID().Hex(),
+		Exchange:    "renting",
+		Key:         EventRentBilled,
+		Body:        body,
+		NextAttempt: now,
+		CreatedAt:   now,
+	}, nil
+}
+
+type BillingRepository interface {
+	ListDueBillings(now time.Time) ([]*Rent, error)
+	CreateBilling(billing Billing, rent Rent) error
+}
+
+type billingScheduler struct {
+	repository BillingRepository
+	interval   time.Duration
+	logger     log.Logger
+}
+
+// NewBillingScheduler bills the cycles of the rents that started every
+// interval, until the rents are returned.
+func NewBillingScheduler(repository BillingRepository, interval time.Duration, logger log.Logger) *billingScheduler {
+	return &billingScheduler{repository, interval, logger}
+}
+
+func (s *billingScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Bill(time.Now())
+		}
+	}
+}
+
+// Bill creates the billings of every cycle started until now. A rent that
+// fails is left for the next run, the others are still billed.
+func (s *billingScheduler) Bill(now time.Time) []*Billing {
+	rents, err := s.repository.ListDueBillings(now)
+	if err != nil {
+		s.logger.Log("method", "Bill", "err", err)
+		return nil
+	}
+
+	billings := make([]*Billing, 0)
+	for _, rent := range rents {
+		for !rent.GetNextBilling().After(now) {
+			billing := rent.NewBilling()
+			billing.CreatedAt = now
+
+			if err := s.repository.CreateBilling(*billing, *rent); err != nil {
+				s.logger.Log("method", "Bill", "rent", rent.ID, "cycle", billing.Cycle, "err", err)
+				break
+			}
+
+			rent.BilledCycles++
+			rent.Version++
+			billings = append(billings, billing)
+		}
+	}
+
+	return billings
+}
@@ -0,0 +1,90 @@
+package pkg_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestBilling(t *testing.T) {
+	setup := func(t *testing.T) (pkg.Service, *fakeRepository, *pkg.Rent) {
+		repository := newFakeRepository()
+		svc, rent := newTestRent(t,
+			withRepository(repository),
+			withStatuses(pkg.StatusReserved, pkg.StatusActive),
+			withEquipment(func(equipment *pkg.Equipment) {
+				equipment.RentingValues = append(equipment.RentingValues, &pkg.RentingValue{
+					PeriodID: "monthly", Value: pkg.NewMoney(200), Period: &pkg.Period{QtyDays: 30},
+				})
+			}),
+			withRent(func(rent *pkg.Rent) {
+				rent.PeriodID = "monthly"
+				rent.EndDate = testStart.AddDate(0, 1, 0)
+				rent.BillingCycle = pkg.BillingMonthly
+				rent.DeliveryValue = pkg.NewMoney(50)
+			}),
+		)
+		return svc, repository, rent
+	}
+
+	t.Run("rent event leaves the renting out", func(t *testing.T) {
+		_, _, rent := setup(t)
+
+		if event := pkg.NewRentEvent(rent); event.Total != 0 || len(event.Items) != 0 {
+			t.Errorf("expected nothing to bill, got %s in %v", event.Total, event.Items)
+		}
+	})
+
+	t.Run("bills every cycle started", func(t *testing.T) {
+		_, repository, _ := setup(t)
+		scheduler := pkg.NewBillingScheduler(repository, time.Hour, log.NewNopLogger())
+
+		billings := scheduler.Bill(testStart.AddDate(0, 2, 1))
+		if len(billings) != 3 {
+			t.Fatalf("expected 3 billings, got %d", len(billings))
+		}
+
+		// march has 31 days, charged a month and a week, plus the delivery
+		if billings[0].Cycle != 1 || billings[0].Total != pkg.NewMoney(590) {
+			t.Errorf("expected first cycle of 590, got cycle %d of %s", billings[0].Cycle, billings[0].Total)
+		}
+
+		if !billings[1].StartDate.Equal(testStart.AddDate(0, 1, 0)) || billings[1].Total != pkg.NewMoney(400) {
+			t.Errorf("expected april cycle of 400, got %v of %s", billings[1].StartDate, billings[1].Total)
+		}
+
+		if again := scheduler.Bill(testStart.AddDate(0, 2, 1)); len(again) != 0 {
+			t.Errorf("expected cycles to be billed once, got %d more", len(again))
+		}
+	})
+
+	t.Run("charges pieces still out until returned", func(t *testing.T) {
+		svc, repository, rent := setup(t)
+		scheduler := pkg.NewBillingScheduler(repository, time.Hour, log.NewNopLogger())
+		scheduler.Bill(testStart)
+
+		rent, err := svc.ReturnItems(context.Background(), rent.ID, []pkg.ItemReturn{
+			{ItemID: rent.Items[0].ID, Qty: 1, Condition: pkg.ConditionGood},
+		})
+
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		billings := scheduler.Bill(testStart.AddDate(0, 1, 0))
+		if len(billings) != 1 || billings[0].Total != pkg.NewMoney(200) {
+			t.Fatalf("expected a cycle of 200 for the piece out, got %v", billings)
+		}
+
+		if _, err := svc.TransitionRent(context.Background(), rent.ID, pkg.StatusReturned); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if billings := scheduler.Bill(testStart.AddDate(0, 3, 0)); len(billings) != 0 {
+			t.Errorf("expected returned rent not to be billed, got %d billings", len(billings))
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"bytes"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/contract.tmpl
+var defaultContractTemplate string
+
+type ContractRenderer interface {
+	Render(w io.Writer, rent *Rent) error
+}
+
+type pdfContractRenderer struct {
+	template *template.Template
+}
+
+// NewContractRenderer parses the contract template found at path, falling
+// back to the default one when path is empty. Templates are plain text
+// executed with the rent, where lines starting with "# " and "## " are
+// rendered as titles and section headers.
+func NewContractRenderer(path string) (ContractRenderer, error) {
+	text := defaultContractTemplate
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text = string(content)
+	}
+
+	tmpl, err := template.New("contract").Funcs(template.FuncMap{
+		"money": func(value Money) string {
+			return "R$ " + value.String()
+		},
+		"date": func(date time.Time) string {
+			return date.Local().Format("02/01/2006")
+		},
+	}).Parse(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &pdfContractRenderer{tmpl}, nil
+}
+
+func (r *pdfContractRenderer) Render(w io.Writer, rent *Rent) error {
+	var buf bytes.Buffer
+	if err := r.template.Execute(&buf, rent); err != nil {
+		return err
+	}
+
+	document := newPDFDocument()
+	for _, line := range strings.Split(buf.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			document.AddLine(strings.TrimPrefix(line, "## "), true, 11)
+		case strings.HasPrefix(line, "# "):
+			document.AddLine(strings.TrimPrefix(line, "# "), true, 14)
+		default:
+			document.AddLine(line, false, 10)
+		}
+	}
+
+	_, err := document.WriteTo(w)
+	return err
+}
@@ -0,0 +1,47 @@
+package pkg_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestContractRenderer(t *testing.T) {
+	renderer, err := pkg.NewContractRenderer("")
+	if err != nil {
+		t.Fatalf("could not parse default template: %v", err)
+	}
+
+	rent := &pkg.Rent{
+		ID:        "someid",
+		PeriodID:  "weekly",
+		StartDate: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2023, 3, 8, 0, 0, 0, 0, time.UTC),
+		Customer:  &pkg.Customer{Name: "John Doe", CpfCnpj: "12345678900"},
+		Items: []*pkg.Item{
+			{Qty: 2, Equipment: &pkg.Equipment{
+				Description:   "Andaime (1,5m)",
+				RentingValues: []*pkg.RentingValue{{PeriodID: "weekly", Value: pkg.NewMoney(70)}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, rent); err != nil {
+		t.Fatalf("could not render contract: %v", err)
+	}
+
+	content := buf.String()
+	if !strings.HasPrefix(content, "%PDF-") || !strings.HasSuffix(content, "%%EOF\n") {
+		t.Error("expected a PDF document")
+	}
+
+	for _, expected := range []string{"(Nome: John Doe)", `2 x Andaime \(1,5m\) - R$ 140.00`} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected contract to contain %q", expected)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RoleManager is the role of the users who may authorize a rent over the
+// credit limit.
+const RoleManager = "manager"
+
+type CreditService interface {
+	GetCreditLimit(customerID string) (Money, error)
+	ListUnpaidInvoices(customerID string) ([]*UnpaidInvoice, error)
+}
+
+type UnpaidInvoice struct {
+	ID      string    `json:"id"`
+	RentID  string    `json:"rent_id,omitempty"`
+	Cycle   int       `json:"cycle,omitempty"`
+	Total   Money     `json:"total"`
+	DueDate time.Time `json:"due_date"`
+}
+
+// CreditOverride lets a rent take the customer over the credit limit, keeping
+// who authorized it and why.
+type CreditOverride struct {
+	Reason       string    `json:"reason" bson:"reason"`
+	AuthorizedBy *User     `json:"authorized_by" bson:"authorized_by"`
+	AuthorizedAt time.Time `json:"authorized_at" bson:"authorized_at"`
+}
+
+// Exposure is what a customer still owes, in the remaining of the open rents
+// and in unpaid invoices. Customers without a credit limit have nothing
+// available.
+type Exposure struct {
+	CustomerID     string `json:"customer_id"`
+	CreditLimit    Money  `json:"credit_limit"`
+	OpenRents      Money  `json:"open_rents"`
+	UnpaidInvoices Money  `json:"unpaid_invoices"`
+	Total          Money  `json:"total"`
+	Available      Money  `json:"available"`
+}
+
+// owingStatuses are the statuses of rents the customer may still owe. Rents
+// are only closed once settled.
+var owingStatuses = []Status{StatusReserved, StatusActive, StatusPartiallyReturned, StatusReturned}
+
+// NewExposure adds up what the customer owes in the rents and invoices.
+// Invoices of the open rents are left out, as their remaining already counts
+// what was billed.
+func NewExposure(customerID string, limit Money, rents []*Rent, invoices []*UnpaidInvoice) *Exposure {
+	exposure := &Exposure{CustomerID: customerID, CreditLimit: limit}
+	open := make(map[string]bool)
+
+	for _, rent := range rents {
+		if rent.CustomerID != customerID || !rent.isOwing() {
+			continue
+		}
+
+		open[rent.ID] = true
+		if remaining := rent.GetRemaining(); remaining > 0 {
+			exposure.OpenRents += remaining
+		}
+	}
+
+	for _, invoice := range invoices {
+		if invoice.RentID == "" || !open[invoice.RentID] {
+			exposure.UnpaidInvoices += invoice.Total
+		}
+	}
+
+	exposure.Total = exposure.OpenRents + exposure.UnpaidInvoices
+	if limit > 0 {
+		exposure.Available = limit - exposure.Total
+	}
+
+	return exposure
+}
+
+// Exceeds tells whether the rent would take the customer over the credit
+// limit. Rents being created, changed or booked are drafts, which are not
+// counted yet.
+func (e *Exposure) Exceeds(rent *Rent) bool {
+	return e.CreditLimit > 0 && e.Total+rent.GetRemaining() > e.CreditLimit
+}
+
+func (r *Rent) isOwing() bool {
+	return r.GetStatus().isOwing()
+}
+
+func (s Status) isOwing() bool {
+	for _, status := range owingStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeOverride stamps the override sent along with the request with the
+// user making it, so that it cannot be authorized on someone else's behalf.
+// Only managers may authorize overrides.
+func authorizeOverride(ctx context.Context, override *CreditOverride) error {
+	if override == nil {
+		return nil
+	}
+
+	user := UserFromContext(ctx)
+	if !user.HasRole(RoleManager) {
+		return NewError(
+			http.StatusForbidden,
+			"override not allowed",
+			"only managers can authorize going over the credit limit",
+		)
+	}
+
+	override.AuthorizedBy = user
+	override.AuthorizedAt = time.Now()
+
+	return nil
+}
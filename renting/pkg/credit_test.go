@@ -0,0 +1,252 @@
+package pkg_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestCredit(t *testing.T) {
+	newRent := func(qty int) pkg.Rent {
+		rent := newTestRentData(newTestEquipment(), qty)
+		rent.CustomerID = "customer"
+		return rent
+	}
+
+	// the customer owes 70 for an active rent, already invoiced, and 50 for
+	// an invoice of something else, out of a limit of 200
+	setup := func() (pkg.Service, *fakeRepository) {
+		repository := newFakeRepository()
+
+		open := newRent(1)
+		open.ID = "open"
+		open.Status = pkg.StatusActive
+		open.StartDate = time.Now().AddDate(0, 0, -1)
+		open.EndDate = open.StartDate.AddDate(0, 0, 7)
+		repository.rents[open.ID] = &open
+
+		closed := newRent(5)
+		closed.ID = "closed"
+		closed.Status = pkg.StatusClosed
+		repository.rents[closed.ID] = &closed
+
+		credit := &fakeCredit{
+			limit: pkg.NewMoney(200),
+			invoices: []*pkg.UnpaidInvoice{
+				{ID: "rent", RentID: "open", Total: pkg.NewMoney(70)},
+				{ID: "other", Total: pkg.NewMoney(50)},
+			},
+		}
+
+		return newTestService(withRepository(repository), withCredit(credit)), repository
+	}
+
+	t.Run("exposure adds open rents and unpaid invoices", func(t *testing.T) {
+		svc, _ := setup()
+
+		exposure, err := svc.GetExposure("customer")
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if exposure.OpenRents != pkg.NewMoney(70) || exposure.UnpaidInvoices != pkg.NewMoney(50) {
+			t.Errorf("expected 70 in rents and 50 in invoices, got %s and %s", exposure.OpenRents, exposure.UnpaidInvoices)
+		}
+
+		if exposure.Total != pkg.NewMoney(120) || exposure.Available != pkg.NewMoney(80) {
+			t.Errorf("expected 120 owed with 80 available, got %s and %s", exposure.Total, exposure.Available)
+		}
+	})
+
+	t.Run("creates rents within the limit", func(t *testing.T) {
+		svc, _ := setup()
+
+		if _, err := svc.CreateRent(context.Background(), newRent(1)); err != nil {
+			t.Errorf("did not expect error: %v", err)
+		}
+	})
+
+	t.Run("rejects rents over the limit", func(t *testing.T) {
+		svc, _ := setup()
+
+		_, err := svc.CreateRent(context.Background(), newRent(2))
+		if e, ok := err.(pkg.Error); !ok || e.StatusCode() != http.StatusUnprocessableEntity {
+			t.Errorf("expected credit limit exceeded, got %v", err)
+		}
+	})
+
+	t.Run("rejects changes over the limit", func(t *testing.T) {
+		svc, _ := setup()
+
+		rent, err := svc.CreateRent(context.Background(), newRent(1))
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		data := newRent(3)
+		data.Version = rent.Version
+
+		if _, err := svc.UpdateRent(context.Background(), rent.ID, data); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("changes keep the authorized override", func(t *testing.T) {
+		svc, _ := setup()
+		endpoints := pkg.CreateEndpoints(svc)
+		ctx := pkg.ContextWithUser(context.Background(), &pkg.User{ID: "manager", Name: "Gerente", Roles: []string{pkg.RoleManager}})
+
+		rent := newRent(2)
+		rent.CreditOverride = &pkg.CreditOverride{Reason: "cliente antigo"}
+
+		res, err := endpoints.Create(ctx, rent)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		data := newRent(3)
+		data.Version = res.(*pkg.Rent).Version
+
+		updated, err := svc.UpdateRent(context.Background(), res.(*pkg.Rent).ID, data)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if updated.CreditOverride == nil || updated.CreditOverride.AuthorizedBy.ID != "manager" {
+			t.Errorf("expected the override to be kept, got %+v", updated.CreditOverride)
+		}
+	})
+
+	t.Run("override records who authorized it", func(t *testing.T) {
+		svc, _ := setup()
+		endpoints := pkg.CreateEndpoints(svc)
+		ctx := pkg.ContextWithUser(context.Background(), &pkg.User{ID: "manager", Name: "Gerente", Roles: []string{pkg.RoleManager}})
+
+		rent := newRent(2)
+		rent.CreditOverride = &pkg.CreditOverride{Reason: "cliente antigo"}
+
+		res, err := endpoints.Create(ctx, rent)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		override := res.(*pkg.Rent).CreditOverride
+		if override.AuthorizedBy == nil || override.AuthorizedBy.ID != "manager" || override.AuthorizedAt.IsZero() {
+			t.Errorf("expected override authorized by manager, got %+v", override)
+		}
+	})
+
+	t.Run("override needs a manager", func(t *testing.T) {
+		svc, _ := setup()
+		ctx := pkg.ContextWithUser(context.Background(), &pkg.User{ID: "clerk", Name: "Atendente"})
+
+		rent := newRent(2)
+		rent.CreditOverride = &pkg.CreditOverride{Reason: "cliente antigo"}
+
+		_, err := pkg.CreateEndpoints(svc).Create(ctx, rent)
+		if e, ok := err.(pkg.Error); !ok || e.StatusCode() != http.StatusForbidden {
+			t.Errorf("expected override not allowed, got %v", err)
+		}
+	})
+
+	t.Run("override needs an authenticated user", func(t *testing.T) {
+		rent := newRent(2)
+		rent.CreditOverride = &pkg.CreditOverride{Reason: "cliente antigo"}
+
+		svc, _ := setup()
+
+		if _, err := pkg.CreateEndpoints(svc).Create(context.Background(), rent); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("checks the limit again when reserving", func(t *testing.T) {
+		svc, repository := setup()
+
+		rent, err := svc.CreateRent(context.Background(), newRent(1))
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		// another rent was reserved in the meantime
+		other := *repository.rents["open"]
+		other.ID = "other"
+		repository.rents[other.ID] = &other
+
+		_, err = svc.TransitionRent(context.Background(), rent.ID, pkg.StatusReserved)
+		if e, ok := err.(pkg.Error); !ok || e.StatusCode() != http.StatusUnprocessableEntity {
+			t.Errorf("expected credit limit exceeded, got %v", err)
+		}
+
+		rent, _ = svc.GetRent(rent.ID)
+		if rent.GetStatus() != pkg.StatusDraft {
+			t.Errorf("expected rent to stay a draft, got %s", rent.GetStatus())
+		}
+	})
+
+	t.Run("checks the limit when converting estimates", func(t *testing.T) {
+		svc, _ := setup()
+
+		estimate, err := svc.CreateEstimate(newRent(2))
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		_, err = svc.ConvertEstimate(context.Background(), estimate.ID, nil)
+		if e, ok := err.(pkg.Error); !ok || e.StatusCode() != http.StatusUnprocessableEntity {
+			t.Errorf("expected credit limit exceeded, got %v", err)
+		}
+	})
+
+	t.Run("ignores overrides sent with estimates", func(t *testing.T) {
+		svc, _ := setup()
+		endpoints := pkg.CreateEndpoints(svc)
+		ctx := pkg.ContextWithUser(context.Background(), &pkg.User{ID: "clerk", Name: "Atendente"})
+
+		rent := newRent(2)
+		rent.CreditOverride = &pkg.CreditOverride{
+			Reason:       "cliente antigo",
+			AuthorizedBy: &pkg.User{ID: "manager", Roles: []string{pkg.RoleManager}},
+		}
+
+		res, err := endpoints.CreateEstimate(ctx, rent)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		req := pkg.ConvertEstimateRequest{ID: res.(*pkg.Estimate).ID}
+		_, err = endpoints.ConvertEstimate(ctx, req)
+		if e, ok := err.(pkg.Error); !ok || e.StatusCode() != http.StatusUnprocessableEntity {
+			t.Errorf("expected credit limit exceeded, got %v", err)
+		}
+	})
+
+	t.Run("managers can override when converting estimates", func(t *testing.T) {
+		svc, _ := setup()
+		endpoints := pkg.CreateEndpoints(svc)
+		ctx := pkg.ContextWithUser(context.Background(), &pkg.User{ID: "manager", Name: "Gerente", Roles: []string{pkg.RoleManager}})
+
+		res, err := endpoints.CreateEstimate(ctx, newRent(2))
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		req := pkg.ConvertEstimateRequest{
+			ID:             res.(*pkg.Estimate).ID,
+			CreditOverride: &pkg.CreditOverride{Reason: "cliente antigo"},
+		}
+
+		res, err = endpoints.ConvertEstimate(ctx, req)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		override := res.(*pkg.Rent).CreditOverride
+		if override == nil || override.AuthorizedBy == nil || override.AuthorizedBy.ID != "manager" {
+			t.Errorf("expected override authorized by manager, got %+v", override)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package pkg_test
+
+import (
+	"context"
+	"testing"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestDamages(t *testing.T) {
+	setup := func(t *testing.T) (pkg.Service, *fakeInventory, *pkg.Rent) {
+		inventory := &fakeInventory{}
+		svc, rent := newTestRent(t,
+			withInventory(inventory),
+			withQty(5),
+			withStatuses(pkg.StatusReserved, pkg.StatusActive),
+			withEquipment(func(equipment *pkg.Equipment) {
+				equipment.Description = "Betoneira"
+				equipment.ReplaceValue = pkg.NewMoney(1500)
+			}),
+		)
+		return svc, inventory, rent
+	}
+
+	t.Run("charges assessed damages", func(t *testing.T) {
+		svc, inventory, rent := setup(t)
+
+		rent, err := svc.ReturnItems(context.Background(), rent.ID, []pkg.ItemReturn{
+			{ItemID: rent.Items[0].ID, Qty: 2, Condition: pkg.ConditionDamaged, Charge: pkg.NewMoney(120)},
+		})
+
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if damages := rent.GetDamagesTotal(); damages != pkg.NewMoney(120) {
+			t.Errorf("expected damages 120, got %s", damages)
+		}
+
+		if inventory.stock["equipment"] != 3 || len(inventory.writtenOff) != 0 {
+			t.Errorf("expected damaged pieces back in stock, got %d taken and %d written off", inventory.stock["equipment"], len(inventory.writtenOff))
+		}
+	})
+
+	t.Run("charges lost pieces at replacement value", func(t *testing.T) {
+		svc, inventory, rent := setup(t)
+
+		rent, err := svc.ReturnItems(context.Background(), rent.ID, []pkg.ItemReturn{
+			{ItemID: rent.Items[0].ID, Qty: 3, Condition: pkg.ConditionGood},
+			{ItemID: rent.Items[0].ID, Qty: 2, Condition: pkg.ConditionLost, Charge: pkg.NewMoney(10)},
+		})
+
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if rent.GetStatus() != pkg.StatusReturned {
+			t.Errorf("expected status %s, got %s", pkg.StatusReturned, rent.GetStatus())
+		}
+
+		if damages := rent.GetDamagesTotal(); damages != pkg.NewMoney(3000) {
+			t.Errorf("expected damages 3000, got %s", damages)
+		}
+
+		if inventory.stock["equipment"] != 2 {
+			t.Errorf("expected lost pieces to stay out of stock, got %d taken", inventory.stock["equipment"])
+		}
+
+		if len(inventory.writtenOff) != 1 || inventory.writtenOff[0].Qty != 2 {
+			t.Errorf("expected 2 pieces written off, got %v", inventory.writtenOff)
+		}
+
+		event := pkg.NewRentEvent(rent)
+		last := event.Items[len(event.Items)-1]
+		if last.Description != "2 x Betoneira lost" || last.Total != pkg.NewMoney(3000) {
+			t.Errorf("expected lost pieces to be billed, got %s of %s", last.Description, last.Total)
+		}
+	})
+}
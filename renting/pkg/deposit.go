@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type DepositStatus string
+
+const (
+	DepositHeld     DepositStatus = "held"
+	DepositRefunded DepositStatus = "refunded"
+	DepositRetained DepositStatus = "retained"
+)
+
+// Deposit is the caução paid by the customer before taking the equipment. It
+// is held until the rent is returned, when it is refunded minus the
+// deductions for damages.
+type Deposit struct {
+	Amount          Money         `json:"amount" validate:"required,gt=0"`
+	PaymentMethodID string        `json:"payment_method_id" bson:"payment_method_id" validate:"required,payment_method"`
+	Status          DepositStatus `json:"status"`
+	Deductions      []*Deduction  `json:"deductions"`
+	Refunded        Money         `json:"refunded"`
+	SettledAt       time.Time     `json:"settled_at,omitempty" bson:"settled_at,omitempty"`
+}
+
+type Deduction struct {
+	Reason    string    `json:"reason" validate:"required"`
+	Value     Money     `json:"value" validate:"required,gt=0"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+func (d *Deposit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"amount":            d.Amount,
+		"payment_method_id": d.PaymentMethodID,
+		"status":            d.Status,
+		"deductions":        d.Deductions,
+		"deducted":          d.GetDeducted(),
+		"balance":           d.GetBalance(),
+		"refunded":          d.Refunded,
+		"retained":          d.GetRetained(),
+		"settled_at":        d.SettledAt,
+	})
+}
+
+func (d *Deposit) IsHeld() bool {
+	return d.Status == "" || d.Status == DepositHeld
+}
+
+func (d *Deposit) GetDeducted() Money {
+	total := Money(0)
+	for _, deduction := range d.Deductions {
+		total += deduction.Value
+	}
+	return total
+}
+
+// GetBalance is how much of the deposit can still be refunded.
+func (d *Deposit) GetBalance() Money {
+	if !d.IsHeld() {
+		return 0
+	}
+	return d.Amount - d.GetDeducted()
+}
+
+// GetRetained is how much of the deposit was kept once it was settled.
+func (d *Deposit) GetRetained() Money {
+	if d.IsHeld() {
+		return 0
+	}
+	return d.Amount - d.Refunded
+}
+
+// settle refunds value and retains the rest of the amount.
+func (d *Deposit) settle(value Money) {
+	d.Refunded = value
+	d.SettledAt = time.Now()
+
+	d.Status = DepositRefunded
+	if value == 0 {
+		d.Status = DepositRetained
+	}
+}
+
+// GetDepositCharged is how much of the deposit the customer is charged: all
+// of it while held, and only what was retained once settled.
+func (r *Rent) GetDepositCharged() Money {
+	if r.Deposit == nil {
+		return 0
+	}
+	if r.Deposit.IsHeld() {
+		return r.Deposit.Amount
+	}
+	return r.Deposit.GetRetained()
+}
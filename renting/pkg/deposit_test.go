@@ -0,0 +1,108 @@
+package pkg_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestDeposit(t *testing.T) {
+	setup := func(t *testing.T, statuses ...pkg.Status) (pkg.Service, *pkg.Rent) {
+		return newTestRent(t, withQty(1), withStatuses(statuses...), withRent(func(rent *pkg.Rent) {
+			rent.Deposit = &pkg.Deposit{
+				Amount:          pkg.NewMoney(200),
+				PaymentMethodID: "cash",
+				Refunded:        pkg.NewMoney(200),
+			}
+		}))
+	}
+
+	t.Run("held on creation", func(t *testing.T) {
+		_, rent := setup(t)
+
+		if rent.Deposit.Status != pkg.DepositHeld || rent.Deposit.Refunded != 0 {
+			t.Errorf("expected held deposit, got %s with %s refunded", rent.Deposit.Status, rent.Deposit.Refunded)
+		}
+
+		if remaining := rent.GetRemaining(); remaining != pkg.NewMoney(270) {
+			t.Errorf("expected remaining 270, got %s", remaining)
+		}
+
+		event := pkg.NewRentEvent(rent)
+		if event.Total != pkg.NewMoney(270) {
+			t.Errorf("expected total 270, got %s", event.Total)
+		}
+	})
+
+	t.Run("deducts damages", func(t *testing.T) {
+		svc, rent := setup(t, pkg.StatusReserved, pkg.StatusActive)
+
+		rent, err := svc.DeductDeposit(context.Background(), rent.ID, pkg.Deduction{Reason: "broken handle", Value: pkg.NewMoney(50)})
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if balance := rent.Deposit.GetBalance(); balance != pkg.NewMoney(150) {
+			t.Errorf("expected balance 150, got %s", balance)
+		}
+
+		_, err = svc.DeductDeposit(context.Background(), rent.ID, pkg.Deduction{Reason: "lost", Value: pkg.NewMoney(200)})
+		if err == nil || err.(pkg.Error).StatusCode() != http.StatusBadRequest {
+			t.Errorf("expected bad request, got %v", err)
+		}
+	})
+
+	t.Run("refunds what was not deducted", func(t *testing.T) {
+		svc, rent := setup(t, pkg.StatusReserved, pkg.StatusActive)
+
+		if _, err := svc.DeductDeposit(context.Background(), rent.ID, pkg.Deduction{Reason: "broken handle", Value: pkg.NewMoney(50)}); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if _, err := svc.RefundDeposit(context.Background(), rent.ID, 0); err == nil {
+			t.Error("expected error refunding before return, got nothing")
+		}
+
+		if _, err := svc.TransitionRent(context.Background(), rent.ID, pkg.StatusReturned); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		rent, err := svc.RefundDeposit(context.Background(), rent.ID, 0)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if rent.Deposit.Status != pkg.DepositRefunded || rent.Deposit.Refunded != pkg.NewMoney(150) {
+			t.Errorf("expected 150 refunded, got %s with %s", rent.Deposit.Status, rent.Deposit.Refunded)
+		}
+
+		event := pkg.NewRentEvent(rent)
+		last := event.Items[len(event.Items)-1]
+		if last.Description != "Retained deposit" || last.Total != pkg.NewMoney(50) {
+			t.Errorf("expected retained deposit of 50, got %s of %s", last.Description, last.Total)
+		}
+
+		if _, err := svc.RefundDeposit(context.Background(), rent.ID, 0); err == nil {
+			t.Error("expected error refunding twice, got nothing")
+		}
+	})
+
+	t.Run("refunds in part", func(t *testing.T) {
+		svc, rent := setup(t, pkg.StatusReserved, pkg.StatusActive, pkg.StatusReturned)
+
+		if _, err := svc.RefundDeposit(context.Background(), rent.ID, pkg.NewMoney(250)); err == nil {
+			t.Error("expected error refunding more than the deposit, got nothing")
+		}
+
+		rent, err := svc.RefundDeposit(context.Background(), rent.ID, pkg.NewMoney(120))
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if retained := rent.Deposit.GetRetained(); retained != pkg.NewMoney(80) {
+			t.Errorf("expected 80 retained, got %s", retained)
+		}
+	})
+}
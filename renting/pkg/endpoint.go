@@ -3,43 +3,78 @@ package pkg
 import (
 	"context"
 	"math"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
 )
 
 type Set struct {
-	Create endpoint.Endpoint
-	List   endpoint.Endpoint
-	Update endpoint.Endpoint
-	Delete endpoint.Endpoint
-	Get    endpoint.Endpoint
+	Create     endpoint.Endpoint
+	List       endpoint.Endpoint
+	Update     endpoint.Endpoint
+	Delete     endpoint.Endpoint
+	Get        endpoint.Endpoint
+	Transition endpoint.Endpoint
+	Return     endpoint.Endpoint
+	Extend     endpoint.Endpoint
+	Deduct     endpoint.Endpoint
+	Refund     endpoint.Endpoint
+	History    endpoint.Endpoint
+
+	CreateEstimate  endpoint.Endpoint
+	GetEstimate     endpoint.Endpoint
+	ConvertEstimate endpoint.Endpoint
+
+	StuckSagas    endpoint.Endpoint
+	Occupancy     endpoint.Endpoint
+	OccupancyFeed endpoint.Endpoint
+	Exposure      endpoint.Endpoint
 }
 
 func CreateEndpoints(svc Service) Set {
 	return Set{
-		Create: createRentEndpoint(svc),
-		List:   createListEndpoint(svc),
-		Update: createUpdateEndpoint(svc),
-		Delete: createDeleteEndpoint(svc),
-		Get:    createGetEndpoint(svc),
+		Create:     createRentEndpoint(svc),
+		List:       createListEndpoint(svc),
+		Update:     createUpdateEndpoint(svc),
+		Delete:     createDeleteEndpoint(svc),
+		Get:        createGetEndpoint(svc),
+		Transition: createTransitionEndpoint(svc),
+		Return:     createReturnEndpoint(svc),
+		Extend:     createExtendEndpoint(svc),
+		Deduct:     createDeductEndpoint(svc),
+		Refund:     createRefundEndpoint(svc),
+		History:    createHistoryEndpoint(svc),
+
+		CreateEstimate:  createEstimateEndpoint(svc),
+		GetEstimate:     createGetEstimateEndpoint(svc),
+		ConvertEstimate: createConvertEstimateEndpoint(svc),
+
+		StuckSagas:    createStuckSagasEndpoint(svc),
+		Occupancy:     createOccupancyEndpoint(svc),
+		OccupancyFeed: createOccupancyEndpoint(svc),
+		Exposure:      createExposureEndpoint(svc),
 	}
 }
 
 func createRentEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
-		return svc.CreateRent(r.(Rent))
+		rent := r.(Rent)
+		if err := authorizeOverride(ctx, rent.CreditOverride); err != nil {
+			return nil, err
+		}
+		return svc.CreateRent(ctx, rent)
 	}
 }
 
 func createListEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
-		pagination := r.(Pagination)
-		rents, total, err := svc.ListRents(pagination.Page, pagination.PerPage)
+		req := r.(ListRequest)
+		rents, total, err := svc.ListRents(req.Filter, req.Page, req.PerPage)
 		if err != nil {
 			return nil, err
 		}
 
-		pages := int64(math.Max(1, math.Round(float64(total/pagination.PerPage))))
+		pages := int64(math.Max(1, math.Ceil(float64(total)/float64(req.PerPage))))
 
 		items := make([]any, len(rents))
 		for i, rent := range rents {
@@ -55,6 +90,11 @@ type Pagination struct {
 	PerPage int64 `json:"per_page"`
 }
 
+type ListRequest struct {
+	Pagination
+	Filter RentFilter `json:"filter"`
+}
+
 type ListResult struct {
 	Items      []any `json:"items"`
 	TotalPages int64 `json:"total_pages"`
@@ -64,7 +104,10 @@ type ListResult struct {
 func createUpdateEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
 		req := r.(UpdateRequest)
-		return svc.UpdateRent(req.ID, req.Data)
+		if err := authorizeOverride(ctx, req.Data.CreditOverride); err != nil {
+			return nil, err
+		}
+		return svc.UpdateRent(ctx, req.ID, req.Data)
 	}
 }
 
@@ -75,7 +118,7 @@ type UpdateRequest struct {
 
 func createDeleteEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, r any) (any, error) {
-		return nil, svc.DeleteRent(r.(string))
+		return nil, svc.DeleteRent(ctx, r.(string))
 	}
 }
 
@@ -84,3 +127,121 @@ func createGetEndpoint(svc Service) endpoint.Endpoint {
 		return svc.GetRent(r.(string))
 	}
 }
+
+func createTransitionEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(TransitionRequest)
+		return svc.TransitionRent(ctx, req.ID, req.Status)
+	}
+}
+
+type TransitionRequest struct {
+	ID     string `json:"id"`
+	Status Status `json:"status"`
+}
+
+func createReturnEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(ReturnRequest)
+		return svc.ReturnItems(ctx, req.ID, req.Items)
+	}
+}
+
+type ReturnRequest struct {
+	ID    string       `json:"id"`
+	Items []ItemReturn `json:"items"`
+}
+
+func createExtendEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(ExtendRequest)
+		return svc.ExtendRent(ctx, req.ID, req.EndDate)
+	}
+}
+
+type ExtendRequest struct {
+	ID      string    `json:"id"`
+	EndDate time.Time `json:"end_date"`
+}
+
+func createDeductEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(DeductRequest)
+		return svc.DeductDeposit(ctx, req.ID, req.Deduction)
+	}
+}
+
+type DeductRequest struct {
+	ID        string    `json:"id"`
+	Deduction Deduction `json:"deduction"`
+}
+
+func createRefundEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(RefundRequest)
+		return svc.RefundDeposit(ctx, req.ID, req.Value)
+	}
+}
+
+type RefundRequest struct {
+	ID    string `json:"id"`
+	Value Money  `json:"value"`
+}
+
+func createHistoryEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.GetRentHistory(r.(string))
+	}
+}
+
+func createEstimateEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.CreateEstimate(r.(Rent))
+	}
+}
+
+func createGetEstimateEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.GetEstimate(r.(string))
+	}
+}
+
+func createConvertEstimateEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(ConvertEstimateRequest)
+		if err := authorizeOverride(ctx, req.CreditOverride); err != nil {
+			return nil, err
+		}
+		return svc.ConvertEstimate(ctx, req.ID, req.CreditOverride)
+	}
+}
+
+type ConvertEstimateRequest struct {
+	ID             string          `json:"id"`
+	CreditOverride *CreditOverride `json:"credit_override"`
+}
+
+func createStuckSagasEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.ListStuckSagas(r.(time.Duration))
+	}
+}
+
+func createOccupancyEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		req := r.(OccupancyRequest)
+		return svc.GetOccupancy(req.EquipmentID, req.From, req.To)
+	}
+}
+
+type OccupancyRequest struct {
+	EquipmentID string    `json:"equipment_id"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+func createExposureEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, r any) (any, error) {
+		return svc.GetExposure(r.(string))
+	}
+}
@@ -1,6 +1,17 @@
 package pkg
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"net/http"
+
+	"reconcip.com.br/microservices/renting/proto"
+)
+
+var errVersionConflict = NewError(
+	http.StatusPreconditionFailed,
+	"rent was changed",
+	"someone else changed the rent, reload it and try again",
+)
 
 type Error struct {
 	Status int    `json:"status"`
@@ -27,3 +38,25 @@ func (e Error) MarshalJSON() ([]byte, error) {
 		"detail": e.Detail,
 	})
 }
+
+func (e Error) AsError() *proto.Error {
+	return &proto.Error{
+		Status: uint32(e.StatusCode()),
+		Title:  e.Title,
+		Detail: e.Detail,
+	}
+}
+
+// replyError turns the errors of the endpoints into the errors sent in gRPC
+// replies, hiding the ones that are not meant for clients.
+func replyError(err error) *proto.Error {
+	if e, ok := err.(Error); ok {
+		return e.AsError()
+	}
+
+	return &proto.Error{
+		Status: http.StatusInternalServerError,
+		Title:  "something went wrong",
+		Detail: "something went wrong while fetching rents",
+	}
+}
@@ -0,0 +1,79 @@
+package pkg_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestEstimate(t *testing.T) {
+	newRent := func() pkg.Rent {
+		return newTestRentData(newTestEquipment(), 2)
+	}
+
+	t.Run("converts into a rent", func(t *testing.T) {
+		repository := newFakeRepository()
+		svc := newTestService(withRepository(repository))
+
+		estimate, err := svc.CreateEstimate(newRent())
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if estimate.Total != pkg.NewMoney(140) {
+			t.Errorf("expected total 140, got %s", estimate.Total)
+		}
+
+		if len(repository.rents) != 0 {
+			t.Error("did not expect estimate to create a rent")
+		}
+
+		rent, err := svc.ConvertEstimate(context.Background(), estimate.ID, nil)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if rent.GetStatus() != pkg.StatusDraft {
+			t.Errorf("expected status %s, got %s", pkg.StatusDraft, rent.GetStatus())
+		}
+
+		if repository.estimates[estimate.ID].RentID != rent.ID {
+			t.Errorf("expected estimate to point to rent %s", rent.ID)
+		}
+
+		if _, err := svc.ConvertEstimate(context.Background(), estimate.ID, nil); err == nil {
+			t.Error("expected error converting estimate twice")
+		}
+	})
+
+	t.Run("refuses changed prices", func(t *testing.T) {
+		repository := newFakeRepository()
+		svc := newTestService(withRepository(repository), withEquipment(func(equipment *pkg.Equipment) {
+			equipment.RentingValues[0].Value = pkg.NewMoney(80)
+		}))
+
+		estimate, _ := svc.CreateEstimate(newRent())
+
+		_, err := svc.ConvertEstimate(context.Background(), estimate.ID, nil)
+		if e, ok := err.(pkg.Error); !ok || e.StatusCode() != http.StatusConflict {
+			t.Errorf("expected conflict, got %v", err)
+		}
+
+		if len(repository.rents) != 0 {
+			t.Error("did not expect rent to be created")
+		}
+	})
+
+	t.Run("refuses expired estimates", func(t *testing.T) {
+		svc := newTestService(withEstimateValidity(-time.Hour))
+
+		estimate, _ := svc.CreateEstimate(newRent())
+
+		if _, err := svc.ConvertEstimate(context.Background(), estimate.ID, nil); err == nil {
+			t.Error("expected error converting expired estimate")
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	EventRentCreated   = "rent.created"
+	EventRentUpdated   = "rent.updated"
+	EventRentCancelled = "rent.cancelled"
+)
+
+// RentEvent is published whenever a rent changes so that other services, like
+// payment and delivery, can bill it and schedule its deliveries.
+type RentEvent struct {
+	RentID             string          `json:"rent_id"`
+	Status             Status          `json:"status"`
+	CustomerID         string          `json:"customer_id"`
+	PaymentConditionID string          `json:"payment_condition_id"`
+	CarrierID          string          `json:"carrier_id"`
+	DeliveryAddress    string          `json:"delivery_address"`
+	StartDate          time.Time       `json:"start_date"`
+	EndDate            time.Time       `json:"end_date"`
+	Total              Money           `json:"total"`
+	Items              []RentEventItem `json:"items"`
+}
+
+type RentEventItem struct {
+	Description string `json:"description"`
+	Total       Money  `json:"total"`
+}
+
+func NewRentEvent(rent *Rent) RentEvent {
+	event := RentEvent{
+		RentID:             rent.ID,
+		Status:             rent.GetStatus(),
+		CustomerID:         rent.CustomerID,
+		PaymentConditionID: rent.PaymentConditionID,
+		CarrierID:          rent.CarrierID,
+		DeliveryAddress:    rent.DeliveryAddress,
+		StartDate:          rent.StartDate,
+		EndDate:            rent.EndDate,
+		Items:              make([]RentEventItem, 0),
+	}
+
+	// rents billed by cycle have their renting charged on each billing
+	if !rent.IsBilledByCycle() {
+		event.addRentingItems(rent)
+	}
+
+	for _, item := range rent.Items {
+		for _, ret := range item.Returns {
+			event.addItem(
+				fmt.Sprintf("%d x %s %s", ret.Qty, item.Equipment.Description, ret.Condition),
+				ret.Charge,
+			)
+		}
+	}
+
+	if deposit := rent.Deposit; deposit != nil {
+		description := "Security deposit"
+		if !deposit.IsHeld() {
+			description = "Retained deposit"
+		}
+		event.addItem(description, rent.GetDepositCharged())
+	}
+
+	return event
+}
+
+func (e *RentEvent) addRentingItems(rent *Rent) {
+	for _, item := range rent.Items {
+		e.addItem(
+			fmt.Sprintf("%d x %s", item.Qty, item.Equipment.Description),
+			rent.GetItemSubtotal(item),
+		)
+	}
+
+	e.addItem("Delivery", rent.DeliveryValue)
+
+	for _, extension := range rent.Extensions {
+		e.addItem(
+			fmt.Sprintf("Extension until %s", extension.EndDate.Format("2006-01-02")),
+			extension.Value,
+		)
+	}
+
+	e.addItem("Late fees", rent.GetLateFees(time.Now()))
+	e.Total -= rent.Discount
+}
+
+func (e *RentEvent) addItem(description string, total Money) {
+	if total <= 0 {
+		return
+	}
+
+	e.Total += total
+	e.Items = append(e.Items, RentEventItem{description, total})
+}
@@ -0,0 +1,380 @@
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+type fakeValidator struct{}
+
+func (v *fakeValidator) Validate(any) error {
+	return nil
+}
+
+type fakeRepository struct {
+	rents     map[string]*pkg.Rent
+	estimates map[string]*pkg.Estimate
+	sagas     map[string]*pkg.Saga
+	changes   []*pkg.RentChange
+	billings  []*pkg.Billing
+	events    []string
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		rents:     make(map[string]*pkg.Rent),
+		estimates: make(map[string]*pkg.Estimate),
+		sagas:     make(map[string]*pkg.Saga),
+	}
+}
+
+func (r *fakeRepository) GetRent(id string) (*pkg.Rent, error) {
+	if rent, ok := r.rents[id]; ok {
+		copied := *rent
+		return &copied, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (r *fakeRepository) CreateRent(ctx context.Context, data pkg.Rent, events ...string) (*pkg.Rent, error) {
+	r.events = append(r.events, events...)
+	data.ID = "rent"
+	r.rents[data.ID] = &data
+	r.recordChange(ctx, "create", nil, &data)
+	return &data, nil
+}
+
+// ListRents only filters rents by customer and equipment.
+func (r *fakeRepository) ListRents(filter pkg.RentFilter, page, perPage int64) ([]*pkg.Rent, int64, error) {
+	rents := make([]*pkg.Rent, 0)
+	for _, rent := range r.rents {
+		if filter.CustomerID != "" && rent.CustomerID != filter.CustomerID {
+			continue
+		}
+
+		if filter.EquipmentID == "" || hasEquipment(rent, filter.EquipmentID) {
+			rents = append(rents, rent)
+		}
+	}
+	return rents, int64(len(rents)), nil
+}
+
+func hasEquipment(rent *pkg.Rent, equipmentID string) bool {
+	for _, item := range rent.Items {
+		if item.EquipmentID == equipmentID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *fakeRepository) UpdateRent(ctx context.Context, id string, data pkg.Rent, events ...string) (*pkg.Rent, error) {
+	curr, ok := r.rents[id]
+	if ok && curr.Version != data.Version {
+		return nil, pkg.ErrVersionConflict
+	}
+
+	data.Version++
+	r.events = append(r.events, events...)
+	r.rents[id] = &data
+	r.recordChange(ctx, "update", curr, &data)
+	return &data, nil
+}
+
+func (r *fakeRepository) DeleteRent(ctx context.Context, id string, events ...string) error {
+	r.events = append(r.events, events...)
+	if curr, ok := r.rents[id]; ok {
+		r.recordChange(ctx, "delete", curr, nil)
+	}
+	delete(r.rents, id)
+	return nil
+}
+
+// recordChange saves the change to the history as the mongo repository does,
+// naming it after the action in ctx if there is one.
+func (r *fakeRepository) recordChange(ctx context.Context, action string, prev, next *pkg.Rent) {
+	if named := pkg.ActionFromContext(ctx); named != "" {
+		action = named
+	}
+	r.changes = append(r.changes, pkg.NewRentChange(action, pkg.UserFromContext(ctx), prev, next))
+}
+
+func (r *fakeRepository) CreateEstimate(data pkg.Estimate) (*pkg.Estimate, error) {
+	data.ID = "estimate"
+	r.estimates[data.ID] = &data
+	return &data, nil
+}
+
+func (r *fakeRepository) GetEstimate(id string) (*pkg.Estimate, error) {
+	if estimate, ok := r.estimates[id]; ok {
+		return estimate, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (r *fakeRepository) UpdateEstimate(id string, data pkg.Estimate) (*pkg.Estimate, error) {
+	r.estimates[id] = &data
+	return &data, nil
+}
+
+func (r *fakeRepository) CreateSaga(data pkg.Saga) (*pkg.Saga, error) {
+	data.ID = fmt.Sprintf("saga%d", len(r.sagas))
+	r.sagas[data.ID] = &data
+	return &data, nil
+}
+
+func (r *fakeRepository) UpdateSaga(id string, data pkg.Saga) error {
+	r.sagas[id] = &data
+	return nil
+}
+
+func (r *fakeRepository) ListStuckSagas(until time.Time) ([]*pkg.Saga, error) {
+	sagas := make([]*pkg.Saga, 0)
+	for _, saga := range r.sagas {
+		if saga.IsStuck(until) {
+			sagas = append(sagas, saga)
+		}
+	}
+	return sagas, nil
+}
+
+func (r *fakeRepository) ListRentChanges(rentID string) ([]*pkg.RentChange, error) {
+	changes := make([]*pkg.RentChange, 0)
+	for _, change := range r.changes {
+		if change.RentID == rentID {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (r *fakeRepository) ListDueBillings(now time.Time) ([]*pkg.Rent, error) {
+	rents := make([]*pkg.Rent, 0)
+	for _, rent := range r.rents {
+		status := rent.GetStatus()
+		if rent.IsBilledByCycle() && !rent.GetNextBilling().After(now) && (status == pkg.StatusActive || status == pkg.StatusPartiallyReturned) {
+			copied := *rent
+			rents = append(rents, &copied)
+		}
+	}
+	return rents, nil
+}
+
+func (r *fakeRepository) CreateBilling(billing pkg.Billing, rent pkg.Rent) error {
+	curr, ok := r.rents[rent.ID]
+	if !ok || curr.Version != rent.Version {
+		return pkg.ErrVersionConflict
+	}
+
+	curr.BilledCycles++
+	curr.Version++
+	r.billings = append(r.billings, &billing)
+	r.events = append(r.events, pkg.EventRentBilled)
+	return nil
+}
+
+// fakeInventory keeps the stock taken by each equipment, failing to reduce
+// the stock of the unavailable one.
+type fakeInventory struct {
+	equipment   *pkg.Equipment
+	unavailable string
+	stock       map[string]int
+	writtenOff  []*pkg.Item
+}
+
+func (i *fakeInventory) ReduceStock(rent *pkg.Rent, items []*pkg.Item) error {
+	for _, item := range items {
+		if item.EquipmentID == i.unavailable {
+			return errors.New("not enough stock")
+		}
+		i.stock[item.EquipmentID] += item.Qty
+	}
+	return nil
+}
+
+func (i *fakeInventory) RestoreStock(rent *pkg.Rent, items []*pkg.Item) error {
+	for _, item := range items {
+		i.stock[item.EquipmentID] -= item.Qty
+	}
+	return nil
+}
+
+func (i *fakeInventory) WriteOffStock(rent *pkg.Rent, items []*pkg.Item) error {
+	i.writtenOff = append(i.writtenOff, items...)
+	return nil
+}
+
+func (i *fakeInventory) ExtendBooking(rent *pkg.Rent) error {
+	return nil
+}
+
+func (i *fakeInventory) GetEquipment(id string) (*pkg.Equipment, error) {
+	return i.equipment, nil
+}
+
+// fakeCredit gives the customer a credit limit, leaving it without one when
+// zero.
+type fakeCredit struct {
+	limit    pkg.Money
+	invoices []*pkg.UnpaidInvoice
+}
+
+func (c *fakeCredit) GetCreditLimit(customerID string) (pkg.Money, error) {
+	return c.limit, nil
+}
+
+func (c *fakeCredit) ListUnpaidInvoices(customerID string) ([]*pkg.UnpaidInvoice, error) {
+	return c.invoices, nil
+}
+
+// testStart is when the rents put together by the tests start.
+var testStart = time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestEquipment is rented weekly for 70.
+func newTestEquipment() *pkg.Equipment {
+	return &pkg.Equipment{
+		ID:          "equipment",
+		Description: "Andaime",
+		RentingValues: []*pkg.RentingValue{
+			{PeriodID: "weekly", Value: pkg.NewMoney(70), Period: &pkg.Period{QtyDays: 7}},
+		},
+	}
+}
+
+// newTestRentData rents qty pieces of the equipment for a week from
+// testStart.
+func newTestRentData(equipment *pkg.Equipment, qty int) pkg.Rent {
+	return pkg.Rent{
+		PeriodID:  "weekly",
+		StartDate: testStart,
+		EndDate:   testStart.AddDate(0, 0, 7),
+		Items: []*pkg.Item{
+			{EquipmentID: equipment.ID, Equipment: equipment, Qty: qty},
+		},
+	}
+}
+
+// testSetup is what newTestService and newTestRent are made of. Options
+// change it before the service is created, and the fakes left nil are
+// created then.
+type testSetup struct {
+	equipment        *pkg.Equipment
+	repository       *fakeRepository
+	inventory        *fakeInventory
+	credit           *fakeCredit
+	estimateValidity time.Duration
+	qty              int
+	changes          []func(*pkg.Rent)
+	statuses         []pkg.Status
+}
+
+type testOption func(*testSetup)
+
+// withEquipment changes the equipment rented.
+func withEquipment(change func(*pkg.Equipment)) testOption {
+	return func(s *testSetup) { change(s.equipment) }
+}
+
+// withRepository keeps the rents in repository, so that tests can look at it.
+func withRepository(repository *fakeRepository) testOption {
+	return func(s *testSetup) { s.repository = repository }
+}
+
+// withInventory takes the stock from inventory, which is given the equipment
+// rented when it has none.
+func withInventory(inventory *fakeInventory) testOption {
+	return func(s *testSetup) { s.inventory = inventory }
+}
+
+func withCredit(credit *fakeCredit) testOption {
+	return func(s *testSetup) { s.credit = credit }
+}
+
+func withEstimateValidity(validity time.Duration) testOption {
+	return func(s *testSetup) { s.estimateValidity = validity }
+}
+
+// withQty rents qty pieces instead of 2.
+func withQty(qty int) testOption {
+	return func(s *testSetup) { s.qty = qty }
+}
+
+// withRent changes the rent before it is created.
+func withRent(change func(*pkg.Rent)) testOption {
+	return func(s *testSetup) { s.changes = append(s.changes, change) }
+}
+
+// withStatuses moves the rent through statuses once created.
+func withStatuses(statuses ...pkg.Status) testOption {
+	return func(s *testSetup) { s.statuses = append(s.statuses, statuses...) }
+}
+
+func newTestSetup(opts ...testOption) *testSetup {
+	s := &testSetup{
+		equipment:        newTestEquipment(),
+		credit:           &fakeCredit{},
+		estimateValidity: time.Hour,
+		qty:              2,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.repository == nil {
+		s.repository = newFakeRepository()
+	}
+
+	if s.inventory == nil {
+		s.inventory = &fakeInventory{}
+	}
+	if s.inventory.equipment == nil {
+		s.inventory.equipment = s.equipment
+	}
+	if s.inventory.stock == nil {
+		s.inventory.stock = make(map[string]int)
+	}
+
+	return s
+}
+
+func (s *testSetup) service() pkg.Service {
+	return pkg.NewService(&fakeValidator{}, s.repository, nil, s.inventory, s.credit, 1, s.estimateValidity)
+}
+
+// newTestService creates the service over the fakes of the options.
+func newTestService(opts ...testOption) pkg.Service {
+	return newTestSetup(opts...).service()
+}
+
+// newTestRent creates a weekly rent of 2 pieces of the test equipment, moved
+// through the statuses of the options.
+func newTestRent(t *testing.T, opts ...testOption) (pkg.Service, *pkg.Rent) {
+	t.Helper()
+
+	s := newTestSetup(opts...)
+	svc := s.service()
+
+	data := newTestRentData(s.equipment, s.qty)
+	for _, change := range s.changes {
+		change(&data)
+	}
+
+	rent, err := svc.CreateRent(context.Background(), data)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	for _, status := range s.statuses {
+		if rent, err = svc.TransitionRent(context.Background(), rent.ID, status); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+	}
+
+	return svc, rent
+}
@@ -0,0 +1,110 @@
+package pkg_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"reconcip.com.br/microservices/renting/pkg"
+	"reconcip.com.br/microservices/renting/proto"
+)
+
+func TestGRPCServer(t *testing.T) {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	equipment := &pkg.Equipment{
+		ID:          "equipment",
+		Description: "Andaime",
+		RentingValues: []*pkg.RentingValue{
+			{PeriodID: "weekly", Value: pkg.NewMoney(70), Period: &pkg.Period{QtyDays: 7}},
+		},
+	}
+
+	rent := &pkg.Rent{
+		ID:         "rent",
+		PeriodID:   "weekly",
+		Status:     pkg.StatusActive,
+		CustomerID: "customer",
+		StartDate:  start,
+		EndDate:    start.AddDate(0, 0, 7),
+		Items: []*pkg.Item{
+			{ID: "item", EquipmentID: "equipment", Equipment: equipment, Qty: 3, Returns: []*pkg.Return{{Qty: 1}}},
+		},
+	}
+
+	var filter pkg.RentFilter
+	var pagination pkg.Pagination
+
+	server := pkg.NewGRPCServer(pkg.Set{
+		Get: func(ctx context.Context, r any) (any, error) {
+			if r.(string) != rent.ID {
+				return nil, pkg.NewError(http.StatusNotFound, "rent not found", "could not find rent")
+			}
+			return rent, nil
+		},
+		List: func(ctx context.Context, r any) (any, error) {
+			req := r.(pkg.ListRequest)
+			filter, pagination = req.Filter, req.Pagination
+			return pkg.ListResult{Items: []any{rent}, TotalPages: 1, TotalItems: 1}, nil
+		},
+	})
+
+	t.Run("gets a rent", func(t *testing.T) {
+		reply, err := server.GetRent(context.Background(), &proto.GetRequest{Id: "rent"})
+		if err != nil || reply.GetErr() != nil {
+			t.Fatalf("did not expect error: %v %v", err, reply.GetErr())
+		}
+
+		item := reply.GetRent().GetItems()[0]
+		if reply.GetRent().GetStatus() != string(pkg.StatusActive) || item.GetReturnedQty() != 1 {
+			t.Errorf("expected active rent with 1 piece returned, got %v", reply.GetRent())
+		}
+
+		if reply.GetRent().GetTotal() != 210 {
+			t.Errorf("expected total of 210, got %v", reply.GetRent().GetTotal())
+		}
+	})
+
+	t.Run("replies errors", func(t *testing.T) {
+		reply, err := server.GetRent(context.Background(), &proto.GetRequest{Id: "other"})
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if reply.GetErr().GetStatus() != http.StatusNotFound {
+			t.Errorf("expected not found, got %v", reply.GetErr())
+		}
+	})
+
+	t.Run("lists rents of the equipment in the period", func(t *testing.T) {
+		reply, err := server.ListRentsByEquipment(context.Background(), &proto.RentsByEquipmentRequest{
+			EquipmentId: "equipment",
+			From:        timestamppb.New(start),
+			To:          timestamppb.New(start.AddDate(0, 1, 0)),
+			Page:        2,
+		})
+
+		if err != nil || len(reply.GetRents()) != 1 {
+			t.Fatalf("expected a rent, got %v %v", reply, err)
+		}
+
+		if filter.EquipmentID != "equipment" || !filter.From.Equal(start) {
+			t.Errorf("expected rents of the equipment from %v, got %+v", start, filter)
+		}
+
+		if pagination.Page != 1 || pagination.PerPage != 50 {
+			t.Errorf("expected second page of 50, got %+v", pagination)
+		}
+	})
+
+	t.Run("lists active rents", func(t *testing.T) {
+		if _, err := server.ListActiveRents(context.Background(), &proto.ActiveRentsRequest{}); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if !filter.Active || pagination.Page != 0 {
+			t.Errorf("expected first page of active rents, got %+v %+v", filter, pagination)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package pkg
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type User struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Roles []string `json:"roles,omitempty" bson:"roles,omitempty"`
+}
+
+// HasRole tells whether the user was given the role by the auth service.
+func (u *User) HasRole(role string) bool {
+	if u == nil {
+		return false
+	}
+
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	actionContextKey
+)
+
+// ActionCompensate is recorded for the changes undoing a failed saga.
+const ActionCompensate = "compensate"
+
+func ContextWithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the user authenticated by the verify middleware, or
+// nil when the request did not go through it.
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userContextKey).(*User)
+	return user
+}
+
+// ContextWithAction names the action recorded in the history for the rent
+// changes made with ctx.
+func ContextWithAction(ctx context.Context, action string) context.Context {
+	return context.WithValue(ctx, actionContextKey, action)
+}
+
+// ActionFromContext returns the action named for the request, if any.
+func ActionFromContext(ctx context.Context) string {
+	action, _ := ctx.Value(actionContextKey).(string)
+	return action
+}
+
+// RentChange is a version of a rent, recording who changed it and what was
+// changed from the version before.
+type RentChange struct {
+	ID        string         `json:"id" bson:"_id,omitempty"`
+	RentID    string         `json:"rent_id" bson:"rent_id"`
+	Version   int            `json:"version"`
+	Action    string         `json:"action"`
+	User      *User          `json:"user"`
+	Changes   []*FieldChange `json:"changes"`
+	Rent      *Rent          `json:"rent"`
+	CreatedAt time.Time      `json:"created_at" bson:"created_at"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// NewRentChange records the action done by user, which took the rent from
+// prev to next. Creations have no prev and deletions have no next. The change
+// takes the version of the rent it leaves, deletions being one past the last.
+func NewRentChange(action string, user *User, prev, next *Rent) *RentChange {
+	change := &RentChange{
+		Action:    action,
+		User:      user,
+		Changes:   make([]*FieldChange, 0),
+		CreatedAt: time.Now(),
+	}
+
+	if next == nil {
+		change.RentID = prev.ID
+		change.Version = prev.Version + 1
+		change.Rent = prev
+		return change
+	}
+
+	if prev == nil {
+		prev = &Rent{}
+	}
+
+	change.RentID = next.ID
+	change.Version = next.Version
+	change.Rent = next
+	change.Changes = DiffRents(prev, next)
+
+	return change
+}
+
+var rentFields = []struct {
+	name  string
+	value func(*Rent) any
+}{
+	{"status", func(r *Rent) any { return r.GetStatus() }},
+	{"customer_id", func(r *Rent) any { return r.CustomerID }},
+	{"period_id", func(r *Rent) any { return r.PeriodID }},
+	{"payment_method_id", func(r *Rent) any { return r.PaymentMethodID }},
+	{"payment_type_id", func(r *Rent) any { return r.PaymentTypeID }},
+	{"payment_condition_id", func(r *Rent) any { return r.PaymentConditionID }},
+	{"carrier_id", func(r *Rent) any { return r.CarrierID }},
+	{"delivery_address", func(r *Rent) any { return r.DeliveryAddress }},
+	{"usage_address", func(r *Rent) any { return r.UsageAddress }},
+	{"start_date", func(r *Rent) any { return dateValue(r.StartDate) }},
+	{"end_date", func(r *Rent) any { return dateValue(r.EndDate) }},
+	{"discount", func(r *Rent) any { return r.Discount }},
+	{"paid_value", func(r *Rent) any { return r.PaidValue }},
+	{"bill", func(r *Rent) any { return r.Bill }},
+	{"delivery_value", func(r *Rent) any { return r.DeliveryValue }},
+	{"total", func(r *Rent) any { return r.GetTotal() }},
+	{"damages_total", func(r *Rent) any { return r.GetDamagesTotal() }},
+	{"deposit.amount", func(r *Rent) any { return depositValue(r, func(d *Deposit) any { return d.Amount }) }},
+	{"deposit.status", func(r *Rent) any { return depositValue(r, func(d *Deposit) any { return d.Status }) }},
+	{"deposit.deducted", func(r *Rent) any { return depositValue(r, func(d *Deposit) any { return d.GetDeducted() }) }},
+	{"credit_override.authorized_by", func(r *Rent) any { return overrideValue(r) }},
+}
+
+func depositValue(r *Rent, value func(*Deposit) any) any {
+	if r.Deposit == nil {
+		return nil
+	}
+	return value(r.Deposit)
+}
+
+func overrideValue(r *Rent) any {
+	if r.CreditOverride == nil || r.CreditOverride.AuthorizedBy == nil {
+		return nil
+	}
+	return r.CreditOverride.AuthorizedBy.ID
+}
+
+// dateValue drops what mongo does not store, so that a date read back from
+// the database is equal to the one saved.
+func dateValue(date time.Time) time.Time {
+	return date.UTC().Truncate(time.Millisecond)
+}
+
+// DiffRents lists the fields that differ between two versions of a rent.
+// Items are compared by equipment.
+func DiffRents(prev, next *Rent) []*FieldChange {
+	changes := make([]*FieldChange, 0)
+
+	for _, field := range rentFields {
+		from, to := field.value(prev), field.value(next)
+		if from != to {
+			changes = append(changes, &FieldChange{field.name, from, to})
+		}
+	}
+
+	for _, item := range next.Items {
+		field := "items." + item.EquipmentID
+
+		old := findItem(prev.Items, item.EquipmentID)
+		if old == nil {
+			changes = append(changes, &FieldChange{field + ".qty", nil, item.Qty})
+			continue
+		}
+
+		if old.Qty != item.Qty {
+			changes = append(changes, &FieldChange{field + ".qty", old.Qty, item.Qty})
+		}
+
+		if old.GetReturnedQty() != item.GetReturnedQty() {
+			changes = append(changes, &FieldChange{field + ".returned_qty", old.GetReturnedQty(), item.GetReturnedQty()})
+		}
+	}
+
+	for _, item := range prev.Items {
+		if findItem(next.Items, item.EquipmentID) == nil {
+			changes = append(changes, &FieldChange{"items." + item.EquipmentID + ".qty", item.Qty, nil})
+		}
+	}
+
+	return changes
+}
+
+func findItem(items []*Item, equipmentID string) *Item {
+	for _, item := range items {
+		if item.EquipmentID == equipmentID {
+			return item
+		}
+	}
+	return nil
+}
+
+// WithHistoryEndpoints names the action the history records for the rents
+// changed by each endpoint. Changes are recorded by the repository, in the
+// same transaction as the rent.
+func WithHistoryEndpoints(endpoints Set) Set {
+	return Set{
+		Create:     recordAs("create")(endpoints.Create),
+		List:       endpoints.List,
+		Update:     recordAs("update")(endpoints.Update),
+		Delete:     recordAs("delete")(endpoints.Delete),
+		Get:        endpoints.Get,
+		Transition: recordAs("transition")(endpoints.Transition),
+		Return:     recordAs("return")(endpoints.Return),
+		Extend:     recordAs("extend")(endpoints.Extend),
+		Deduct:     recordAs("deduct_deposit")(endpoints.Deduct),
+		Refund:     recordAs("refund_deposit")(endpoints.Refund),
+		History:    endpoints.History,
+
+		CreateEstimate:  endpoints.CreateEstimate,
+		GetEstimate:     endpoints.GetEstimate,
+		ConvertEstimate: recordAs("convert_estimate")(endpoints.ConvertEstimate),
+
+		StuckSagas:    endpoints.StuckSagas,
+		Occupancy:     endpoints.Occupancy,
+		OccupancyFeed: endpoints.OccupancyFeed,
+		Exposure:      endpoints.Exposure,
+	}
+}
+
+func recordAs(action string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, r any) (any, error) {
+			return next(ContextWithAction(ctx, action), r)
+		}
+	}
+}
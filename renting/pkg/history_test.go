@@ -0,0 +1,125 @@
+package pkg_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestHistory(t *testing.T) {
+	equipment := newTestEquipment()
+
+	newRent := func() pkg.Rent {
+		return newTestRentData(equipment, 2)
+	}
+
+	findChange := func(changes []*pkg.FieldChange, field string) *pkg.FieldChange {
+		for _, change := range changes {
+			if change.Field == field {
+				return change
+			}
+		}
+		return nil
+	}
+
+	t.Run("diff", func(t *testing.T) {
+		prev := newRent()
+		next := newRent()
+		next.EndDate = testStart.AddDate(0, 0, 14)
+		next.Discount = pkg.NewMoney(10)
+		next.Items = []*pkg.Item{
+			{EquipmentID: "equipment", Equipment: equipment, Qty: 3},
+			{EquipmentID: "other", Equipment: equipment, Qty: 1},
+		}
+
+		changes := pkg.DiffRents(&prev, &next)
+
+		endDate := findChange(changes, "end_date")
+		if endDate == nil || !endDate.To.(time.Time).Equal(next.EndDate) {
+			t.Errorf("expected end date change, got %v", endDate)
+		}
+
+		discount := findChange(changes, "discount")
+		if discount == nil || discount.From != pkg.Money(0) || discount.To != pkg.NewMoney(10) {
+			t.Errorf("expected discount change, got %v", discount)
+		}
+
+		qty := findChange(changes, "items.equipment.qty")
+		if qty == nil || qty.From != 2 || qty.To != 3 {
+			t.Errorf("expected qty change, got %v", qty)
+		}
+
+		added := findChange(changes, "items.other.qty")
+		if added == nil || added.From != nil || added.To != 1 {
+			t.Errorf("expected added item, got %v", added)
+		}
+
+		if findChange(changes, "start_date") != nil {
+			t.Error("did not expect start date change")
+		}
+	})
+
+	t.Run("records versions with user", func(t *testing.T) {
+		repository := newFakeRepository()
+		svc := newTestService(withRepository(repository))
+
+		endpoints := pkg.WithHistoryEndpoints(pkg.CreateEndpoints(svc))
+		ctx := pkg.ContextWithUser(context.Background(), &pkg.User{ID: "user", Name: "John"})
+
+		res, err := endpoints.Create(ctx, newRent())
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		rent := res.(*pkg.Rent)
+		data := *rent
+		data.Discount = pkg.NewMoney(5)
+
+		res, err = endpoints.Update(ctx, pkg.UpdateRequest{ID: rent.ID, Data: data})
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		updated := res.(*pkg.Rent)
+
+		res, err = endpoints.History(ctx, rent.ID)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		changes := res.([]*pkg.RentChange)
+		if len(changes) != 2 {
+			t.Fatalf("expected 2 versions, got %d", len(changes))
+		}
+
+		update := changes[1]
+		if changes[0].Version != rent.Version || update.Version != updated.Version || update.Action != "update" {
+			t.Errorf("expected versions %d and %d of update, got %d and %d of %s", rent.Version, updated.Version, changes[0].Version, update.Version, update.Action)
+		}
+
+		if update.User == nil || update.User.ID != "user" {
+			t.Errorf("expected change by user, got %v", update.User)
+		}
+
+		if findChange(update.Changes, "discount") == nil {
+			t.Errorf("expected discount change, got %v", update.Changes)
+		}
+	})
+
+	t.Run("does not record failures", func(t *testing.T) {
+		repository := newFakeRepository()
+		svc := newTestService(withRepository(repository))
+
+		endpoints := pkg.WithHistoryEndpoints(pkg.CreateEndpoints(svc))
+
+		if _, err := endpoints.Transition(context.Background(), pkg.TransitionRequest{ID: "missing", Status: pkg.StatusActive}); err == nil {
+			t.Fatal("expected error, got nothing")
+		}
+
+		if len(repository.changes) != 0 {
+			t.Errorf("expected no changes, got %d", len(repository.changes))
+		}
+	})
+}
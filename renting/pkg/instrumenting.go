@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -17,40 +18,40 @@ func NewInstrumentingService(next Service, counter metrics.Counter, duration met
 	return &instrumentingService{next, duration, counter}
 }
 
-func (s *instrumentingService) CreateRent(data Rent) (_ *Rent, err error) {
+func (s *instrumentingService) CreateRent(ctx context.Context, data Rent) (_ *Rent, err error) {
 	defer func(begin time.Time) {
 		s.reqCounter.With("method", "CreateRent", "error", fmt.Sprint(err != nil)).Add(1)
 		s.reqDuration.With("method", "CreateRent").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return s.next.CreateRent(data)
+	return s.next.CreateRent(ctx, data)
 }
 
-func (s *instrumentingService) ListRents(page, perPage int64) (_ []*Rent, total int64, err error) {
+func (s *instrumentingService) ListRents(filter RentFilter, page, perPage int64) (_ []*Rent, total int64, err error) {
 	defer func(begin time.Time) {
 		s.reqCounter.With("method", "ListRents", "error", fmt.Sprint(err != nil)).Add(1)
 		s.reqDuration.With("method", "ListRents").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return s.next.ListRents(page, perPage)
+	return s.next.ListRents(filter, page, perPage)
 }
 
-func (s *instrumentingService) UpdateRent(id string, data Rent) (_ *Rent, err error) {
+func (s *instrumentingService) UpdateRent(ctx context.Context, id string, data Rent) (_ *Rent, err error) {
 	defer func(begin time.Time) {
 		s.reqCounter.With("method", "UpdateRent", "error", fmt.Sprint(err != nil)).Add(1)
 		s.reqDuration.With("method", "UpdateRent").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return s.next.UpdateRent(id, data)
+	return s.next.UpdateRent(ctx, id, data)
 }
 
-func (s *instrumentingService) DeleteRent(id string) (err error) {
+func (s *instrumentingService) DeleteRent(ctx context.Context, id string) (err error) {
 	defer func(begin time.Time) {
 		s.reqCounter.With("method", "DeleteRent", "error", fmt.Sprint(err != nil)).Add(1)
 		s.reqDuration.With("method", "DeleteRent").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return s.next.DeleteRent(id)
+	return s.next.DeleteRent(ctx, id)
 }
 
 func (s *instrumentingService) GetRent(id string) (_ *Rent, err error) {
@@ -61,3 +62,111 @@ func (s *instrumentingService) GetRent(id string) (_ *Rent, err error) {
 
 	return s.next.GetRent(id)
 }
+
+func (s *instrumentingService) TransitionRent(ctx context.Context, id string, status Status) (_ *Rent, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "TransitionRent", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "TransitionRent").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.TransitionRent(ctx, id, status)
+}
+
+func (s *instrumentingService) ReturnItems(ctx context.Context, id string, returns []ItemReturn) (_ *Rent, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "ReturnItems", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "ReturnItems").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.ReturnItems(ctx, id, returns)
+}
+
+func (s *instrumentingService) ExtendRent(ctx context.Context, id string, endDate time.Time) (_ *Rent, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "ExtendRent", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "ExtendRent").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.ExtendRent(ctx, id, endDate)
+}
+
+func (s *instrumentingService) DeductDeposit(ctx context.Context, id string, deduction Deduction) (_ *Rent, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "DeductDeposit", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "DeductDeposit").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.DeductDeposit(ctx, id, deduction)
+}
+
+func (s *instrumentingService) RefundDeposit(ctx context.Context, id string, value Money) (_ *Rent, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "RefundDeposit", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "RefundDeposit").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.RefundDeposit(ctx, id, value)
+}
+
+func (s *instrumentingService) CreateEstimate(data Rent) (_ *Estimate, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "CreateEstimate", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "CreateEstimate").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.CreateEstimate(data)
+}
+
+func (s *instrumentingService) GetEstimate(id string) (_ *Estimate, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "GetEstimate", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "GetEstimate").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.GetEstimate(id)
+}
+
+func (s *instrumentingService) ConvertEstimate(ctx context.Context, id string, override *CreditOverride) (_ *Rent, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "ConvertEstimate", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "ConvertEstimate").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.ConvertEstimate(ctx, id, override)
+}
+
+func (s *instrumentingService) GetRentHistory(id string) (_ []*RentChange, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "GetRentHistory", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "GetRentHistory").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.GetRentHistory(id)
+}
+
+func (s *instrumentingService) GetOccupancy(equipmentID string, from, to time.Time) (_ *Occupancy, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "GetOccupancy", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "GetOccupancy").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.GetOccupancy(equipmentID, from, to)
+}
+
+func (s *instrumentingService) GetExposure(customerID string) (_ *Exposure, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "GetExposure", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "GetExposure").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.GetExposure(customerID)
+}
+
+func (s *instrumentingService) ListStuckSagas(olderThan time.Duration) (_ []*Saga, err error) {
+	defer func(begin time.Time) {
+		s.reqCounter.With("method", "ListStuckSagas", "error", fmt.Sprint(err != nil)).Add(1)
+		s.reqDuration.With("method", "ListStuckSagas").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.next.ListStuckSagas(olderThan)
+}
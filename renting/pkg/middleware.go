@@ -1,13 +1,10 @@
 package pkg
 
 import (
-	"encoding/json"
 	"time"
 
 	"github.com/go-kit/kit/endpoint"
-	amqptransport "github.com/go-kit/kit/transport/amqp"
 	"github.com/go-kit/log"
-	"github.com/streadway/amqp"
 	"golang.org/x/net/context"
 )
 
@@ -20,7 +17,7 @@ func NewLoggingService(next Service, logger log.Logger) Service {
 	return &loggingService{next, logger}
 }
 
-func (l *loggingService) CreateRent(data Rent) (rent *Rent, err error) {
+func (l *loggingService) CreateRent(ctx context.Context, data Rent) (rent *Rent, err error) {
 	defer func() {
 		l.logger.Log(
 			"method", "CreateRent",
@@ -29,13 +26,14 @@ func (l *loggingService) CreateRent(data Rent) (rent *Rent, err error) {
 			"err", err,
 		)
 	}()
-	return l.next.CreateRent(data)
+	return l.next.CreateRent(ctx, data)
 }
 
-func (l *loggingService) ListRents(page, perPage int64) (rents []*Rent, total int64, err error) {
+func (l *loggingService) ListRents(filter RentFilter, page, perPage int64) (rents []*Rent, total int64, err error) {
 	defer func() {
 		l.logger.Log(
 			"method", "ListRents",
+			"filter", filter,
 			"page", page,
 			"perPage", perPage,
 			"rents", rents,
@@ -43,10 +41,10 @@ func (l *loggingService) ListRents(page, perPage int64) (rents []*Rent, total in
 			"err", err,
 		)
 	}()
-	return l.next.ListRents(page, perPage)
+	return l.next.ListRents(filter, page, perPage)
 }
 
-func (l *loggingService) UpdateRent(id string, data Rent) (rent *Rent, err error) {
+func (l *loggingService) UpdateRent(ctx context.Context, id string, data Rent) (rent *Rent, err error) {
 	defer func() {
 		l.logger.Log(
 			"method", "UpdateRent",
@@ -56,10 +54,10 @@ func (l *loggingService) UpdateRent(id string, data Rent) (rent *Rent, err error
 			"err", err,
 		)
 	}()
-	return l.next.UpdateRent(id, data)
+	return l.next.UpdateRent(ctx, id, data)
 }
 
-func (l *loggingService) DeleteRent(id string) (err error) {
+func (l *loggingService) DeleteRent(ctx context.Context, id string) (err error) {
 	defer func() {
 		l.logger.Log(
 			"method", "DeleteRent",
@@ -67,7 +65,7 @@ func (l *loggingService) DeleteRent(id string) (err error) {
 			"err", err,
 		)
 	}()
-	return l.next.DeleteRent(id)
+	return l.next.DeleteRent(ctx, id)
 }
 
 func (l *loggingService) GetRent(id string) (rent *Rent, err error) {
@@ -82,71 +80,220 @@ func (l *loggingService) GetRent(id string) (rent *Rent, err error) {
 	return l.next.GetRent(id)
 }
 
+func (l *loggingService) TransitionRent(ctx context.Context, id string, status Status) (rent *Rent, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "TransitionRent",
+			"id", id,
+			"status", status,
+			"rent", rent,
+			"err", err,
+		)
+	}()
+	return l.next.TransitionRent(ctx, id, status)
+}
+
+func (l *loggingService) ReturnItems(ctx context.Context, id string, returns []ItemReturn) (rent *Rent, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "ReturnItems",
+			"id", id,
+			"returns", returns,
+			"rent", rent,
+			"err", err,
+		)
+	}()
+	return l.next.ReturnItems(ctx, id, returns)
+}
+
+func (l *loggingService) ExtendRent(ctx context.Context, id string, endDate time.Time) (rent *Rent, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "ExtendRent",
+			"id", id,
+			"endDate", endDate,
+			"rent", rent,
+			"err", err,
+		)
+	}()
+	return l.next.ExtendRent(ctx, id, endDate)
+}
+
+func (l *loggingService) DeductDeposit(ctx context.Context, id string, deduction Deduction) (rent *Rent, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "DeductDeposit",
+			"id", id,
+			"deduction", deduction,
+			"rent", rent,
+			"err", err,
+		)
+	}()
+	return l.next.DeductDeposit(ctx, id, deduction)
+}
+
+func (l *loggingService) RefundDeposit(ctx context.Context, id string, value Money) (rent *Rent, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "RefundDeposit",
+			"id", id,
+			"value", value,
+			"rent", rent,
+			"err", err,
+		)
+	}()
+	return l.next.RefundDeposit(ctx, id, value)
+}
+
+func (l *loggingService) CreateEstimate(data Rent) (estimate *Estimate, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "CreateEstimate",
+			"data", data,
+			"estimate", estimate,
+			"err", err,
+		)
+	}()
+	return l.next.CreateEstimate(data)
+}
+
+func (l *loggingService) GetEstimate(id string) (estimate *Estimate, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetEstimate",
+			"id", id,
+			"estimate", estimate,
+			"err", err,
+		)
+	}()
+	return l.next.GetEstimate(id)
+}
+
+func (l *loggingService) ConvertEstimate(ctx context.Context, id string, override *CreditOverride) (rent *Rent, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "ConvertEstimate",
+			"id", id,
+			"rent", rent,
+			"err", err,
+		)
+	}()
+	return l.next.ConvertEstimate(ctx, id, override)
+}
+
+func (l *loggingService) GetRentHistory(id string) (changes []*RentChange, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetRentHistory",
+			"id", id,
+			"changes", changes,
+			"err", err,
+		)
+	}()
+	return l.next.GetRentHistory(id)
+}
+
+func (l *loggingService) GetOccupancy(equipmentID string, from, to time.Time) (occupancy *Occupancy, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetOccupancy",
+			"equipmentID", equipmentID,
+			"from", from,
+			"to", to,
+			"err", err,
+		)
+	}()
+	return l.next.GetOccupancy(equipmentID, from, to)
+}
+
+func (l *loggingService) GetExposure(customerID string) (exposure *Exposure, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "GetExposure",
+			"customerID", customerID,
+			"exposure", exposure,
+			"err", err,
+		)
+	}()
+	return l.next.GetExposure(customerID)
+}
+
+func (l *loggingService) ListStuckSagas(olderThan time.Duration) (sagas []*Saga, err error) {
+	defer func() {
+		l.logger.Log(
+			"method", "ListStuckSagas",
+			"olderThan", olderThan,
+			"sagas", sagas,
+			"err", err,
+		)
+	}()
+	return l.next.ListStuckSagas(olderThan)
+}
+
 type inventoryService struct {
-	reduceStock  endpoint.Endpoint
-	restoreStock endpoint.Endpoint
-	processLater endpoint.Endpoint
+	reduceStock   endpoint.Endpoint
+	restoreStock  endpoint.Endpoint
+	extendBooking endpoint.Endpoint
+	getEquipment  endpoint.Endpoint
+	writeOffStock endpoint.Endpoint
 }
 
-func (s *inventoryService) ReduceStock(items []*Item) {
+func (s *inventoryService) ReduceStock(rent *Rent, items []*Item) error {
 	for _, item := range items {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 		defer cancel()
 
-		if _, err := s.reduceStock(ctx, item); err != nil {
-			s.processLater(ctx, item)
+		if _, err := s.reduceStock(ctx, NewStockRequest(rent, item)); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-func (s *inventoryService) RestoreStock(items []*Item) {
+func (s *inventoryService) RestoreStock(rent *Rent, items []*Item) error {
 	for _, item := range items {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 		defer cancel()
 
-		s.restoreStock(ctx, item)
+		if _, err := s.restoreStock(ctx, NewStockRequest(rent, item)); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func NewInventoryService(reduceStock, restoreStock, processLater endpoint.Endpoint) InventoryService {
-	return &inventoryService{reduceStock, restoreStock, processLater}
-}
+func (s *inventoryService) WriteOffStock(rent *Rent, items []*Item) error {
+	for _, item := range items {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
 
-func ProcessLaterEndpoint(conn *amqp.Connection) endpoint.Endpoint {
-	channel, err := conn.Channel()
-	if err != nil {
-		panic(err)
+		if _, err := s.writeOffStock(ctx, NewStockRequest(rent, item)); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	replyQueue, err := channel.QueueDeclare("", false, false, false, false, nil)
-	if err != nil {
-		panic(err)
-	}
+func (s *inventoryService) ExtendBooking(rent *Rent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
 
-	return amqptransport.NewPublisher(
-		channel,
-		&replyQueue,
-		encodeAMQPRequest,
-		decodeAMQPResponse,
-		amqptransport.PublisherBefore(
-			amqptransport.SetPublishKey("stock.reduce"),
-			amqptransport.SetPublishExchange("inventory"),
-			amqptransport.SetContentType("application/json"),
-		),
-		amqptransport.PublisherDeliverer(amqptransport.SendAndForgetDeliverer),
-	).Endpoint()
-}
-
-func encodeAMQPRequest(ctx context.Context, p *amqp.Publishing, r any) error {
-	body, err := json.Marshal(r)
+	_, err := s.extendBooking(ctx, rent)
+	return err
+}
+
+func (s *inventoryService) GetEquipment(id string) (*Equipment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	equipment, err := s.getEquipment(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	p.Body = body
-	return nil
+	return equipment.(*Equipment), nil
 }
 
-func decodeAMQPResponse(ctx context.Context, d *amqp.Delivery) (any, error) {
-	return nil, nil
+func NewInventoryService(reduceStock, restoreStock, extendBooking, getEquipment, writeOffStock endpoint.Endpoint) InventoryService {
+	return &inventoryService{reduceStock, restoreStock, extendBooking, getEquipment, writeOffStock}
 }
@@ -0,0 +1,84 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("adds up without float errors", func(t *testing.T) {
+		var total pkg.Money
+		for i := 0; i < 10; i++ {
+			total += pkg.NewMoney(0.1)
+		}
+
+		if total != pkg.NewMoney(1) {
+			t.Errorf("expected total 1.00, got %s", total)
+		}
+	})
+
+	t.Run("rounds to the cent", func(t *testing.T) {
+		if value := pkg.NewMoney(10).Mul(1.0 / 3); value.String() != "3.33" {
+			t.Errorf("expected value 3.33, got %s", value)
+		}
+
+		if value := pkg.NewMoney(-1.5); value.String() != "-1.50" {
+			t.Errorf("expected value -1.50, got %s", value)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(map[string]pkg.Money{"total": pkg.NewMoney(19.9)})
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if string(data) != `{"total":19.90}` {
+			t.Errorf("expected number, got %s", data)
+		}
+
+		var decoded struct {
+			Total pkg.Money `json:"total"`
+		}
+
+		if err := json.Unmarshal([]byte(`{"total":0.3}`), &decoded); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if decoded.Total != pkg.NewMoney(0.3) {
+			t.Errorf("expected total 0.30, got %s", decoded.Total)
+		}
+	})
+
+	t.Run("bson", func(t *testing.T) {
+		var decoded struct {
+			Total pkg.Money `bson:"total"`
+			Value pkg.Money `bson:"value"`
+		}
+
+		data, err := bson.Marshal(bson.M{"total": 149.99, "value": 50})
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if err := bson.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if decoded.Total != pkg.NewMoney(149.99) || decoded.Value != pkg.NewMoney(50) {
+			t.Errorf("expected 149.99 and 50.00, got %s and %s", decoded.Total, decoded.Value)
+		}
+
+		data, err = bson.Marshal(decoded)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if total := bson.Raw(data).Lookup("total"); total.Double() != 149.99 {
+			t.Errorf("expected double 149.99, got %v", total)
+		}
+	})
+}
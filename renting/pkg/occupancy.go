@@ -0,0 +1,176 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Occupancy shows when the pieces of an equipment are out, and how many are
+// left free each day of a period.
+type Occupancy struct {
+	Equipment *Equipment      `json:"equipment"`
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Rents     []*Occupation   `json:"rents"`
+	Days      []*OccupancyDay `json:"days"`
+}
+
+// Occupation is a rent holding pieces of the equipment, over all its items of
+// the equipment. Pieces returned stop occupying it the day after their return.
+type Occupation struct {
+	RentID     string    `json:"rent_id"`
+	CustomerID string    `json:"customer_id"`
+	Status     Status    `json:"status"`
+	StartDate  time.Time `json:"start_date"`
+	EndDate    time.Time `json:"end_date"`
+	Qty        int       `json:"qty"`
+	Returns    []*Return `json:"-"`
+}
+
+// OccupancyDay is how many pieces are out on a day. When more are out than
+// the stock, as when the stock was written off during a rent, none is free
+// and the excess is overbooked.
+type OccupancyDay struct {
+	Date       time.Time `json:"date"`
+	Occupied   int       `json:"occupied"`
+	Free       int       `json:"free"`
+	Overbooked int       `json:"overbooked"`
+}
+
+// occupyingStatuses are the statuses of rents holding pieces, or about to.
+var occupyingStatuses = []Status{StatusReserved, StatusActive, StatusPartiallyReturned}
+
+// NewOccupancy computes the occupancy of the equipment from the rents holding
+// it, for each day from the day of from to the day of to. Overdue rents keep
+// their pieces until now.
+func NewOccupancy(equipment *Equipment, rents []*Rent, from, to, now time.Time) *Occupancy {
+	occupancy := &Occupancy{
+		Equipment: equipment,
+		From:      from,
+		To:        to,
+		Rents:     make([]*Occupation, 0),
+		Days:      make([]*OccupancyDay, 0),
+	}
+
+	for _, rent := range rents {
+		if !rent.isOccupying() {
+			continue
+		}
+
+		end := rent.EndDate
+		if rent.IsOverdue(now) {
+			end = now
+		}
+
+		// items of the same equipment are held together, as a single
+		// occupation of the rent
+		var occupation *Occupation
+		for _, item := range rent.Items {
+			if item.EquipmentID != equipment.ID {
+				continue
+			}
+
+			if occupation == nil {
+				occupation = &Occupation{
+					RentID:     rent.ID,
+					CustomerID: rent.CustomerID,
+					Status:     rent.GetStatus(),
+					StartDate:  rent.StartDate,
+					EndDate:    end,
+				}
+				occupancy.Rents = append(occupancy.Rents, occupation)
+			}
+
+			occupation.Qty += item.Qty
+			occupation.Returns = append(occupation.Returns, item.Returns...)
+		}
+	}
+
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for day := start; !day.After(to); day = day.AddDate(0, 0, 1) {
+		occupied := 0
+		for _, occupation := range occupancy.Rents {
+			occupied += occupation.occupiedOn(day, day.AddDate(0, 0, 1))
+		}
+
+		free := equipment.Stock - occupied
+		overbooked := 0
+		if free < 0 {
+			free, overbooked = 0, -free
+		}
+
+		occupancy.Days = append(occupancy.Days, &OccupancyDay{
+			Date:       day,
+			Occupied:   occupied,
+			Free:       free,
+			Overbooked: overbooked,
+		})
+	}
+
+	return occupancy
+}
+
+func (r *Rent) isOccupying() bool {
+	for _, status := range occupyingStatuses {
+		if r.GetStatus() == status {
+			return true
+		}
+	}
+	return false
+}
+
+// occupiedOn returns how many pieces are held during the day, which starts at
+// start and ends at end.
+func (o *Occupation) occupiedOn(start, end time.Time) int {
+	if !o.StartDate.Before(end) || o.EndDate.Before(start) {
+		return 0
+	}
+
+	qty := o.Qty
+	for _, ret := range o.Returns {
+		if ret.Date.Before(start) {
+			qty -= ret.Qty
+		}
+	}
+
+	return qty
+}
+
+// WriteICS writes the occupancy as an iCalendar feed, with an all-day event
+// for each rent holding pieces of the equipment.
+func (o *Occupancy) WriteICS(w io.Writer, now time.Time) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Reconcip//Renting//PT",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:" + icsText(o.Equipment.Description),
+	}
+
+	for _, occupation := range o.Rents {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%s-%s@reconcip.com.br", occupation.RentID, o.Equipment.ID),
+			"DTSTAMP:"+now.UTC().Format("20060102T150405Z"),
+			"DTSTART;VALUE=DATE:"+occupation.StartDate.Format("20060102"),
+			// the end of all-day events is exclusive
+			"DTEND;VALUE=DATE:"+occupation.EndDate.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+icsText(fmt.Sprintf("%d x %s", occupation.Qty, o.Equipment.Description)),
+			"DESCRIPTION:"+icsText(fmt.Sprintf("Rent %s (%s)", occupation.RentID, occupation.Status)),
+			"END:VEVENT",
+		)
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	_, err := io.WriteString(w, strings.Join(lines, "\r\n")+"\r\n")
+	return err
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func icsText(text string) string {
+	return icsEscaper.Replace(text)
+}
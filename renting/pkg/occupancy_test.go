@@ -0,0 +1,188 @@
+package pkg_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/auth/jwt"
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestOccupancy(t *testing.T) {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time {
+		return start.AddDate(0, 0, d)
+	}
+
+	equipment := &pkg.Equipment{ID: "equipment", Description: "Betoneira, 400L", Stock: 10}
+
+	rents := []*pkg.Rent{
+		{
+			ID:        "reserved",
+			Status:    pkg.StatusReserved,
+			StartDate: day(2),
+			EndDate:   day(4),
+			Items:     []*pkg.Item{{EquipmentID: "equipment", Qty: 3}},
+		},
+		{
+			ID:        "partially_returned",
+			Status:    pkg.StatusPartiallyReturned,
+			StartDate: day(0),
+			EndDate:   day(5),
+			Items: []*pkg.Item{
+				{EquipmentID: "equipment", Qty: 4, Returns: []*pkg.Return{{Qty: 1, Date: day(1)}}},
+				{EquipmentID: "other", Qty: 7},
+			},
+		},
+		{
+			ID:        "cancelled",
+			Status:    pkg.StatusCancelled,
+			StartDate: day(0),
+			EndDate:   day(5),
+			Items:     []*pkg.Item{{EquipmentID: "equipment", Qty: 5}},
+		},
+	}
+
+	t.Run("counts the pieces out each day", func(t *testing.T) {
+		occupancy := pkg.NewOccupancy(equipment, rents, day(0), day(6), day(0))
+
+		if len(occupancy.Rents) != 2 || len(occupancy.Days) != 7 {
+			t.Fatalf("expected 2 rents over 7 days, got %d over %d", len(occupancy.Rents), len(occupancy.Days))
+		}
+
+		expected := []int{4, 4, 6, 6, 6, 3, 0}
+		for i, d := range occupancy.Days {
+			if d.Occupied != expected[i] || d.Free != 10-expected[i] {
+				t.Errorf("expected %d pieces out on day %d, got %d with %d free", expected[i], i, d.Occupied, d.Free)
+			}
+		}
+	})
+
+	t.Run("overdue rents hold pieces until now", func(t *testing.T) {
+		overdue := []*pkg.Rent{{
+			ID:        "overdue",
+			Status:    pkg.StatusActive,
+			StartDate: day(0),
+			EndDate:   day(2),
+			Items:     []*pkg.Item{{EquipmentID: "equipment", Qty: 2}},
+		}}
+
+		occupancy := pkg.NewOccupancy(equipment, overdue, day(0), day(6), day(4))
+		if occupancy.Days[4].Occupied != 2 || occupancy.Days[5].Occupied != 0 {
+			t.Errorf("expected pieces out until day 4, got %d and %d", occupancy.Days[4].Occupied, occupancy.Days[5].Occupied)
+		}
+	})
+
+	t.Run("counts pieces out over the stock as overbooked", func(t *testing.T) {
+		short := &pkg.Equipment{ID: "equipment", Stock: 5}
+
+		occupancy := pkg.NewOccupancy(short, rents, day(0), day(6), day(0))
+		if d := occupancy.Days[2]; d.Free != 0 || d.Overbooked != 1 {
+			t.Errorf("expected nothing free and 1 overbooked, got %d free and %d overbooked", d.Free, d.Overbooked)
+		}
+
+		if d := occupancy.Days[0]; d.Free != 1 || d.Overbooked != 0 {
+			t.Errorf("expected 1 free, got %d free and %d overbooked", d.Free, d.Overbooked)
+		}
+	})
+
+	t.Run("writes an iCalendar feed", func(t *testing.T) {
+		occupancy := pkg.NewOccupancy(equipment, rents, day(0), day(6), day(0))
+
+		var buf bytes.Buffer
+		if err := occupancy.WriteICS(&buf, day(0)); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		ics := buf.String()
+		for _, line := range []string{
+			"BEGIN:VCALENDAR\r\n",
+			"DTSTART;VALUE=DATE:20230303\r\n",
+			"DTEND;VALUE=DATE:20230306\r\n",
+			"SUMMARY:3 x Betoneira\\, 400L\r\n",
+			"END:VCALENDAR\r\n",
+		} {
+			if !strings.Contains(ics, line) {
+				t.Errorf("expected feed to contain %q, got:\n%s", line, ics)
+			}
+		}
+
+		if events := strings.Count(ics, "BEGIN:VEVENT"); events != 2 {
+			t.Errorf("expected 2 events, got %d", events)
+		}
+	})
+
+	t.Run("rejects long periods", func(t *testing.T) {
+		svc := pkg.NewService(&fakeValidator{}, newFakeRepository(), nil, &fakeInventory{equipment: equipment}, &fakeCredit{}, 1, time.Hour)
+
+		if _, err := svc.GetOccupancy("equipment", day(0), day(400)); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("sums up the items of a rent", func(t *testing.T) {
+		split := []*pkg.Rent{{
+			ID:        "split",
+			Status:    pkg.StatusActive,
+			StartDate: day(0),
+			EndDate:   day(2),
+			Items: []*pkg.Item{
+				{EquipmentID: "equipment", Qty: 2, Returns: []*pkg.Return{{Qty: 1, Date: day(0)}}},
+				{EquipmentID: "equipment", Qty: 3},
+			},
+		}}
+
+		occupancy := pkg.NewOccupancy(equipment, split, day(0), day(6), day(0))
+		if len(occupancy.Rents) != 1 || occupancy.Rents[0].Qty != 5 {
+			t.Fatalf("expected a single occupation of 5, got %d", len(occupancy.Rents))
+		}
+
+		if occupancy.Days[0].Occupied != 5 || occupancy.Days[1].Occupied != 4 {
+			t.Errorf("expected 5 pieces out and then 4, got %d and %d", occupancy.Days[0].Occupied, occupancy.Days[1].Occupied)
+		}
+
+		var buf bytes.Buffer
+		if err := occupancy.WriteICS(&buf, day(0)); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if uids := strings.Count(buf.String(), "UID:split-equipment@"); uids != 1 {
+			t.Errorf("expected a single event for the rent, got %d", uids)
+		}
+	})
+
+	t.Run("serves the feed", func(t *testing.T) {
+		var (
+			req   pkg.OccupancyRequest
+			token any
+		)
+
+		handler := pkg.NewHTTPServer(pkg.Set{
+			OccupancyFeed: func(ctx context.Context, r any) (any, error) {
+				req = r.(pkg.OccupancyRequest)
+				token = ctx.Value(jwt.JWTContextKey)
+				return pkg.NewOccupancy(equipment, rents, req.From, req.To, day(0)), nil
+			},
+		}, nil)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/equipment/equipment/occupancy.ics?from=2023-03-01&to=2023-03-07&token=feed", nil))
+
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/calendar") {
+			t.Fatalf("expected a calendar, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+		}
+
+		if req.EquipmentID != "equipment" || req.To.Day() != 7 {
+			t.Errorf("expected the equipment until the 7th, got %+v", req)
+		}
+
+		if token != "feed" {
+			t.Errorf("expected the feed token, got %v", token)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxMessage is saved in the same transaction as the change it announces
+// and published by the relay after the transaction commits, so that changes
+// are not lost when the broker is down.
+type OutboxMessage struct {
+	ID          string     `bson:"_id"`
+	Exchange    string     `bson:"exchange"`
+	Key         string     `bson:"key"`
+	Body        []byte     `bson:"body"`
+	Attempts    int        `bson:"attempts"`
+	LastError   string     `bson:"last_error"`
+	NextAttempt time.Time  `bson:"next_attempt"`
+	SentAt      *time.Time `bson:"sent_at"`
+	CreatedAt   time.Time  `bson:"created_at"`
+}
+
+// rentExchanges are the exchanges rent events are published to, one message
+// each so that they are retried independently.
+var rentExchanges = []string{"renting", "inventory"}
+
+// NewRentMessages creates the messages of a rent event. Cancellations only
+// carry the rent id, there is nothing left to bill.
+func NewRentMessages(event string, rent *Rent) ([]*OutboxMessage, error) {
+	payload := RentEvent{RentID: rent.ID}
+	if event != EventRentCancelled {
+		payload = NewRentEvent(rent)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	messages := make([]*OutboxMessage, 0, len(rentExchanges))
+
+	for _, exchange := range rentExchanges {
+		messages = append(messages, &OutboxMessage{
+			ID:          primitive.NewObjectID().Hex(),
+			Exchange:    exchange,
+			Key:         event,
+			Body:        body,
+			NextAttempt: now,
+			CreatedAt:   now,
+		})
+	}
+
+	return messages, nil
+}
+
+// retryDelay backs off exponentially from a second up to five minutes.
+func (m *OutboxMessage) retryDelay() time.Duration {
+	delay := time.Duration(math.Pow(2, float64(m.Attempts))) * time.Second
+	if delay > 5*time.Minute || delay <= 0 {
+		return 5 * time.Minute
+	}
+	return delay
+}
+
+type OutboxRepository interface {
+	PendingMessages(now time.Time, limit int64) ([]*OutboxMessage, error)
+	MarkMessageSent(id string, sentAt time.Time) error
+	MarkMessageFailed(id string, reason string, nextAttempt time.Time) error
+}
+
+type outboxRelay struct {
+	repository OutboxRepository
+	dial       func() (*amqp.Connection, error)
+	conn       *amqp.Connection
+	channel    *amqp.Channel
+	closed     chan *amqp.Error
+	confirms   chan amqp.Confirmation
+	tag        uint64
+	interval   time.Duration
+	logger     log.Logger
+}
+
+// NewOutboxRelay publishes the pending messages of the outbox every interval,
+// waiting for the broker to confirm each one before marking it sent. The
+// connection is dialed again whenever the broker closes it.
+func NewOutboxRelay(repository OutboxRepository, dial func() (*amqp.Connection, error), interval time.Duration, logger log.Logger) *outboxRelay {
+	return &outboxRelay{repository: repository, dial: dial, interval: interval, logger: logger}
+}
+
+func (r *outboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.close()
+			return
+		case err := <-r.closed:
+			r.logger.Log("method", "relay", "err", err)
+			r.close()
+		case <-ticker.C:
+			r.relay()
+		}
+	}
+}
+
+// open opens a channel in confirm mode, dialing the broker again if the
+// connection was lost. Delivery tags start over on every channel.
+func (r *outboxRelay) open() error {
+	if r.conn == nil || r.conn.IsClosed() {
+		conn, err := r.dial()
+		if err != nil {
+			return err
+		}
+		r.conn = conn
+	}
+
+	channel, err := r.conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	for _, exchange := range rentExchanges {
+		if err := channel.ExchangeDeclare(exchange, "direct", true, false, false, false, nil); err != nil {
+			channel.Close()
+			return err
+		}
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return err
+	}
+
+	r.channel = channel
+	r.closed = channel.NotifyClose(make(chan *amqp.Error, 1))
+	r.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 100))
+	r.tag = 0
+
+	return nil
+}
+
+func (r *outboxRelay) close() {
+	if r.channel != nil {
+		r.channel.Close()
+	}
+	r.channel, r.closed, r.confirms = nil, nil, nil
+}
+
+func (r *outboxRelay) relay() {
+	messages, err := r.repository.PendingMessages(time.Now(), 100)
+	if err != nil {
+		r.logger.Log("method", "relay", "err", err)
+		return
+	}
+
+	if len(messages) == 0 {
+		return
+	}
+
+	if r.channel == nil {
+		if err := r.open(); err != nil {
+			r.logger.Log("method", "relay", "err", err)
+			return
+		}
+	}
+
+	for _, message := range messages {
+		if err := r.publish(message); err != nil {
+			message.Attempts++
+			next := time.Now().Add(message.retryDelay())
+
+			r.logger.Log("method", "relay", "message", message.ID, "attempts", message.Attempts, "err", err)
+			r.repository.MarkMessageFailed(message.ID, err.Error(), next)
+
+			if errors.Is(err, amqp.ErrClosed) {
+				r.close()
+				return
+			}
+			continue
+		}
+
+		r.repository.MarkMessageSent(message.ID, time.Now())
+	}
+}
+
+// publish sends the message and waits for the broker to confirm it. Confirms
+// are matched by delivery tag, so a late confirm of a message that timed out
+// is not taken for this one.
+func (r *outboxRelay) publish(message *OutboxMessage) error {
+	err := r.channel.Publish(message.Exchange, message.Key, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    message.ID,
+		Body:         message.Body,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	r.tag++
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case confirm, ok := <-r.confirms:
+			if !ok {
+				return amqp.ErrClosed
+			}
+			if confirm.DeliveryTag < r.tag {
+				continue
+			}
+			if !confirm.Ack {
+				return errors.New("message not acknowledged by the broker")
+			}
+			return nil
+		case <-timeout:
+			return errors.New("timed out waiting for the broker")
+		}
+	}
+}
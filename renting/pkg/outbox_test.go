@@ -0,0 +1,70 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestRentMessage(t *testing.T) {
+	rent := &pkg.Rent{
+		ID:                 "rent",
+		CustomerID:         "customer",
+		PaymentConditionID: "condition",
+		DeliveryValue:      pkg.NewMoney(50),
+		Items:              make([]*pkg.Item, 0),
+	}
+
+	t.Run("goes to the renting and inventory exchanges", func(t *testing.T) {
+		messages, err := pkg.NewRentMessages(pkg.EventRentUpdated, rent)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if len(messages) != 2 || messages[0].Exchange != "renting" || messages[1].Exchange != "inventory" {
+			t.Fatalf("expected messages to renting and inventory, got %+v", messages)
+		}
+
+		if messages[0].ID == messages[1].ID {
+			t.Error("expected each message to have its own id")
+		}
+	})
+
+	t.Run("carries the rent event", func(t *testing.T) {
+		messages, err := pkg.NewRentMessages(pkg.EventRentUpdated, rent)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		message := messages[0]
+		if message.Key != pkg.EventRentUpdated {
+			t.Errorf("expected %s, got %s", pkg.EventRentUpdated, message.Key)
+		}
+
+		if message.SentAt != nil || message.NextAttempt.After(time.Now()) {
+			t.Error("expected message to be pending")
+		}
+
+		var event pkg.RentEvent
+		if err := json.Unmarshal(message.Body, &event); err != nil {
+			t.Fatalf("could not decode body: %v", err)
+		}
+
+		if event.RentID != "rent" || event.CustomerID != "customer" || event.Total != pkg.NewMoney(50) {
+			t.Errorf("unexpected event %+v", event)
+		}
+	})
+
+	t.Run("cancellations carry only the rent", func(t *testing.T) {
+		messages, _ := pkg.NewRentMessages(pkg.EventRentCancelled, rent)
+
+		var event pkg.RentEvent
+		json.Unmarshal(messages[0].Body, &event)
+
+		if event.RentID != "rent" || event.CustomerID != "" {
+			t.Errorf("unexpected event %+v", event)
+		}
+	})
+}
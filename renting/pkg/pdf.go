@@ -0,0 +1,153 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+)
+
+type pdfLine struct {
+	text string
+	bold bool
+	size float64
+}
+
+// pdfDocument writes plain text documents as PDF, using the standard
+// Helvetica fonts so that nothing needs to be embedded.
+type pdfDocument struct {
+	pages  [][]pdfLine
+	cursor float64
+}
+
+func newPDFDocument() *pdfDocument {
+	return &pdfDocument{}
+}
+
+func (d *pdfDocument) AddLine(text string, bold bool, size float64) {
+	for _, line := range wrapText(text, size) {
+		if d.pages == nil || d.cursor-size*1.4 < pdfMargin {
+			d.pages = append(d.pages, make([]pdfLine, 0))
+			d.cursor = pdfPageHeight - pdfMargin
+		}
+
+		d.cursor -= size * 1.4
+		page := len(d.pages) - 1
+		d.pages[page] = append(d.pages[page], pdfLine{line, bold, size})
+	}
+}
+
+// wrapText breaks text into lines that fit the page. Helvetica glyphs are
+// about half as wide as the font size, which is good enough for text.
+func wrapText(text string, size float64) []string {
+	limit := int((pdfPageWidth - 2*pdfMargin) / (size * 0.5))
+	words := strings.Fields(text)
+
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	lines := make([]string, 0)
+	line := words[0]
+
+	for _, word := range words[1:] {
+		if len([]rune(line))+1+len([]rune(word)) > limit {
+			lines = append(lines, line)
+			line = word
+		} else {
+			line += " " + word
+		}
+	}
+
+	return append(lines, line)
+}
+
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	if d.pages == nil {
+		d.AddLine("", false, 10)
+	}
+
+	var buf bytes.Buffer
+	offsets := make([]int, 0)
+
+	object := func(content string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), content)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		// objects 1 to 4 are the catalog, the page tree and the fonts,
+		// then each page takes two objects: itself and its content
+		kids[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth,
+			pdfPageHeight,
+			6+i*2,
+		))
+
+		var content bytes.Buffer
+		cursor := pdfPageHeight - pdfMargin
+
+		for _, line := range page {
+			font := "F1"
+			if line.bold {
+				font = "F2"
+			}
+
+			cursor -= line.size * 1.4
+			fmt.Fprintf(&content, "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", font, line.size, pdfMargin, cursor, escapePDFText(line.text))
+		}
+
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.WriteTo(w)
+}
+
+// escapePDFText converts text to the single byte encoding of the fonts,
+// replacing the characters it does not have.
+func escapePDFText(text string) string {
+	var buf bytes.Buffer
+
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r < 32:
+			buf.WriteByte(' ')
+		case r < 256:
+			buf.WriteByte(byte(r))
+		default:
+			buf.WriteByte('?')
+		}
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,83 @@
+package pkg
+
+import "sort"
+
+type Price struct {
+	Periods []*PeriodPrice `json:"periods"`
+	Total   Money          `json:"total"`
+}
+
+type PeriodPrice struct {
+	PeriodID string `json:"period_id"`
+	Name     string `json:"name"`
+	QtyDays  int    `json:"qty_days"`
+	Qty      int    `json:"qty"`
+	Value    Money  `json:"value"`
+}
+
+// BestPrice finds the cheapest combination of renting values covering the
+// amount of days, charging a single piece. Combinations may go past the days
+// when that is cheaper, like renting a week instead of six days. Values
+// without a known period length are left out, so it returns nil when none
+// can be used.
+func BestPrice(values []*RentingValue, days int) *Price {
+	usable := make([]*RentingValue, 0, len(values))
+	for _, value := range values {
+		if value.Period != nil && value.Period.QtyDays > 0 {
+			usable = append(usable, value)
+		}
+	}
+
+	if len(usable) == 0 {
+		return nil
+	}
+
+	if days < 1 {
+		days = 1
+	}
+
+	// cost[d] is the cheapest price covering d days, and choice[d] the
+	// value last added to reach it
+	cost := make([]Money, days+1)
+	choice := make([]int, days+1)
+
+	for d := 1; d <= days; d++ {
+		choice[d] = -1
+		for i, value := range usable {
+			prev := d - int(value.Period.QtyDays)
+			if prev < 0 {
+				prev = 0
+			}
+
+			if total := cost[prev] + value.Value; choice[d] == -1 || total < cost[d] {
+				cost[d] = total
+				choice[d] = i
+			}
+		}
+	}
+
+	price := &Price{Periods: make([]*PeriodPrice, 0), Total: cost[days]}
+	counts := make(map[int]int)
+
+	for d := days; d > 0; d -= int(usable[choice[d]].Period.QtyDays) {
+		counts[choice[d]]++
+	}
+
+	for i, qty := range counts {
+		value := usable[i]
+		price.Periods = append(price.Periods, &PeriodPrice{
+			PeriodID: value.PeriodID,
+			Name:     value.Period.Name,
+			QtyDays:  int(value.Period.QtyDays),
+			Qty:      qty,
+			Value:    value.Value,
+		})
+	}
+
+	// longest periods first, as in 1 month + 1 week + 3 days
+	sort.Slice(price.Periods, func(i, j int) bool {
+		return price.Periods[i].QtyDays > price.Periods[j].QtyDays
+	})
+
+	return price
+}
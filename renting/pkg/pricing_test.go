@@ -0,0 +1,74 @@
+package pkg_test
+
+import (
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestBestPrice(t *testing.T) {
+	values := []*pkg.RentingValue{
+		{PeriodID: "daily", Period: &pkg.Period{Name: "Diário", QtyDays: 1}, Value: pkg.NewMoney(10)},
+		{PeriodID: "weekly", Period: &pkg.Period{Name: "Semanal", QtyDays: 7}, Value: pkg.NewMoney(50)},
+		{PeriodID: "monthly", Period: &pkg.Period{Name: "Mensal", QtyDays: 30}, Value: pkg.NewMoney(150)},
+	}
+
+	t.Run("combines periods", func(t *testing.T) {
+		price := pkg.BestPrice(values, 40)
+
+		if price.Total != pkg.NewMoney(230) {
+			t.Errorf("expected total 230, got %s", price.Total)
+		}
+
+		expected := []struct {
+			period string
+			qty    int
+		}{{"monthly", 1}, {"weekly", 1}, {"daily", 3}}
+
+		if len(price.Periods) != len(expected) {
+			t.Fatalf("expected %d periods, got %d", len(expected), len(price.Periods))
+		}
+
+		for i, period := range price.Periods {
+			if period.PeriodID != expected[i].period || period.Qty != expected[i].qty {
+				t.Errorf("expected %d x %s, got %d x %s", expected[i].qty, expected[i].period, period.Qty, period.PeriodID)
+			}
+		}
+	})
+
+	t.Run("covers more days when cheaper", func(t *testing.T) {
+		price := pkg.BestPrice(values, 6)
+
+		if price.Total != pkg.NewMoney(50) {
+			t.Errorf("expected total 50, got %s", price.Total)
+		}
+		if len(price.Periods) != 1 || price.Periods[0].PeriodID != "weekly" {
+			t.Errorf("expected a single weekly period, got %v", price.Periods)
+		}
+	})
+
+	t.Run("without period lengths", func(t *testing.T) {
+		price := pkg.BestPrice([]*pkg.RentingValue{{PeriodID: "custom", Value: pkg.NewMoney(100)}}, 10)
+
+		if price != nil {
+			t.Errorf("expected no price, got %v", price)
+		}
+	})
+
+	t.Run("rent subtotal", func(t *testing.T) {
+		start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+		rent := &pkg.Rent{
+			PeriodID:  "daily",
+			StartDate: start,
+			EndDate:   start.AddDate(0, 0, 40),
+			Items: []*pkg.Item{
+				{Qty: 2, Equipment: &pkg.Equipment{RentingValues: values}},
+			},
+		}
+
+		if subtotal := rent.GetSubtotal(); subtotal != pkg.NewMoney(460) {
+			t.Errorf("expected subtotal 460, got %s", subtotal)
+		}
+	})
+}
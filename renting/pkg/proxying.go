@@ -2,37 +2,135 @@ package pkg
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"time"
 
+	"github.com/go-kit/kit/auth/jwt"
 	"github.com/go-kit/kit/endpoint"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"reconcip.com.br/microservices/renting/proto"
 )
 
+// WithVerifyEndpoints rejects requests without a valid token, making the
+// authenticated user available in the context of the endpoints it wraps. The
+// occupancy feed only takes feed tokens, which are good for nothing else.
+func WithVerifyEndpoints(cc *grpc.ClientConn, endpoints Set) Set {
+	verify := verifyMiddleware(verifyEndpoint(cc, "Verify"))
+	verifyFeed := verifyMiddleware(verifyEndpoint(cc, "VerifyFeed"))
+	return Set{
+		Create:     verify(endpoints.Create),
+		List:       verify(endpoints.List),
+		Update:     verify(endpoints.Update),
+		Get:        verify(endpoints.Get),
+		Delete:     verify(endpoints.Delete),
+		Transition: verify(endpoints.Transition),
+		Return:     verify(endpoints.Return),
+		Extend:     verify(endpoints.Extend),
+		Deduct:     verify(endpoints.Deduct),
+		Refund:     verify(endpoints.Refund),
+		History:    verify(endpoints.History),
+
+		CreateEstimate:  verify(endpoints.CreateEstimate),
+		GetEstimate:     verify(endpoints.GetEstimate),
+		ConvertEstimate: verify(endpoints.ConvertEstimate),
+
+		StuckSagas:    verify(endpoints.StuckSagas),
+		Occupancy:     verify(endpoints.Occupancy),
+		OccupancyFeed: verifyFeed(endpoints.OccupancyFeed),
+		Exposure:      verify(endpoints.Exposure),
+	}
+}
+
+func verifyMiddleware(verify endpoint.Endpoint) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, r any) (any, error) {
+			user, err := verify(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			return next(ContextWithUser(ctx, user.(*User)), r)
+		}
+	}
+}
+
+func verifyEndpoint(cc *grpc.ClientConn, method string) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Auth",
+		method,
+		encodeVerifyRequest,
+		decodeVerifyResponse,
+		&proto.VerifyReply{},
+		grpctransport.ClientBefore(jwt.ContextToGRPC()),
+	).Endpoint()
+}
+
+func encodeVerifyRequest(ctx context.Context, r any) (any, error) {
+	return nil, nil
+}
+
+func decodeVerifyResponse(ctx context.Context, r any) (any, error) {
+	reply := r.(*proto.VerifyReply)
+	if reply.GetErr() != nil {
+		return nil, NewError(
+			int(reply.GetErr().GetStatus()),
+			reply.GetErr().GetTitle(),
+			reply.GetErr().GetDetail(),
+		)
+	}
+
+	return &User{
+		ID:    reply.GetUser().GetId(),
+		Name:  reply.GetUser().GetName(),
+		Roles: reply.GetUser().GetRoles(),
+	}, nil
+}
+
 func WithPaymentTypeEndpoints(cc *grpc.ClientConn, endpoints Set) Set {
 	withPaymentType := withPaymentTypeMiddleware(cc)
 	return Set{
-		Create: withPaymentType(endpoints.Create),
-		List:   withPaymentType(endpoints.List),
-		Update: withPaymentType(endpoints.Update),
-		Get:    withPaymentType(endpoints.Get),
-		Delete: endpoints.Delete,
+		Create:     withPaymentType(endpoints.Create),
+		List:       withPaymentType(endpoints.List),
+		Update:     withPaymentType(endpoints.Update),
+		Get:        withPaymentType(endpoints.Get),
+		Delete:     endpoints.Delete,
+		Transition: withPaymentType(endpoints.Transition),
+		Return:     withPaymentType(endpoints.Return),
+		Extend:     withPaymentType(endpoints.Extend),
+		Deduct:     withPaymentType(endpoints.Deduct),
+		Refund:     withPaymentType(endpoints.Refund),
+		History:    endpoints.History,
+
+		CreateEstimate:  withPaymentType(endpoints.CreateEstimate),
+		GetEstimate:     withPaymentType(endpoints.GetEstimate),
+		ConvertEstimate: withPaymentType(endpoints.ConvertEstimate),
+
+		StuckSagas:    endpoints.StuckSagas,
+		Occupancy:     endpoints.Occupancy,
+		OccupancyFeed: endpoints.OccupancyFeed,
+		Exposure:      endpoints.Exposure,
 	}
 }
 
 func withPaymentTypeMiddleware(cc *grpc.ClientConn) endpoint.Middleware {
-	getPaymentType := getPaymentTypeEndpoint(cc)
+	loader := NewBatchLoader(getPaymentTypesEndpoint(cc), enrichmentBatchSize, enrichmentCacheSize, enrichmentTTL)
 
-	appendType := func(ctx context.Context, rent *Rent) {
-		paymentType, err := getPaymentType(ctx, rent.PaymentTypeID)
-		if err == nil {
-			rent.PaymentType = paymentType.(*PaymentType)
-		}
-	}
+	return enrichMiddleware(
+		loader,
+		func(rent *Rent) string { return rent.PaymentTypeID },
+		func(rent *Rent, paymentType any) { rent.PaymentType = paymentType.(*PaymentType) },
+	)
+}
 
+// enrichMiddleware loads at once what the rents in a response refer to, by the
+// id returned by key, and hands it to each rent through set. Rents whose
+// reference could not be loaded are returned as they are.
+func enrichMiddleware(loader *BatchLoader, key func(*Rent) string, set func(*Rent, any)) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, r any) (any, error) {
 			res, err := next(ctx, r)
@@ -40,19 +138,42 @@ func withPaymentTypeMiddleware(cc *grpc.ClientConn) endpoint.Middleware {
 				return nil, err
 			}
 
-			if rent, ok := res.(*Rent); ok {
-				appendType(ctx, rent)
+			rents := responseRents(res)
+			ids := make([]string, len(rents))
+			for i, rent := range rents {
+				ids[i] = key(rent)
 			}
 
-			if result, ok := res.(ListResult); ok {
-				for _, rent := range result.Items {
-					appendType(ctx, rent.(*Rent))
+			found, _ := loader.Load(ctx, ids)
+			for _, rent := range rents {
+				if value, ok := found[key(rent)]; ok {
+					set(rent, value)
 				}
 			}
 
-			return res, err
+			return res, nil
+		}
+	}
+}
+
+// responseRents returns the rents in the response of an endpoint, be it a
+// rent, an estimate or a page of rents.
+func responseRents(res any) []*Rent {
+	switch res := res.(type) {
+	case *Rent:
+		return []*Rent{res}
+	case *Estimate:
+		return []*Rent{res.Rent}
+	case ListResult:
+		rents := make([]*Rent, 0, len(res.Items))
+		for _, item := range res.Items {
+			if rent, ok := item.(*Rent); ok {
+				rents = append(rents, rent)
+			}
 		}
+		return rents
 	}
+	return nil
 }
 
 func getPaymentTypeEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
@@ -66,62 +187,86 @@ func getPaymentTypeEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
 	).Endpoint()
 }
 
+func getPaymentTypesEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Payment",
+		"GetTypesByIDs",
+		encodeByIDsRequest,
+		decodePaymentTypes,
+		&proto.TypesReply{},
+	).Endpoint()
+}
+
 func encodeRequest(ctx context.Context, r any) (any, error) {
 	return &proto.GetRequest{Id: r.(string)}, nil
 }
 
+func encodeByIDsRequest(ctx context.Context, r any) (any, error) {
+	return &proto.GetByIDsRequest{Ids: r.([]string)}, nil
+}
+
 func decodePaymentType(ctx context.Context, r any) (any, error) {
 	res := r.(*proto.TypeReply)
-	paymentType := res.GetType()
+	return newPaymentType(res.GetType()), nil
+}
+
+func decodePaymentTypes(ctx context.Context, r any) (any, error) {
+	reply := r.(*proto.TypesReply)
+	if reply.GetErr() != "" {
+		return nil, errors.New(reply.GetErr())
+	}
+
+	paymentTypes := make(map[string]any, len(reply.GetTypes()))
+	for _, paymentType := range reply.GetTypes() {
+		paymentTypes[paymentType.GetId()] = newPaymentType(paymentType)
+	}
+
+	return paymentTypes, nil
+}
 
+func newPaymentType(paymentType *proto.Type) *PaymentType {
 	return &PaymentType{
 		ID:   paymentType.GetId(),
 		Name: paymentType.GetName(),
-	}, nil
+	}
 }
 
 func WithPaymentMethodEndpoints(cc *grpc.ClientConn, endpoints Set) Set {
 	withPaymentMethod := withPaymentMethodMiddleware(cc)
 
 	return Set{
-		Create: withPaymentMethod(endpoints.Create),
-		List:   withPaymentMethod(endpoints.List),
-		Update: withPaymentMethod(endpoints.Update),
-		Get:    withPaymentMethod(endpoints.Get),
-		Delete: endpoints.Delete,
+		Create:     withPaymentMethod(endpoints.Create),
+		List:       withPaymentMethod(endpoints.List),
+		Update:     withPaymentMethod(endpoints.Update),
+		Get:        withPaymentMethod(endpoints.Get),
+		Delete:     endpoints.Delete,
+		Transition: withPaymentMethod(endpoints.Transition),
+		Return:     withPaymentMethod(endpoints.Return),
+		Extend:     withPaymentMethod(endpoints.Extend),
+		Deduct:     withPaymentMethod(endpoints.Deduct),
+		Refund:     withPaymentMethod(endpoints.Refund),
+		History:    endpoints.History,
+
+		CreateEstimate:  withPaymentMethod(endpoints.CreateEstimate),
+		GetEstimate:     withPaymentMethod(endpoints.GetEstimate),
+		ConvertEstimate: withPaymentMethod(endpoints.ConvertEstimate),
+
+		StuckSagas:    endpoints.StuckSagas,
+		Occupancy:     endpoints.Occupancy,
+		OccupancyFeed: endpoints.OccupancyFeed,
+		Exposure:      endpoints.Exposure,
 	}
 }
 
 func withPaymentMethodMiddleware(cc *grpc.ClientConn) endpoint.Middleware {
-	getPaymentMethod := getPaymentMethodEndpoint(cc)
-
-	appendMethod := func(ctx context.Context, rent *Rent) {
-		method, err := getPaymentMethod(ctx, rent.PaymentMethodID)
-		if err == nil {
-			rent.PaymentMethod = method.(*PaymentMethod)
-		}
-	}
+	loader := NewBatchLoader(getPaymentMethodsEndpoint(cc), enrichmentBatchSize, enrichmentCacheSize, enrichmentTTL)
 
-	return func(next endpoint.Endpoint) endpoint.Endpoint {
-		return func(ctx context.Context, r any) (any, error) {
-			res, err := next(ctx, r)
-			if err != nil {
-				return nil, err
-			}
-
-			if rent, ok := res.(*Rent); ok {
-				appendMethod(ctx, rent)
-			}
-
-			if result, ok := res.(ListResult); ok {
-				for _, rent := range result.Items {
-					appendMethod(ctx, rent.(*Rent))
-				}
-			}
-
-			return res, nil
-		}
-	}
+	return enrichMiddleware(
+		loader,
+		func(rent *Rent) string { return rent.PaymentMethodID },
+		func(rent *Rent, method any) { rent.PaymentMethod = method.(*PaymentMethod) },
+	)
 }
 
 func getPaymentMethodEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
@@ -135,51 +280,93 @@ func getPaymentMethodEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
 	).Endpoint()
 }
 
+func getPaymentMethodsEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Payment",
+		"GetMethodsByIDs",
+		encodeByIDsRequest,
+		decodePaymentMethods,
+		&proto.MethodsReply{},
+	).Endpoint()
+}
+
 func decodePaymentMethod(ctx context.Context, r any) (any, error) {
 	reply := r.(*proto.MethodReply)
+	return newPaymentMethod(reply.GetMethod()), nil
+}
 
+func decodePaymentMethods(ctx context.Context, r any) (any, error) {
+	reply := r.(*proto.MethodsReply)
+	if reply.GetErr() != "" {
+		return nil, errors.New(reply.GetErr())
+	}
+
+	methods := make(map[string]any, len(reply.GetMethods()))
+	for _, method := range reply.GetMethods() {
+		methods[method.GetId()] = newPaymentMethod(method)
+	}
+
+	return methods, nil
+}
+
+func newPaymentMethod(method *proto.Method) *PaymentMethod {
 	return &PaymentMethod{
-		ID:   reply.Method.GetId(),
-		Name: reply.Method.GetName(),
-	}, nil
+		ID:   method.GetId(),
+		Name: method.GetName(),
+	}
 }
 
 func WithPaymentConditionEndpoints(cc *grpc.ClientConn, endpoints Set) Set {
 	withPaymentCondition := withPaymentConditionMiddleware(cc)
 
 	return Set{
-		Create: withPaymentCondition(endpoints.Create),
-		List:   withPaymentCondition(endpoints.List),
-		Update: withPaymentCondition(endpoints.Update),
-		Get:    withPaymentCondition(endpoints.Get),
-		Delete: endpoints.Delete,
+		Create:     withPaymentCondition(endpoints.Create),
+		List:       withPaymentCondition(endpoints.List),
+		Update:     withPaymentCondition(endpoints.Update),
+		Get:        withPaymentCondition(endpoints.Get),
+		Delete:     endpoints.Delete,
+		Transition: withPaymentCondition(endpoints.Transition),
+		Return:     withPaymentCondition(endpoints.Return),
+		Extend:     withPaymentCondition(endpoints.Extend),
+		Deduct:     withPaymentCondition(endpoints.Deduct),
+		Refund:     withPaymentCondition(endpoints.Refund),
+		History:    endpoints.History,
+
+		CreateEstimate:  withPaymentCondition(endpoints.CreateEstimate),
+		GetEstimate:     withPaymentCondition(endpoints.GetEstimate),
+		ConvertEstimate: withPaymentCondition(endpoints.ConvertEstimate),
+
+		StuckSagas:    endpoints.StuckSagas,
+		Occupancy:     endpoints.Occupancy,
+		OccupancyFeed: endpoints.OccupancyFeed,
+		Exposure:      endpoints.Exposure,
 	}
 }
 
 func withPaymentConditionMiddleware(cc *grpc.ClientConn) endpoint.Middleware {
-	getPaymentCondition := getPaymentConditionEndpoint(cc)
+	loader := NewBatchLoader(getPaymentConditionsEndpoint(cc), enrichmentBatchSize, enrichmentCacheSize, enrichmentTTL)
 
-	appendCondition := func(ctx context.Context, rent *Rent) {
-		condition, err := getPaymentCondition(ctx, rent.PaymentConditionID)
-		if err == nil {
-			rent.PaymentCondition = condition.(*PaymentCondition)
-		}
-	}
+	withCondition := enrichMiddleware(
+		loader,
+		func(rent *Rent) string { return rent.PaymentConditionID },
+		func(rent *Rent, condition any) { rent.PaymentCondition = condition.(*PaymentCondition) },
+	)
 
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		next = withCondition(next)
+
 		return func(ctx context.Context, r any) (any, error) {
 			res, err := next(ctx, r)
 			if err != nil {
 				return nil, err
 			}
 
-			if rent, ok := res.(*Rent); ok {
-				appendCondition(ctx, rent)
-			}
-
-			if result, ok := res.(ListResult); ok {
-				for _, rent := range result.Items {
-					appendCondition(ctx, rent.(*Rent))
+			// installments depend on the total of each rent, so they are
+			// worked out here from the condition loaded along with the others
+			for _, rent := range responseRents(res) {
+				if rent.PaymentCondition != nil && rent.GetTotal() > 0 {
+					rent.Installments = rent.PaymentCondition.Schedule(rent.GetTotal(), rent.StartDate)
 				}
 			}
 
@@ -199,66 +386,81 @@ func getPaymentConditionEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
 	).Endpoint()
 }
 
+func getPaymentConditionsEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Payment",
+		"GetConditionsByIDs",
+		encodeByIDsRequest,
+		decodePaymentConditions,
+		&proto.ConditionsReply{},
+	).Endpoint()
+}
+
 func decodePaymentCondition(ctx context.Context, r any) (any, error) {
 	reply := r.(*proto.ConditionReply)
+	return newPaymentCondition(reply.GetCondition()), nil
+}
+
+func decodePaymentConditions(ctx context.Context, r any) (any, error) {
+	reply := r.(*proto.ConditionsReply)
+	if reply.GetErr() != "" {
+		return nil, errors.New(reply.GetErr())
+	}
+
+	conditions := make(map[string]any, len(reply.GetConditions()))
+	for _, condition := range reply.GetConditions() {
+		conditions[condition.GetId()] = newPaymentCondition(condition)
+	}
 
-	condition := reply.GetCondition()
-	paymentType := condition.GetPaymentType()
+	return conditions, nil
+}
 
+func newPaymentCondition(condition *proto.Condition) *PaymentCondition {
 	return &PaymentCondition{
-		ID:        condition.GetId(),
-		Name:      condition.GetName(),
-		Increment: condition.GetIncrement(),
-		PaymentType: &PaymentType{
-			ID:   paymentType.GetId(),
-			Name: paymentType.GetName(),
-		},
+		ID:           condition.GetId(),
+		Name:         condition.GetName(),
+		Increment:    condition.GetIncrement(),
+		PaymentType:  newPaymentType(condition.GetPaymentType()),
 		Installments: condition.GetInstallments(),
-	}, nil
+	}
 }
 
 func WithCustomerEndpoints(cc *grpc.ClientConn, endpoints Set) Set {
 	withCustomer := withCustomerMiddleware(cc)
 
 	return Set{
-		Create: withCustomer(endpoints.Create),
-		List:   withCustomer(endpoints.List),
-		Update: withCustomer(endpoints.Update),
-		Get:    withCustomer(endpoints.Get),
-		Delete: endpoints.Delete,
+		Create:     withCustomer(endpoints.Create),
+		List:       withCustomer(endpoints.List),
+		Update:     withCustomer(endpoints.Update),
+		Get:        withCustomer(endpoints.Get),
+		Delete:     endpoints.Delete,
+		Transition: withCustomer(endpoints.Transition),
+		Return:     withCustomer(endpoints.Return),
+		Extend:     withCustomer(endpoints.Extend),
+		Deduct:     withCustomer(endpoints.Deduct),
+		Refund:     withCustomer(endpoints.Refund),
+		History:    endpoints.History,
+
+		CreateEstimate:  withCustomer(endpoints.CreateEstimate),
+		GetEstimate:     withCustomer(endpoints.GetEstimate),
+		ConvertEstimate: withCustomer(endpoints.ConvertEstimate),
+
+		StuckSagas:    endpoints.StuckSagas,
+		Occupancy:     endpoints.Occupancy,
+		OccupancyFeed: endpoints.OccupancyFeed,
+		Exposure:      endpoints.Exposure,
 	}
 }
 
 func withCustomerMiddleware(cc *grpc.ClientConn) endpoint.Middleware {
-	getCustomer := getCustomerEndpoint(cc)
-
-	appendCustomer := func(ctx context.Context, rent *Rent) {
-		customer, err := getCustomer(ctx, rent.CustomerID)
-		if err == nil {
-			rent.Customer = customer.(*Customer)
-		}
-	}
-
-	return func(next endpoint.Endpoint) endpoint.Endpoint {
-		return func(ctx context.Context, r any) (any, error) {
-			res, err := next(ctx, r)
-			if err != nil {
-				return nil, err
-			}
-
-			if rent, ok := res.(*Rent); ok {
-				appendCustomer(ctx, rent)
-			}
+	loader := NewBatchLoader(getCustomersEndpoint(cc), enrichmentBatchSize, enrichmentCacheSize, enrichmentTTL)
 
-			if result, ok := res.(ListResult); ok {
-				for _, rent := range result.Items {
-					appendCustomer(ctx, rent.(*Rent))
-				}
-			}
-
-			return res, nil
-		}
-	}
+	return enrichMiddleware(
+		loader,
+		func(rent *Rent) string { return rent.CustomerID },
+		func(rent *Rent, customer any) { rent.Customer = customer.(*Customer) },
+	)
 }
 
 func getCustomerEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
@@ -272,18 +474,109 @@ func getCustomerEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
 	).Endpoint()
 }
 
+func getCustomersEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Customer",
+		"GetByIDs",
+		encodeByIDsRequest,
+		decodeCustomers,
+		&proto.CustomersReply{},
+	).Endpoint()
+}
+
 func decodeCustomer(ctx context.Context, r any) (any, error) {
-	reply := r.(*proto.Customer)
+	return newCustomer(r.(*proto.Customer)), nil
+}
+
+func decodeCustomers(ctx context.Context, r any) (any, error) {
+	reply := r.(*proto.CustomersReply)
 
+	customers := make(map[string]any, len(reply.GetClients()))
+	for _, customer := range reply.GetClients() {
+		customers[customer.GetId()] = newCustomer(customer)
+	}
+
+	return customers, nil
+}
+
+func newCustomer(customer *proto.Customer) *Customer {
 	return &Customer{
-		ID:        reply.GetId(),
-		Name:      reply.GetName(),
-		Email:     reply.GetEmail(),
-		CpfCnpj:   reply.GetCpfCnpj(),
-		RgInscEst: reply.GetRgInscEst(),
-		Phone:     reply.GetPhone(),
-		Cellphone: reply.GetCellphone(),
-	}, nil
+		ID:          customer.GetId(),
+		Name:        customer.GetName(),
+		Email:       customer.GetEmail(),
+		CpfCnpj:     customer.GetCpfCnpj(),
+		RgInscEst:   customer.GetRgInscEst(),
+		Phone:       customer.GetPhone(),
+		Cellphone:   customer.GetCellphone(),
+		CreditLimit: NewMoney(customer.GetCreditLimit()),
+	}
+}
+
+type grpcCreditService struct {
+	getCustomer       endpoint.Endpoint
+	getUnpaidInvoices endpoint.Endpoint
+}
+
+// NewGRPCCreditService reads the credit limit from the customer service and
+// the unpaid invoices from the payment service.
+func NewGRPCCreditService(customer, payment *grpc.ClientConn) CreditService {
+	return &grpcCreditService{getCustomerEndpoint(customer), getUnpaidInvoicesEndpoint(payment)}
+}
+
+func (s *grpcCreditService) GetCreditLimit(customerID string) (Money, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	customer, err := s.getCustomer(ctx, customerID)
+	if err != nil {
+		return 0, err
+	}
+
+	return customer.(*Customer).CreditLimit, nil
+}
+
+func (s *grpcCreditService) ListUnpaidInvoices(customerID string) ([]*UnpaidInvoice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	invoices, err := s.getUnpaidInvoices(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return invoices.([]*UnpaidInvoice), nil
+}
+
+func getUnpaidInvoicesEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Payment",
+		"GetUnpaidInvoices",
+		encodeRequest,
+		decodeUnpaidInvoices,
+		&proto.InvoicesReply{},
+	).Endpoint()
+}
+
+func decodeUnpaidInvoices(ctx context.Context, r any) (any, error) {
+	reply := r.(*proto.InvoicesReply)
+	if reply.GetErr() != "" {
+		return nil, errors.New(reply.GetErr())
+	}
+
+	invoices := make([]*UnpaidInvoice, len(reply.GetInvoices()))
+	for i, invoice := range reply.GetInvoices() {
+		invoices[i] = &UnpaidInvoice{
+			ID:      invoice.GetId(),
+			RentID:  invoice.GetRentId(),
+			Cycle:   int(invoice.GetCycle()),
+			Total:   NewMoney(invoice.GetTotal()),
+			DueDate: invoice.GetDueDate().AsTime(),
+		}
+	}
+
+	return invoices, nil
 }
 
 type grpcDeliveryService struct {
@@ -319,6 +612,30 @@ func (s *grpcDeliveryService) GetQuote(origin, dest, carrier string, items []*It
 	return quote.(*Quote), nil
 }
 
+func (s *grpcDeliveryService) GetQuotes(origin, dest string, items []*Item) ([]*Quote, error) {
+	quoteItems := make([]QuoteItem, len(items))
+	for i, item := range items {
+		quoteItems[i] = QuoteItem{Qty: int64(item.Qty)}
+	}
+
+	getQuotes := getQuotesEndpoint(s.conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	quotes, err := getQuotes(ctx, QuoteRequest{
+		Origin: origin,
+		Dest:   dest,
+		Items:  quoteItems,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return quotes.([]*Quote), nil
+}
+
 func getQuoteEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
 	return grpctransport.NewClient(
 		cc,
@@ -330,11 +647,42 @@ func getQuoteEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
 	).Endpoint()
 }
 
+func getQuotesEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Delivery",
+		"GetQuotes",
+		encodeQuotesRequest,
+		decodeQuotesResponse,
+		&proto.GetQuotesReply{},
+	).Endpoint()
+}
+
 func encodeQuoteRequest(ctx context.Context, r any) (any, error) {
 	req := r.(QuoteRequest)
-	items := make([]*proto.Item, len(req.Items))
 
-	for i, item := range req.Items {
+	return &proto.GetQuoteRequest{
+		Origin:      req.Origin,
+		Destination: req.Dest,
+		Carrier:     req.Carrier,
+		Items:       encodeQuoteItems(req.Items),
+	}, nil
+}
+
+func encodeQuotesRequest(ctx context.Context, r any) (any, error) {
+	req := r.(QuoteRequest)
+
+	return &proto.GetQuotesRequest{
+		Origin:      req.Origin,
+		Destination: req.Dest,
+		Items:       encodeQuoteItems(req.Items),
+	}, nil
+}
+
+func encodeQuoteItems(quoteItems []QuoteItem) []*proto.Item {
+	items := make([]*proto.Item, len(quoteItems))
+
+	for i, item := range quoteItems {
 		items[i] = &proto.Item{
 			Qty:    item.Qty,
 			Weight: item.Weight,
@@ -344,21 +692,39 @@ func encodeQuoteRequest(ctx context.Context, r any) (any, error) {
 		}
 	}
 
-	return &proto.GetQuoteRequest{
-		Origin:      req.Origin,
-		Destination: req.Dest,
-		Carrier:     req.Carrier,
-		Items:       items,
-	}, nil
+	return items
 }
 
+// decodeQuoteResponse fails for carriers that could not find a route, which
+// quote an infinite value.
 func decodeQuoteResponse(ctx context.Context, r any) (any, error) {
-	reply := r.(*proto.Quote)
+	quote := r.(*proto.Quote)
+	if math.IsInf(quote.GetValue(), 0) {
+		return nil, errors.New("carrier could not find a route")
+	}
+	return decodeQuote(quote), nil
+}
 
+// decodeQuotesResponse leaves out the carriers that could not find a route.
+func decodeQuotesResponse(ctx context.Context, r any) (any, error) {
+	reply := r.(*proto.GetQuotesReply)
+	quotes := make([]*Quote, 0, len(reply.GetQuotes()))
+
+	for _, quote := range reply.GetQuotes() {
+		if !math.IsInf(quote.GetValue(), 0) {
+			quotes = append(quotes, decodeQuote(quote))
+		}
+	}
+
+	return quotes, nil
+}
+
+func decodeQuote(quote *proto.Quote) *Quote {
 	return &Quote{
-		Carrier: reply.GetCarrier(),
-		Value:   reply.GetValue(),
-	}, nil
+		Carrier:  quote.GetCarrier(),
+		Value:    NewMoney(quote.GetValue()),
+		Duration: quote.GetDuration(),
+	}
 }
 
 type QuoteRequest struct {
@@ -380,67 +746,121 @@ func WithEquipmentEndpoints(cc *grpc.ClientConn, endpoints Set) Set {
 	withEquipment := withEquipmentMiddleware(cc)
 
 	return Set{
-		Create: withEquipment(endpoints.Create),
-		List:   withEquipment(endpoints.List),
-		Update: withEquipment(endpoints.Update),
-		Get:    withEquipment(endpoints.Get),
-		Delete: endpoints.Delete,
+		Create:     withEquipment(endpoints.Create),
+		List:       withEquipment(endpoints.List),
+		Update:     withEquipment(endpoints.Update),
+		Get:        withEquipment(endpoints.Get),
+		Delete:     endpoints.Delete,
+		Transition: withEquipment(endpoints.Transition),
+		Return:     withEquipment(endpoints.Return),
+		Extend:     withEquipment(endpoints.Extend),
+		Deduct:     withEquipment(endpoints.Deduct),
+		Refund:     withEquipment(endpoints.Refund),
+		History:    endpoints.History,
+
+		CreateEstimate:  withEquipment(endpoints.CreateEstimate),
+		GetEstimate:     withEquipment(endpoints.GetEstimate),
+		ConvertEstimate: withEquipment(endpoints.ConvertEstimate),
+
+		StuckSagas:    endpoints.StuckSagas,
+		Occupancy:     endpoints.Occupancy,
+		OccupancyFeed: endpoints.OccupancyFeed,
+		Exposure:      endpoints.Exposure,
 	}
 }
 
 func withEquipmentMiddleware(cc *grpc.ClientConn) endpoint.Middleware {
-	getEquipment := getEquipmentEndpoint(cc)
+	loader := NewBatchLoader(getEquipmentByIDsEndpoint(cc), enrichmentBatchSize, enrichmentCacheSize, enrichmentTTL)
 
-	appendEquipment := func(ctx context.Context, index int, item *Item) error {
-		equipment, err := getEquipment(ctx, item.EquipmentID)
+	equipmentIDs := func(items []*Item) []string {
+		ids := make([]string, len(items))
+		for i, item := range items {
+			ids[i] = item.EquipmentID
+		}
+		return ids
+	}
+
+	// the items of a rent being saved must all refer to existing equipment,
+	// priced as it is now rather than as cached
+	appendEquipment := func(ctx context.Context, items []*Item) error {
+		found, err := loader.LoadFresh(ctx, equipmentIDs(items))
 		if err != nil {
 			return NewError(
-				http.StatusBadRequest,
-				"equipment not found",
-				fmt.Sprintf("Items[%d] equipment not found", index),
+				http.StatusInternalServerError,
+				"error loading equipment",
+				"could not load the equipment of the items",
 			)
 		}
-		item.Equipment = equipment.(*Equipment)
+
+		for i, item := range items {
+			equipment, ok := found[item.EquipmentID]
+			if !ok {
+				return NewError(
+					http.StatusBadRequest,
+					"equipment not found",
+					fmt.Sprintf("Items[%d] equipment not found", i),
+				)
+			}
+			item.Equipment = equipment.(*Equipment)
+		}
+
 		return nil
 	}
 
+	// a page of rents only gets the equipment of the items saved without it
+	fillEquipment := func(ctx context.Context, rents []*Rent) {
+		items := make([]*Item, 0)
+		for _, rent := range rents {
+			for _, item := range rent.Items {
+				if item.Equipment == nil {
+					items = append(items, item)
+				}
+			}
+		}
+
+		if len(items) == 0 {
+			return
+		}
+
+		found, _ := loader.Load(ctx, equipmentIDs(items))
+		for _, item := range items {
+			if equipment, ok := found[item.EquipmentID]; ok {
+				item.Equipment = equipment.(*Equipment)
+			}
+		}
+	}
+
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, r any) (any, error) {
 			if rent, ok := r.(Rent); ok {
-				for i, item := range rent.Items {
-					if err := appendEquipment(ctx, i, item); err != nil {
-						return nil, err
-					}
+				if err := appendEquipment(ctx, rent.Items); err != nil {
+					return nil, err
 				}
 				return next(ctx, rent)
 			}
 
-			if result, ok := r.(ListResult); ok {
-				for _, item := range result.Items {
-					for i, item := range item.(*Rent).Items {
-						if err := appendEquipment(ctx, i, item); err != nil {
-							return nil, err
-						}
-					}
-				}
-				return next(ctx, result)
-			}
-
 			if req, ok := r.(UpdateRequest); ok {
-				for i, item := range req.Data.Items {
-					if err := appendEquipment(ctx, i, item); err != nil {
-						return nil, err
-					}
+				if err := appendEquipment(ctx, req.Data.Items); err != nil {
+					return nil, err
 				}
 				return next(ctx, req)
 			}
 
-			return next(ctx, r)
+			res, err := next(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+
+			if result, ok := res.(ListResult); ok {
+				fillEquipment(ctx, responseRents(result))
+			}
+
+			return res, nil
 		}
 	}
 }
 
-func getEquipmentEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+func GetEquipmentEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
 	return grpctransport.NewClient(
 		cc,
 		"proto.Inventory",
@@ -451,13 +871,38 @@ func getEquipmentEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
 	).Endpoint()
 }
 
+func getEquipmentByIDsEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Inventory",
+		"GetEquipmentByIDs",
+		encodeByIDsRequest,
+		decodeEquipmentList,
+		&proto.EquipmentReply{},
+	).Endpoint()
+}
+
 func decodeEquipment(ctx context.Context, r any) (any, error) {
-	equipment := r.(*proto.Equipment)
+	return newEquipment(r.(*proto.Equipment)), nil
+}
+
+func decodeEquipmentList(ctx context.Context, r any) (any, error) {
+	reply := r.(*proto.EquipmentReply)
+
+	equipment := make(map[string]any, len(reply.GetEquipment()))
+	for _, item := range reply.GetEquipment() {
+		equipment[item.GetId()] = newEquipment(item)
+	}
+
+	return equipment, nil
+}
+
+func newEquipment(equipment *proto.Equipment) *Equipment {
 	rentingValues := make([]*RentingValue, len(equipment.GetRentingValues()))
 
 	for i, value := range equipment.GetRentingValues() {
 		rentingValues[i] = &RentingValue{
-			Value:    value.GetValue(),
+			Value:    NewMoney(value.GetValue()),
 			PeriodID: value.GetPeriod().GetId(),
 			Period: &Period{
 				ID:      value.GetPeriod().GetId(),
@@ -471,10 +916,13 @@ func decodeEquipment(ctx context.Context, r any) (any, error) {
 		ID:             equipment.GetId(),
 		Description:    equipment.GetDescription(),
 		Weight:         equipment.GetWeight(),
-		UnitValue:      equipment.GetUnitValue(),
+		UnitValue:      NewMoney(equipment.GetUnitValue()),
+		ReplaceValue:   NewMoney(equipment.GetReplaceValue()),
+		Stock:          int(equipment.GetStock()),
 		EffectiveStock: int(equipment.GetEffectiveStock()),
+		LateFeeRate:    equipment.GetLateFeeRate(),
 		RentingValues:  rentingValues,
-	}, nil
+	}
 }
 
 func RestoreStockEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
@@ -483,40 +931,156 @@ func RestoreStockEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
 		"proto.Inventory",
 		"RestoreStock",
 		encodeRestoreStockRequest,
-		NopGRPCDecoder,
+		decodeErrReply,
 		&proto.RestoreStockReply{},
 	).Endpoint()
 }
 
+func WriteOffStockEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Inventory",
+		"WriteOffStock",
+		encodeWriteOffStockRequest,
+		decodeErrReply,
+		&proto.WriteOffStockReply{},
+	).Endpoint()
+}
+
 func ReduceStockEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
 	return grpctransport.NewClient(
 		cc,
 		"proto.Inventory",
 		"ReduceStock",
 		encodeReduceStockRequest,
-		NopGRPCDecoder,
+		decodeReduceStockReply,
 		&proto.ReduceStockReply{},
 	).Endpoint()
 }
 
 func encodeReduceStockRequest(ctx context.Context, r any) (any, error) {
-	item := r.(*Item)
+	req := r.(StockRequest)
 
 	return &proto.ReduceStockRequest{
-		Id:  item.EquipmentID,
-		Qty: int64(item.Qty),
+		Id:        req.EquipmentID,
+		Qty:       int64(req.Qty),
+		RentId:    req.RentID,
+		StartDate: timestamppb.New(req.StartDate),
+		EndDate:   timestamppb.New(req.EndDate),
 	}, nil
 }
 
 func encodeRestoreStockRequest(ctx context.Context, r any) (any, error) {
-	item := r.(*Item)
+	req := r.(StockRequest)
 
 	return &proto.RestoreStockRequest{
-		Id:  item.EquipmentID,
-		Qty: int64(item.Qty),
+		Id:     req.EquipmentID,
+		Qty:    int64(req.Qty),
+		RentId: req.RentID,
 	}, nil
 }
 
+func encodeWriteOffStockRequest(ctx context.Context, r any) (any, error) {
+	req := r.(StockRequest)
+
+	return &proto.WriteOffStockRequest{
+		Id:     req.EquipmentID,
+		Qty:    int64(req.Qty),
+		RentId: req.RentID,
+	}, nil
+}
+
+type StockRequest struct {
+	EquipmentID string    `json:"equipment_id"`
+	Qty         int       `json:"qty"`
+	RentID      string    `json:"rent_id"`
+	StartDate   time.Time `json:"start_date"`
+	EndDate     time.Time `json:"end_date"`
+}
+
+func NewStockRequest(rent *Rent, item *Item) StockRequest {
+	return StockRequest{
+		EquipmentID: item.EquipmentID,
+		Qty:         item.Qty,
+		RentID:      rent.ID,
+		StartDate:   rent.StartDate,
+		EndDate:     rent.EndDate,
+	}
+}
+
+func getAvailabilityEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Inventory",
+		"GetAvailability",
+		encodeAvailabilityRequest,
+		decodeAvailabilityResponse,
+		&proto.AvailabilityReply{},
+	).Endpoint()
+}
+
+func encodeAvailabilityRequest(ctx context.Context, r any) (any, error) {
+	req := r.(AvailabilityRequest)
+
+	return &proto.AvailabilityRequest{
+		Id:        req.EquipmentID,
+		StartDate: timestamppb.New(req.StartDate),
+		EndDate:   timestamppb.New(req.EndDate),
+		RentId:    req.RentID,
+	}, nil
+}
+
+func decodeAvailabilityResponse(ctx context.Context, r any) (any, error) {
+	reply := r.(*proto.AvailabilityReply)
+	return int(reply.GetQty()), nil
+}
+
+type AvailabilityRequest struct {
+	EquipmentID string
+	StartDate   time.Time
+	EndDate     time.Time
+	RentID      string
+}
+
+func ExtendBookingEndpoint(cc *grpc.ClientConn) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		cc,
+		"proto.Inventory",
+		"ExtendBooking",
+		encodeExtendBookingRequest,
+		decodeErrReply,
+		&proto.ExtendBookingReply{},
+	).Endpoint()
+}
+
+func encodeExtendBookingRequest(ctx context.Context, r any) (any, error) {
+	rent := r.(*Rent)
+
+	return &proto.ExtendBookingRequest{
+		RentId:  rent.ID,
+		EndDate: timestamppb.New(rent.EndDate),
+	}, nil
+}
+
+// decodeReduceStockReply keeps conflicts over the stock, so that rents can
+// tell when the pieces were taken in the meantime.
+func decodeReduceStockReply(ctx context.Context, r any) (any, error) {
+	reply := r.(*proto.ReduceStockReply)
+	if reply.GetStatus() == http.StatusConflict {
+		return nil, NewError(http.StatusConflict, "not enough stock", reply.GetErr())
+	}
+	return decodeErrReply(ctx, r)
+}
+
+// decodeErrReply turns the errors sent in replies into errors, so that
+// failures are not taken as successes.
+func decodeErrReply(ctx context.Context, r any) (any, error) {
+	if reply, ok := r.(interface{ GetErr() string }); ok && reply.GetErr() != "" {
+		return nil, errors.New(reply.GetErr())
+	}
+	return nil, nil
+}
+
 func NopGRPCDecoder(ctx context.Context, r any) (any, error) {
 	return nil, nil
 }
@@ -2,7 +2,9 @@ package pkg
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,12 +13,23 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrVersionConflict = errors.New("version conflict")
+
 type Repository interface {
 	GetRent(id string) (*Rent, error)
-	CreateRent(Rent) (*Rent, error)
-	ListRents(page, perPage int64) ([]*Rent, int64, error)
-	UpdateRent(id string, data Rent) (*Rent, error)
-	DeleteRent(id string) error
+	CreateRent(ctx context.Context, data Rent, events ...string) (*Rent, error)
+	ListRents(filter RentFilter, page, perPage int64) ([]*Rent, int64, error)
+	UpdateRent(ctx context.Context, id string, data Rent, events ...string) (*Rent, error)
+	DeleteRent(ctx context.Context, id string, events ...string) error
+	CreateEstimate(Estimate) (*Estimate, error)
+	GetEstimate(id string) (*Estimate, error)
+	UpdateEstimate(id string, data Estimate) (*Estimate, error)
+	CreateSaga(Saga) (*Saga, error)
+	UpdateSaga(id string, data Saga) error
+	ListStuckSagas(until time.Time) ([]*Saga, error)
+	ListRentChanges(rentID string) ([]*RentChange, error)
+	ListDueBillings(now time.Time) ([]*Rent, error)
+	CreateBilling(billing Billing, rent Rent) error
 }
 
 type mongoRepository struct {
@@ -32,32 +45,166 @@ func NewMongoRepository(url, user, pass, db string) (*mongoRepository, error) {
 	}
 
 	database := conn.Database(db)
+	if err := createIndexes(database); err != nil {
+		return nil, err
+	}
+
 	return &mongoRepository{database}, nil
 }
 
-func (r *mongoRepository) CreateRent(data Rent) (*Rent, error) {
+// createIndexes backs the filters available when listing rents and the
+// lookup of pending outbox messages. It also creates the collections, which
+// cannot be done inside transactions.
+func createIndexes(database *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := database.Collection("outbox").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "sent_at", Value: 1}, {Key: "next_attempt", Value: 1}},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Collection("rent_history").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "rent_id", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Collection("rent_billings").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "rent_id", Value: 1}, {Key: "cycle", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Collection("rents").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "customerid", Value: 1}}},
+		{Keys: bson.D{{Key: "carrierid", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "enddate", Value: 1}}},
+		{Keys: bson.D{{Key: "startdate", Value: 1}, {Key: "enddate", Value: 1}}},
+		{Keys: bson.D{{Key: "items.equipmentid", Value: 1}}},
+		{Keys: bson.D{{Key: "billing_cycle", Value: 1}, {Key: "next_billing", Value: 1}}},
+		{Keys: bson.D{
+			{Key: "observations", Value: "text"},
+			{Key: "deliveryaddress", Value: "text"},
+			{Key: "usageaddress", Value: "text"},
+		}},
+	})
+
+	return err
+}
+
+// withEvents runs fn in a transaction that also saves the messages of the
+// events to the outbox and the change to the history, so that all are
+// committed or none is. The rent is read before fn to tell what changed, next
+// being nil when it is deleted.
+func (r *mongoRepository) withEvents(ctx context.Context, id string, next *Rent, events []string, fn func(mongo.SessionContext) error) error {
+	session, err := r.database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		var prev *Rent
+		err := r.database.Collection("rents").FindOne(sc, bson.M{"_id": id}).Decode(&prev)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+
+		if err := fn(sc); err != nil {
+			return nil, err
+		}
+
+		rent := next
+		if rent == nil {
+			rent = prev
+		}
+
+		if rent == nil {
+			return nil, nil
+		}
+
+		for _, event := range events {
+			messages, err := NewRentMessages(event, rent)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, message := range messages {
+				if _, err := r.database.Collection("outbox").InsertOne(sc, message); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		action := ActionFromContext(ctx)
+		if action == "" {
+			action = defaultAction(prev, next)
+		}
+
+		change := NewRentChange(action, UserFromContext(ctx), prev, next)
+		change.ID = primitive.NewObjectID().Hex()
+
+		_, err = r.database.Collection("rent_history").InsertOne(sc, change)
+		return nil, err
+	})
+
+	return err
+}
+
+// defaultAction names the changes made outside of the endpoints after what
+// happened to the rent.
+func defaultAction(prev, next *Rent) string {
+	switch {
+	case prev == nil:
+		return "create"
+	case next == nil:
+		return "delete"
+	default:
+		return "update"
+	}
+}
+
+func (r *mongoRepository) CreateRent(ctx context.Context, data Rent, events ...string) (*Rent, error) {
 	collection := r.database.Collection("rents")
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 
 	defer cancel()
 
 	data.ID = primitive.NewObjectID().Hex()
-	result, err := collection.InsertOne(ctx, data)
+	data.NextBilling = data.GetNextBilling()
+	setItemIDs(data.Items)
+
+	err := r.withEvents(ctx, data.ID, &data, events, func(sc mongo.SessionContext) error {
+		_, err := collection.InsertOne(sc, data)
+		return err
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return r.GetRent(result.InsertedID.(string))
+	return r.GetRent(data.ID)
 }
 
-func (r *mongoRepository) ListRents(page, perPage int64) ([]*Rent, int64, error) {
+func (r *mongoRepository) ListRents(filter RentFilter, page, perPage int64) ([]*Rent, int64, error) {
 	collection := r.database.Collection("rents")
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 
 	defer cancel()
 
-	total, err := collection.EstimatedDocumentCount(ctx)
+	query := filterQuery(filter)
+	total, err := collection.CountDocuments(ctx, query)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -66,7 +213,11 @@ func (r *mongoRepository) ListRents(page, perPage int64) ([]*Rent, int64, error)
 	options.SetLimit(perPage)
 	options.SetSkip(page * perPage)
 
-	result, err := collection.Find(ctx, bson.D{}, options)
+	if filter.Sort != "" {
+		options.SetSort(sortQuery(filter.Sort))
+	}
+
+	result, err := collection.Find(ctx, query, options)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -98,25 +249,358 @@ func (r *mongoRepository) GetRent(id string) (*Rent, error) {
 	return rent, nil
 }
 
-func (r *mongoRepository) UpdateRent(id string, data Rent) (*Rent, error) {
+func (r *mongoRepository) UpdateRent(ctx context.Context, id string, data Rent, events ...string) (*Rent, error) {
 	collection := r.database.Collection("rents")
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 
 	defer cancel()
 
-	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": id}, data); err != nil {
+	data.ID = id
+	data.NextBilling = data.GetNextBilling()
+	setItemIDs(data.Items)
+
+	filter := versionFilter(id, data.Version)
+	data.Version++
+
+	err := r.withEvents(ctx, id, &data, events, func(sc mongo.SessionContext) error {
+		result, err := collection.ReplaceOne(sc, filter, data)
+		if err == nil && result.MatchedCount == 0 {
+			return ErrVersionConflict
+		}
+		return err
+	})
+
+	if err != nil {
 		return nil, err
 	}
 
 	return r.GetRent(id)
 }
 
-func (r *mongoRepository) DeleteRent(id string) error {
+// versionFilter matches the rent only if it is still at version. Rents saved
+// before versions existed have none and are at version 0.
+func versionFilter(id string, version int) bson.M {
+	if version == 0 {
+		return bson.M{"_id": id, "$or": bson.A{
+			bson.M{"version": 0},
+			bson.M{"version": bson.M{"$exists": false}},
+		}}
+	}
+	return bson.M{"_id": id, "version": version}
+}
+
+func (r *mongoRepository) DeleteRent(ctx context.Context, id string, events ...string) error {
 	collection := r.database.Collection("rents")
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+
+	defer cancel()
+
+	return r.withEvents(ctx, id, nil, events, func(sc mongo.SessionContext) error {
+		_, err := collection.DeleteOne(sc, bson.M{"_id": id})
+		return err
+	})
+}
+
+func (r *mongoRepository) PendingMessages(now time.Time, limit int64) ([]*OutboxMessage, error) {
+	collection := r.database.Collection("outbox")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+
+	defer cancel()
+
+	query := bson.M{"sent_at": nil, "next_attempt": bson.M{"$lte": now}}
+	options := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(limit)
+
+	result, err := collection.Find(ctx, query, options)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*OutboxMessage, 0)
+	return messages, result.All(ctx, &messages)
+}
+
+func (r *mongoRepository) MarkMessageSent(id string, sentAt time.Time) error {
+	collection := r.database.Collection("outbox")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+	_, err := collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"sent_at": sentAt}})
+
+	return err
+}
+
+func (r *mongoRepository) MarkMessageFailed(id string, reason string, nextAttempt time.Time) error {
+	collection := r.database.Collection("outbox")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+	_, err := collection.UpdateByID(ctx, id, bson.M{
+		"$set": bson.M{"last_error": reason, "next_attempt": nextAttempt},
+		"$inc": bson.M{"attempts": 1},
+	})
+
+	return err
+}
+
+func (r *mongoRepository) CreateEstimate(data Estimate) (*Estimate, error) {
+	collection := r.database.Collection("estimates")
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	data.ID = primitive.NewObjectID().Hex()
+	setItemIDs(data.Rent.Items)
+
+	result, err := collection.InsertOne(ctx, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetEstimate(result.InsertedID.(string))
+}
+
+func (r *mongoRepository) GetEstimate(id string) (*Estimate, error) {
+	collection := r.database.Collection("estimates")
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+	result := collection.FindOne(ctx, bson.M{"_id": id})
+
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var estimate *Estimate
+	if err := result.Decode(&estimate); err != nil {
+		return nil, err
+	}
+
+	return estimate, nil
+}
+
+func (r *mongoRepository) UpdateEstimate(id string, data Estimate) (*Estimate, error) {
+	collection := r.database.Collection("estimates")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+
+	defer cancel()
+
+	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": id}, data); err != nil {
+		return nil, err
+	}
+
+	return r.GetEstimate(id)
+}
+
+func (r *mongoRepository) CreateSaga(data Saga) (*Saga, error) {
+	collection := r.database.Collection("sagas")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+
+	defer cancel()
+
+	data.ID = primitive.NewObjectID().Hex()
+	if _, err := collection.InsertOne(ctx, data); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+func (r *mongoRepository) UpdateSaga(id string, data Saga) error {
+	collection := r.database.Collection("sagas")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+
+	defer cancel()
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": id}, data)
+
+	return err
+}
+
+// ListStuckSagas finds the sagas that could not be compensated and the ones
+// still running without progress since until, oldest first.
+func (r *mongoRepository) ListStuckSagas(until time.Time) ([]*Saga, error) {
+	collection := r.database.Collection("sagas")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+
+	defer cancel()
+
+	query := bson.M{"$or": bson.A{
+		bson.M{"status": SagaFailed},
+		bson.M{
+			"status":     bson.M{"$in": bson.A{SagaRunning, SagaCompensating}},
+			"updated_at": bson.M{"$lt": until},
+		},
+	}}
+
+	options := options.Find().SetSort(bson.D{{Key: "updated_at", Value: 1}})
+	result, err := collection.Find(ctx, query, options)
+	if err != nil {
+		return nil, err
+	}
+
+	sagas := make([]*Saga, 0)
+	return sagas, result.All(ctx, &sagas)
+}
+
+func (r *mongoRepository) ListRentChanges(rentID string) ([]*RentChange, error) {
+	collection := r.database.Collection("rent_history")
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 
 	defer cancel()
-	_, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+
+	options := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	result, err := collection.Find(ctx, bson.M{"rent_id": rentID}, options)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]*RentChange, 0)
+	return changes, result.All(ctx, &changes)
+}
+
+// ListDueBillings returns the rents billed by cycle whose next cycle started
+// until now, as long as they are out.
+func (r *mongoRepository) ListDueBillings(now time.Time) ([]*Rent, error) {
+	collection := r.database.Collection("rents")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	defer cancel()
+
+	result, err := collection.Find(ctx, bson.M{
+		"billing_cycle": bson.M{"$nin": bson.A{"", nil}},
+		"next_billing":  bson.M{"$lte": now},
+		"status":        bson.M{"$in": bson.A{StatusActive, StatusPartiallyReturned}},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	rents := make([]*Rent, 0)
+	return rents, result.All(ctx, &rents)
+}
+
+// CreateBilling saves the billing, moves the rent to its next cycle and sends
+// the billing to payment in a single transaction. The rent must still be at
+// its version, so that a cycle is never billed twice.
+func (r *mongoRepository) CreateBilling(billing Billing, rent Rent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	session, err := r.database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+
+	defer session.EndSession(ctx)
+
+	billing.ID = primitive.NewObjectID().Hex()
+	rent.BilledCycles++
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		if _, err := r.database.Collection("rent_billings").InsertOne(sc, billing); err != nil {
+			return nil, err
+		}
+
+		result, err := r.database.Collection("rents").UpdateOne(sc, versionFilter(rent.ID, rent.Version), bson.M{
+			"$set": bson.M{"billed_cycles": rent.BilledCycles, "next_billing": rent.GetNextBilling()},
+			"$inc": bson.M{"version": 1},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if result.MatchedCount == 0 {
+			return nil, ErrVersionConflict
+		}
+
+		message, err := NewBillingMessage(&billing)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = r.database.Collection("outbox").InsertOne(sc, message)
+		return nil, err
+	})
 
 	return err
 }
+
+// rentSortFields maps the fields rents can be sorted by to their document keys.
+var rentSortFields = map[string]string{
+	"start_date": "startdate",
+	"end_date":   "enddate",
+	"customer":   "customerid",
+	"status":     "status",
+}
+
+func filterQuery(filter RentFilter) bson.M {
+	query := bson.M{}
+
+	if filter.CustomerID != "" {
+		query["customerid"] = filter.CustomerID
+	}
+
+	if filter.CarrierID != "" {
+		query["carrierid"] = filter.CarrierID
+	}
+
+	if filter.EquipmentID != "" {
+		query["items.equipmentid"] = filter.EquipmentID
+	}
+
+	if filter.Status != "" {
+		query["status"] = filter.Status
+		if filter.Status == StatusReserved {
+			// rents saved before statuses existed have none
+			query["status"] = bson.M{"$in": bson.A{StatusReserved, nil}}
+		}
+	}
+
+	// rents with pieces out, unless asked for a given status
+	if filter.Active && filter.Status == "" {
+		query["status"] = bson.M{"$in": bson.A{StatusActive, StatusPartiallyReturned}}
+	}
+
+	// rents overlapping the period
+	if !filter.From.IsZero() {
+		query["enddate"] = bson.M{"$gte": filter.From}
+	}
+
+	if !filter.To.IsZero() {
+		query["startdate"] = bson.M{"$lte": filter.To}
+	}
+
+	if filter.Search != "" {
+		query["$text"] = bson.M{"$search": filter.Search}
+	}
+
+	if filter.Overdue {
+		query["$and"] = bson.A{
+			bson.M{"status": bson.M{"$in": bson.A{StatusActive, StatusPartiallyReturned}}},
+			bson.M{"enddate": bson.M{"$lt": time.Now()}},
+		}
+	}
+
+	return query
+}
+
+func sortQuery(sort string) bson.D {
+	order := 1
+	if strings.HasPrefix(sort, "-") {
+		order = -1
+	}
+
+	field := rentSortFields[strings.TrimPrefix(sort, "-")]
+	return bson.D{{Key: field, Value: order}, {Key: "_id", Value: order}}
+}
+
+func setItemIDs(items []*Item) {
+	for _, item := range items {
+		if item.ID == "" {
+			item.ID = primitive.NewObjectID().Hex()
+		}
+	}
+}
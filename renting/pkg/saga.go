@@ -0,0 +1,233 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type SagaStatus string
+
+const (
+	SagaRunning      SagaStatus = "running"
+	SagaCompleted    SagaStatus = "completed"
+	SagaCompensating SagaStatus = "compensating"
+	SagaCompensated  SagaStatus = "compensated"
+	SagaFailed       SagaStatus = "failed"
+)
+
+type StepStatus string
+
+const (
+	StepPending            StepStatus = "pending"
+	StepDone               StepStatus = "done"
+	StepFailed             StepStatus = "failed"
+	StepCompensated        StepStatus = "compensated"
+	StepCompensationFailed StepStatus = "compensation_failed"
+)
+
+// Saga records the progress of an operation spanning several services, like
+// reserving a rent's stock and billing it, so that the ones left halfway can
+// be found and fixed.
+type Saga struct {
+	ID        string      `json:"id" bson:"_id,omitempty"`
+	Name      string      `json:"name"`
+	RentID    string      `json:"rent_id" bson:"rent_id"`
+	Status    SagaStatus  `json:"status"`
+	Steps     []*SagaStep `json:"steps"`
+	Error     string      `json:"error"`
+	CreatedAt time.Time   `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" bson:"updated_at"`
+}
+
+type SagaStep struct {
+	Name   string     `json:"name"`
+	Status StepStatus `json:"status"`
+	Error  string     `json:"error"`
+}
+
+// IsStuck tells whether the saga needs someone to look at it, either because
+// it could not be compensated or because it has not moved since until.
+func (s *Saga) IsStuck(until time.Time) bool {
+	switch s.Status {
+	case SagaFailed:
+		return true
+	case SagaRunning, SagaCompensating:
+		return s.UpdatedAt.Before(until)
+	default:
+		return false
+	}
+}
+
+type sagaStep struct {
+	name       string
+	action     func() error
+	compensate func() error
+}
+
+// runSaga runs the steps in order, saving the saga after each one. When a
+// step fails, the ones done before it are compensated in reverse order and
+// the error of the step is returned.
+func (s *service) runSaga(saga *Saga, steps ...sagaStep) error {
+	saga.Status = SagaRunning
+	saga.Steps = make([]*SagaStep, len(steps))
+	saga.CreatedAt = time.Now()
+	saga.UpdatedAt = saga.CreatedAt
+
+	for i, step := range steps {
+		saga.Steps[i] = &SagaStep{Name: step.name, Status: StepPending}
+	}
+
+	created, err := s.repository.CreateSaga(*saga)
+	if err != nil {
+		return NewError(
+			http.StatusInternalServerError,
+			"error starting saga",
+			"could not save the saga state",
+		)
+	}
+
+	saga.ID = created.ID
+
+	for i, step := range steps {
+		if err := step.action(); err != nil {
+			saga.Steps[i].Status = StepFailed
+			saga.Steps[i].Error = err.Error()
+			saga.Error = err.Error()
+
+			s.compensate(saga, steps[:i])
+			return err
+		}
+
+		saga.Steps[i].Status = StepDone
+		s.saveSaga(saga)
+	}
+
+	saga.Status = SagaCompleted
+	s.saveSaga(saga)
+
+	return nil
+}
+
+// compensate undoes the steps already done. A step that cannot be undone
+// leaves the saga failed, but the others are still compensated.
+func (s *service) compensate(saga *Saga, steps []sagaStep) {
+	saga.Status = SagaCompensating
+	s.saveSaga(saga)
+
+	saga.Status = SagaCompensated
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i].compensate != nil {
+			if err := steps[i].compensate(); err != nil {
+				saga.Status = SagaFailed
+				saga.Steps[i].Status = StepCompensationFailed
+				saga.Steps[i].Error = err.Error()
+				continue
+			}
+		}
+		saga.Steps[i].Status = StepCompensated
+	}
+
+	s.saveSaga(saga)
+}
+
+// saveSaga stores the progress of the saga. Failing to do so does not stop
+// the saga, it would only look stuck.
+func (s *service) saveSaga(saga *Saga) {
+	saga.UpdatedAt = time.Now()
+	s.repository.UpdateSaga(saga.ID, *saga)
+}
+
+func (s *service) reduceStockStep(rent *Rent, item *Item) sagaStep {
+	items := []*Item{item}
+
+	return sagaStep{
+		name: "reduce_stock:" + item.EquipmentID,
+		action: func() error {
+			err := s.inventory.ReduceStock(rent, items)
+			if e, ok := err.(Error); ok && e.StatusCode() == http.StatusConflict {
+				return err
+			}
+
+			if err != nil {
+				return NewError(
+					http.StatusInternalServerError,
+					"error reducing stock",
+					"could not reduce the stock of "+item.EquipmentID,
+				)
+			}
+			return nil
+		},
+		compensate: func() error {
+			return s.inventory.RestoreStock(rent, items)
+		},
+	}
+}
+
+func (s *service) restoreStockStep(rent *Rent, items []*Item) sagaStep {
+	return sagaStep{
+		name: "restore_stock",
+		action: func() error {
+			if err := s.inventory.RestoreStock(rent, items); err != nil {
+				return NewError(
+					http.StatusInternalServerError,
+					"error restoring stock",
+					"could not restore the stock of the rent",
+				)
+			}
+			return nil
+		},
+		compensate: func() error {
+			return s.inventory.ReduceStock(rent, items)
+		},
+	}
+}
+
+// writeOffStep removes lost pieces from the inventory. It cannot be undone,
+// so it must be the last step of the saga.
+func (s *service) writeOffStep(rent *Rent, items []*Item) sagaStep {
+	return sagaStep{
+		name: "write_off_stock",
+		action: func() error {
+			if err := s.inventory.WriteOffStock(rent, items); err != nil {
+				return NewError(
+					http.StatusInternalServerError,
+					"error writing off stock",
+					"could not remove the lost pieces from the stock",
+				)
+			}
+			return nil
+		},
+	}
+}
+
+// updateStep saves the rent along with the event, putting back how it was
+// before when the saga is compensated.
+func (s *service) updateStep(ctx context.Context, prev *Rent, data *Rent, event, detail string) sagaStep {
+	return sagaStep{
+		name: "update_rent",
+		action: func() error {
+			rent, err := s.repository.UpdateRent(ctx, prev.ID, *data, event)
+			if err == ErrVersionConflict {
+				return errVersionConflict
+			}
+
+			if err != nil {
+				return NewError(
+					http.StatusInternalServerError,
+					"error updating rent",
+					detail,
+				)
+			}
+			*data = *rent
+			return nil
+		},
+		compensate: func() error {
+			restored := *prev
+			restored.Version = data.Version
+
+			_, err := s.repository.UpdateRent(ContextWithAction(ctx, ActionCompensate), prev.ID, restored, EventRentUpdated)
+			return err
+		},
+	}
+}
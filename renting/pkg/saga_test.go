@@ -0,0 +1,114 @@
+package pkg_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestSaga(t *testing.T) {
+	newRent := func() *pkg.Rent {
+		return &pkg.Rent{
+			ID:     "rent",
+			Status: pkg.StatusDraft,
+			Items: []*pkg.Item{
+				{ID: "first", EquipmentID: "scaffold", Equipment: &pkg.Equipment{}, Qty: 10},
+				{ID: "second", EquipmentID: "mixer", Equipment: &pkg.Equipment{}, Qty: 1},
+			},
+		}
+	}
+
+	t.Run("completes", func(t *testing.T) {
+		repository := newFakeRepository()
+		repository.rents["rent"] = newRent()
+
+		inventory := &fakeInventory{stock: make(map[string]int)}
+		svc := pkg.NewService(&fakeValidator{}, repository, nil, inventory, &fakeCredit{}, 1, time.Hour)
+
+		rent, err := svc.TransitionRent(context.Background(), "rent", pkg.StatusReserved)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if rent.GetStatus() != pkg.StatusReserved {
+			t.Errorf("expected status %s, got %s", pkg.StatusReserved, rent.GetStatus())
+		}
+
+		if inventory.stock["scaffold"] != 10 || inventory.stock["mixer"] != 1 {
+			t.Errorf("expected stock to be reduced, got %v", inventory.stock)
+		}
+
+		if len(repository.events) != 1 || repository.events[0] != pkg.EventRentUpdated {
+			t.Errorf("expected rent to be invoiced, got %v", repository.events)
+		}
+
+		if saga := repository.sagas["saga0"]; saga.Status != pkg.SagaCompleted {
+			t.Errorf("expected saga %s, got %s", pkg.SagaCompleted, saga.Status)
+		}
+	})
+
+	t.Run("compensates failed steps", func(t *testing.T) {
+		repository := newFakeRepository()
+		repository.rents["rent"] = newRent()
+
+		inventory := &fakeInventory{unavailable: "mixer", stock: make(map[string]int)}
+		svc := pkg.NewService(&fakeValidator{}, repository, nil, inventory, &fakeCredit{}, 1, time.Hour)
+
+		if _, err := svc.TransitionRent(context.Background(), "rent", pkg.StatusReserved); err == nil {
+			t.Fatal("expected error reducing stock")
+		}
+
+		if inventory.stock["scaffold"] != 0 {
+			t.Errorf("expected stock to be restored, got %d", inventory.stock["scaffold"])
+		}
+
+		if status := repository.rents["rent"].GetStatus(); status != pkg.StatusDraft {
+			t.Errorf("expected rent to stay %s, got %s", pkg.StatusDraft, status)
+		}
+
+		if len(repository.events) != 0 {
+			t.Errorf("did not expect events, got %v", repository.events)
+		}
+
+		saga := repository.sagas["saga0"]
+		if saga.Status != pkg.SagaCompensated {
+			t.Errorf("expected saga %s, got %s", pkg.SagaCompensated, saga.Status)
+		}
+
+		expected := []pkg.StepStatus{pkg.StepCompensated, pkg.StepFailed, pkg.StepPending}
+		if len(saga.Steps) != len(expected) {
+			t.Fatalf("expected %d steps, got %d", len(expected), len(saga.Steps))
+		}
+
+		for i, step := range saga.Steps {
+			if step.Status != expected[i] {
+				t.Errorf("expected step %s to be %s, got %s", step.Name, expected[i], step.Status)
+			}
+		}
+	})
+
+	t.Run("stuck sagas", func(t *testing.T) {
+		now := time.Now()
+		sagas := map[pkg.SagaStatus]bool{
+			pkg.SagaFailed:       true,
+			pkg.SagaRunning:      true,
+			pkg.SagaCompensating: true,
+			pkg.SagaCompleted:    false,
+			pkg.SagaCompensated:  false,
+		}
+
+		for status, stuck := range sagas {
+			saga := &pkg.Saga{Status: status, UpdatedAt: now.Add(-time.Hour)}
+			if saga.IsStuck(now.Add(-time.Minute)) != stuck {
+				t.Errorf("expected %s saga stuck to be %v", status, stuck)
+			}
+		}
+
+		running := &pkg.Saga{Status: pkg.SagaRunning, UpdatedAt: now}
+		if running.IsStuck(now.Add(-time.Minute)) {
+			t.Error("did not expect recent saga to be stuck")
+		}
+	})
+}
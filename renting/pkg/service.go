@@ -1,13 +1,17 @@
 package pkg
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
 type Rent struct {
 	ID                 string            `json:"id" bson:"_id,omitempty"`
+	Status             Status            `json:"status"`
 	PeriodID           string            `json:"period_id" validate:"required"`
 	PaymentMethodID    string            `json:"payment_method_id" validate:"required,payment_method"`
 	PaymentMethod      *PaymentMethod    `json:"payment_method,omitempty"`
@@ -15,31 +19,49 @@ type Rent struct {
 	PaymentCondition   *PaymentCondition `json:"payment_condition,omitempty"`
 	PaymentTypeID      string            `json:"payment_type_id" validate:"required,payment_type"`
 	PaymentType        *PaymentType      `json:"payment_type,omitempty"`
-	CarrierID          string            `json:"carrier_id" validate:"required"`
+	CarrierID          string            `json:"carrier_id" validate:"required_without=CarrierPolicy"`
+	CarrierPolicy      CarrierPolicy     `json:"carrier_policy" bson:"carrier_policy" validate:"omitempty,oneof=cheapest fastest"`
+	Quotes             []*Quote          `json:"-" bson:"quotes"`
 	CustomerID         string            `json:"customer_id" validate:"required,customer"`
 	Customer           *Customer         `json:"customer,omitempty"`
 	StartDate          time.Time         `json:"start_date" validate:"required"`
 	EndDate            time.Time         `json:"end_date" validate:"required"`
 	Items              []*Item           `json:"items" validate:"required,dive"`
-	Discount           float64           `json:"discount" validate:"omitempty,gt=0"`
-	PaidValue          float64           `json:"paid_value" validate:"omitempty,gt=0"`
-	Bill               float64           `json:"bill" validate:"required_with=PaidValue,ltefield=PaidValue"`
+	Discount           Money             `json:"discount" validate:"omitempty,gt=0"`
+	PaidValue          Money             `json:"paid_value" validate:"omitempty,gt=0"`
+	Bill               Money             `json:"bill" validate:"required_with=PaidValue,ltefield=PaidValue"`
 	Observations       string            `json:"observations"`
 	CheckInfo          string            `json:"check_info"`
-	DeliveryValue      float64           `json:"delivery_value"`
-	DeliveryAddress    string            `json:"delivery_address" validate:"required_with=CarrierID"`
+	DeliveryValue      Money             `json:"delivery_value"`
+	DeliveryAddress    string            `json:"delivery_address" validate:"required_with=CarrierID CarrierPolicy"`
 	UsageAddress       string            `json:"usage_address"`
+	Extensions         []*Extension      `json:"-" bson:"extensions"`
+	LateFeeRate        float64           `json:"-" bson:"late_fee_rate"`
+	Installments       []*Installment    `json:"-" bson:"-"`
+	Deposit            *Deposit          `json:"deposit"`
+	BillingCycle       BillingCycle      `json:"billing_cycle" bson:"billing_cycle" validate:"omitempty,oneof=monthly"`
+	BilledCycles       int               `json:"-" bson:"billed_cycles"`
+	NextBilling        time.Time         `json:"-" bson:"next_billing"`
+	CreditOverride     *CreditOverride   `json:"credit_override" bson:"credit_override,omitempty"`
+	Version            int               `json:"version"`
 }
 
 func (r *Rent) MarshalJSON() ([]byte, error) {
+	for _, item := range r.Items {
+		item.Price = r.GetItemPrice(item)
+	}
+
 	return json.Marshal(map[string]any{
 		"id":                r.ID,
+		"status":            r.GetStatus(),
 		"period":            r.PeriodID,
 		"start_date":        r.StartDate.Local(),
 		"end_date":          r.EndDate.Local(),
 		"qty_days":          r.GetQtyDays(),
 		"customer":          r.Customer,
 		"carrier":           r.CarrierID,
+		"carrier_policy":    r.CarrierPolicy,
+		"quotes":            r.Quotes,
 		"observations":      r.Observations,
 		"usage_address":     r.UsageAddress,
 		"deliver_address":   r.DeliveryAddress,
@@ -55,37 +77,220 @@ func (r *Rent) MarshalJSON() ([]byte, error) {
 		"total_weight":      r.GetTotalWeight(),
 		"total_unit_value":  r.GetTotalUnitValue(),
 		"total_pieces":      r.GetTotalPieces(),
+		"returned_qty":      r.GetReturnedPieces(),
+		"outstanding_qty":   r.GetOutstandingPieces(),
 		"payment_method":    r.PaymentMethod,
 		"payment_type":      r.PaymentType,
 		"payment_condition": r.PaymentCondition,
+		"installments":      r.Installments,
 		"items":             r.Items,
+		"overdue":           r.IsOverdue(time.Now()),
+		"late_days":         r.GetLateDays(time.Now()),
+		"late_fees":         r.GetLateFees(time.Now()),
+		"extensions":        r.Extensions,
+		"extensions_total":  r.GetExtensionsTotal(),
+		"damages_total":     r.GetDamagesTotal(),
+		"deposit":           r.Deposit,
+		"billing_cycle":     r.BillingCycle,
+		"billed_cycles":     r.BilledCycles,
+		"credit_override":   r.CreditOverride,
+		"version":           r.Version,
 	})
 }
 
+// Headers sends the version of the rent as its ETag, so that clients can send
+// it back in If-Match when updating it.
+func (r *Rent) Headers() http.Header {
+	headers := http.Header{}
+	headers.Set("ETag", fmt.Sprintf("\"%d\"", r.Version))
+	return headers
+}
+
+// GetStatus returns the rent's lifecycle status. Rents created before statuses
+// existed had their stock reduced on creation, so they are treated as reserved.
+func (r *Rent) GetStatus() Status {
+	if r.Status == "" {
+		return StatusReserved
+	}
+	return r.Status
+}
+
 func (r *Rent) GetQtyDays() int {
 	return int(r.EndDate.Sub(r.StartDate).Hours() / 24)
 }
 
-func (r *Rent) GetTotal() float64 {
-	return r.GetSubtotal() + r.DeliveryValue - r.Discount
+// GetOriginalEndDate returns the end date the rent had before being extended.
+func (r *Rent) GetOriginalEndDate() time.Time {
+	if len(r.Extensions) > 0 {
+		return r.Extensions[0].PreviousEndDate
+	}
+	return r.EndDate
+}
+
+// NewExtension prices the days between the current end date and endDate. Only
+// the pieces still out are charged, at the daily rate of the rent period.
+func (r *Rent) NewExtension(endDate time.Time) *Extension {
+	extension := &Extension{
+		PreviousEndDate: r.EndDate,
+		EndDate:         endDate,
+		QtyDays:         int(endDate.Sub(r.EndDate).Hours() / 24),
+		Items:           make([]*ExtensionItem, 0),
+	}
+
+	for _, item := range r.GetOutstandingItems() {
+		value := r.chargeDays(item, float64(item.Qty*extension.QtyDays))
+
+		extension.Value += value
+		extension.Items = append(extension.Items, &ExtensionItem{
+			ItemID: item.ID,
+			Qty:    item.Qty,
+			Value:  value,
+		})
+	}
+
+	return extension
+}
+
+// GetDailyValue returns how much a piece of the item costs per day, splitting
+// its renting value by the days of the rent period. Periods without a known
+// length are split by the original length of the rent.
+func (r *Rent) GetDailyValue(item *Item) Money {
+	return r.chargeDays(item, 1)
+}
+
+// chargeDays prices a piece of the item for the given days at the daily value,
+// rounding only the result so the rounding of each day does not add up.
+func (r *Rent) chargeDays(item *Item, days float64) Money {
+	periodDays := item.Equipment.GetPeriodDays(r.PeriodID)
+	if periodDays <= 0 {
+		periodDays = int(r.GetOriginalEndDate().Sub(r.StartDate).Hours() / 24)
+	}
+
+	if periodDays <= 0 {
+		return 0
+	}
+
+	return item.Equipment.GetRentingValue(r.PeriodID).Mul(days / float64(periodDays))
+}
+
+// IsOverdue tells whether the rent is past its end date with pieces still out.
+func (r *Rent) IsOverdue(now time.Time) bool {
+	status := r.GetStatus()
+	if status != StatusActive && status != StatusPartiallyReturned {
+		return false
+	}
+	return now.After(r.EndDate)
+}
+
+func (r *Rent) GetLateDays(now time.Time) int {
+	if !r.IsOverdue(now) {
+		return 0
+	}
+	return lateDays(r.EndDate, now)
+}
+
+// GetLateFeeRate returns the multiplier applied to the daily value of the item
+// for each day late. The equipment rate takes precedence over the rent's.
+func (r *Rent) GetLateFeeRate(item *Item) float64 {
+	if item.Equipment.LateFeeRate > 0 {
+		return item.Equipment.LateFeeRate
+	}
+	return r.LateFeeRate
+}
+
+// GetLateFees charges each piece returned after the end date for the days it
+// was late, plus the pieces still out if the rent is overdue.
+func (r *Rent) GetLateFees(now time.Time) Money {
+	total := Money(0)
+	overdue := r.IsOverdue(now)
+
+	for _, item := range r.Items {
+		days := 0
+		for _, ret := range item.Returns {
+			days += ret.Qty * lateDays(r.EndDate, ret.Date)
+		}
+
+		if overdue {
+			days += item.GetOutstandingQty() * lateDays(r.EndDate, now)
+		}
+
+		total += r.chargeDays(item, float64(days)*r.GetLateFeeRate(item))
+	}
+
+	return total
+}
+
+// lateDays counts the full days between the end of the rent and date, so
+// returns made on the last day are not charged.
+func lateDays(end, date time.Time) int {
+	if !date.After(end) {
+		return 0
+	}
+	return int(date.Sub(end).Hours() / 24)
+}
+
+func (r *Rent) GetExtensionsTotal() Money {
+	total := Money(0)
+	for _, extension := range r.Extensions {
+		total += extension.Value
+	}
+	return total
+}
+
+// GetTotal is what the rent costs, with the days it was extended by.
+func (r *Rent) GetTotal() Money {
+	return r.GetSubtotal() + r.GetExtensionsTotal() + r.DeliveryValue - r.Discount
 }
 
-func (r *Rent) GetChange() float64 {
+func (r *Rent) GetChange() Money {
 	return r.Bill - r.PaidValue
 }
 
-func (r *Rent) GetRemaining() float64 {
-	return r.GetTotal() - r.PaidValue
+func (r *Rent) GetRemaining() Money {
+	return r.GetTotal() + r.GetLateFees(time.Now()) + r.GetDamagesTotal() + r.GetDepositCharged() - r.PaidValue
 }
 
-func (r *Rent) GetSubtotal() float64 {
-	total := 0.0
+// GetDamagesTotal sums up what is charged for damaged and lost pieces.
+func (r *Rent) GetDamagesTotal() Money {
+	total := Money(0)
+	for _, item := range r.Items {
+		for _, ret := range item.Returns {
+			total += ret.Charge
+		}
+	}
+	return total
+}
+
+func (r *Rent) GetSubtotal() Money {
+	total := Money(0)
 	for _, item := range r.Items {
-		total += item.GetSubtotal(r.PeriodID)
+		total += r.GetItemSubtotal(item)
 	}
 	return total
 }
 
+// GetItemPrice returns the cheapest combination of the equipment's periods
+// covering the original length of the rent, for a single piece. Equipment
+// without period lengths is charged a single rent period.
+func (r *Rent) GetItemPrice(item *Item) *Price {
+	days := int(r.GetOriginalEndDate().Sub(r.StartDate).Hours() / 24)
+	if price := BestPrice(item.Equipment.RentingValues, days); price != nil {
+		return price
+	}
+
+	value := item.Equipment.GetRentingValue(r.PeriodID)
+	return &Price{
+		Total: value,
+		Periods: []*PeriodPrice{
+			{PeriodID: r.PeriodID, Qty: 1, Value: value},
+		},
+	}
+}
+
+func (r *Rent) GetItemSubtotal(item *Item) Money {
+	return r.GetItemPrice(item).Total.Times(item.Qty)
+}
+
 func (r *Rent) GetTotalWeight() float64 {
 	total := 0.0
 	for _, item := range r.Items {
@@ -94,10 +299,10 @@ func (r *Rent) GetTotalWeight() float64 {
 	return total
 }
 
-func (r *Rent) GetTotalUnitValue() float64 {
-	total := 0.0
+func (r *Rent) GetTotalUnitValue() Money {
+	total := Money(0)
 	for _, item := range r.Items {
-		total += float64(item.Qty) * item.Equipment.UnitValue
+		total += item.Equipment.UnitValue.Times(item.Qty)
 	}
 	return total
 }
@@ -110,21 +315,167 @@ func (r *Rent) GetTotalPieces() int {
 	return total
 }
 
+func (r *Rent) GetReturnedPieces() int {
+	total := 0
+	for _, item := range r.Items {
+		total += item.GetReturnedQty()
+	}
+	return total
+}
+
+func (r *Rent) GetOutstandingPieces() int {
+	total := 0
+	for _, item := range r.Items {
+		total += item.GetOutstandingQty()
+	}
+	return total
+}
+
+// GetOutstandingItems returns the pieces of each item that were not given
+// back yet, leaving out the items that are fully returned.
+func (r *Rent) GetOutstandingItems() []*Item {
+	items := make([]*Item, 0)
+	for _, item := range r.Items {
+		if qty := item.GetOutstandingQty(); qty > 0 {
+			items = append(items, &Item{
+				ID:          item.ID,
+				EquipmentID: item.EquipmentID,
+				Equipment:   item.Equipment,
+				Qty:         qty,
+			})
+		}
+	}
+	return items
+}
+
+func (r *Rent) GetItem(id string) *Item {
+	for _, item := range r.Items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// clone copies the rent deep enough for the copy to stay as it is while the
+// items of the rent receive returns.
+func (r *Rent) clone() *Rent {
+	clone := *r
+	clone.Items = make([]*Item, len(r.Items))
+	clone.Extensions = append([]*Extension{}, r.Extensions...)
+
+	if r.Deposit != nil {
+		deposit := *r.Deposit
+		deposit.Deductions = append([]*Deduction{}, r.Deposit.Deductions...)
+		clone.Deposit = &deposit
+	}
+
+	for i, item := range r.Items {
+		copied := *item
+		copied.Returns = append([]*Return{}, item.Returns...)
+		clone.Items[i] = &copied
+	}
+
+	return &clone
+}
+
 type Item struct {
 	ID          string     `json:"id" bson:"_id,omitempty"`
 	EquipmentID string     `json:"equipment_id" validate:"required"`
 	Equipment   *Equipment `json:"equipment"`
-	Qty         int        `json:"qty" validate:"required,gt=0,ltecsfield=Equipment.EffectiveStock"`
+	Qty         int        `json:"qty" validate:"required,gt=0"`
+	Returns     []*Return  `json:"-" bson:"returns"`
+	Price       *Price     `json:"-" bson:"-"`
+}
+
+func (i *Item) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"id":              i.ID,
+		"equipment_id":    i.EquipmentID,
+		"equipment":       i.Equipment,
+		"qty":             i.Qty,
+		"returned_qty":    i.GetReturnedQty(),
+		"outstanding_qty": i.GetOutstandingQty(),
+		"returns":         i.Returns,
+		"price":           i.Price,
+	})
+}
+
+func (i *Item) GetReturnedQty() int {
+	total := 0
+	for _, ret := range i.Returns {
+		total += ret.Qty
+	}
+	return total
 }
 
-func (i *Item) GetSubtotal(period string) float64 {
-	return float64(i.Qty) * i.Equipment.GetRentingValue(period)
+func (i *Item) GetOutstandingQty() int {
+	return i.Qty - i.GetReturnedQty()
 }
 
 func (i *Item) GetSubtotalWeight() float64 {
 	return float64(i.Qty) * i.Equipment.Weight
 }
 
+type ReturnCondition string
+
+const (
+	ConditionGood    ReturnCondition = "good"
+	ConditionDamaged ReturnCondition = "damaged"
+	ConditionLost    ReturnCondition = "lost"
+)
+
+// Return records pieces given back, or lost, by the customer. Damaged and
+// lost pieces are charged to the customer.
+type Return struct {
+	Qty       int             `json:"qty"`
+	Date      time.Time       `json:"date"`
+	Condition ReturnCondition `json:"condition"`
+	Charge    Money           `json:"charge"`
+}
+
+// ItemReturn is a return as informed by the user. Charge is the amount
+// assessed for damaged pieces, lost ones are charged at their replacement
+// value.
+type ItemReturn struct {
+	ItemID    string          `json:"item_id" validate:"required"`
+	Qty       int             `json:"qty" validate:"required,gt=0"`
+	Date      time.Time       `json:"date"`
+	Condition ReturnCondition `json:"condition" validate:"required,oneof=good damaged lost"`
+	Charge    Money           `json:"charge" validate:"omitempty,gt=0"`
+}
+
+type Extension struct {
+	PreviousEndDate time.Time        `json:"previous_end_date"`
+	EndDate         time.Time        `json:"end_date"`
+	QtyDays         int              `json:"qty_days"`
+	Value           Money            `json:"value"`
+	Items           []*ExtensionItem `json:"items"`
+	CreatedAt       time.Time        `json:"created_at"`
+}
+
+type ExtensionItem struct {
+	ItemID string `json:"item_id"`
+	Qty    int    `json:"qty"`
+	Value  Money  `json:"value"`
+}
+
+// Estimate is a budget sent to the customer before committing to a rent. It
+// is priced like a rent but holds no stock, and can be converted into a rent
+// until it expires.
+type Estimate struct {
+	ID        string    `json:"id" bson:"_id,omitempty"`
+	Rent      *Rent     `json:"rent"`
+	Total     Money     `json:"total"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+	RentID    string    `json:"rent_id" bson:"rent_id"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+func (e *Estimate) IsExpired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
+
 type PaymentType struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
@@ -144,26 +495,65 @@ type PaymentCondition struct {
 	PaymentType  *PaymentType `json:"payment_type"`
 }
 
+// Schedule splits the total, plus the condition increment, into installments
+// the same way the payment service does, so that listing rents does not ask
+// it for the installments of each one.
+func (c *PaymentCondition) Schedule(total Money, start time.Time) []*Installment {
+	offsets := c.Installments
+	if len(offsets) == 0 {
+		offsets = []int32{0}
+	}
+
+	incremented := total.Mul(1 + float64(c.Increment)/100)
+	each := incremented / Money(len(offsets))
+
+	installments := make([]*Installment, len(offsets))
+	for i, offset := range offsets {
+		value := each
+		if i == len(offsets)-1 {
+			value = incremented - each.Times(len(offsets)-1)
+		}
+
+		installments[i] = &Installment{
+			Number:  i + 1,
+			DueDate: start.AddDate(0, 0, int(offset)),
+			Value:   value,
+		}
+	}
+
+	return installments
+}
+
+type Installment struct {
+	Number  int       `json:"number"`
+	DueDate time.Time `json:"due_date"`
+	Value   Money     `json:"value"`
+}
+
 type Customer struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Email     string `json:"email" validate:"omitempty,email"`
-	CpfCnpj   string `json:"cpf_cnpj" validate:"required,cpf_cnpj"`
-	RgInscEst string `json:"rg_insc_est"`
-	Phone     string `json:"phone"`
-	Cellphone string `json:"cellphone"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Email       string `json:"email" validate:"omitempty,email"`
+	CpfCnpj     string `json:"cpf_cnpj" validate:"required,cpf_cnpj"`
+	RgInscEst   string `json:"rg_insc_est"`
+	Phone       string `json:"phone"`
+	Cellphone   string `json:"cellphone"`
+	CreditLimit Money  `json:"credit_limit"`
 }
 
 type Equipment struct {
 	ID             string          `json:"id"`
 	Description    string          `json:"description"`
 	Weight         float64         `json:"weight"`
-	UnitValue      float64         `json:"unit_value"`
+	UnitValue      Money           `json:"unit_value"`
+	ReplaceValue   Money           `json:"replace_value"`
+	Stock          int             `json:"in_stock"`
 	EffectiveStock int             `json:"effective_qty"`
+	LateFeeRate    float64         `json:"late_fee_rate"`
 	RentingValues  []*RentingValue `json:"renting_values" validate:"required,dive"`
 }
 
-func (e *Equipment) GetRentingValue(period string) float64 {
+func (e *Equipment) GetRentingValue(period string) Money {
 	for _, value := range e.RentingValues {
 		if value.PeriodID == period {
 			return value.Value
@@ -172,10 +562,20 @@ func (e *Equipment) GetRentingValue(period string) float64 {
 	return 0
 }
 
+// GetPeriodDays returns how many days the renting value of the period covers.
+func (e *Equipment) GetPeriodDays(period string) int {
+	for _, value := range e.RentingValues {
+		if value.PeriodID == period && value.Period != nil {
+			return int(value.Period.QtyDays)
+		}
+	}
+	return 0
+}
+
 type RentingValue struct {
 	PeriodID string  `json:"period_id"`
 	Period   *Period `json:"period,omitempty"`
-	Value    float64 `json:"value"`
+	Value    Money   `json:"value"`
 }
 
 type Period struct {
@@ -190,32 +590,102 @@ type Account struct {
 }
 
 type Quote struct {
-	Carrier string
-	Value   float64
+	Carrier  string  `json:"carrier"`
+	Value    Money   `json:"value"`
+	Duration float64 `json:"duration"`
+}
+
+// CarrierPolicy picks the carrier of a rent among the quotes of all carriers,
+// instead of the rent naming one.
+type CarrierPolicy string
+
+const (
+	CarrierCheapest CarrierPolicy = "cheapest"
+	CarrierFastest  CarrierPolicy = "fastest"
+)
+
+// Pick returns the best quote according to the policy, breaking ties with the
+// other criteria.
+func (p CarrierPolicy) Pick(quotes []*Quote) *Quote {
+	var best *Quote
+
+	for _, quote := range quotes {
+		if best == nil || p.isBetter(quote, best) {
+			best = quote
+		}
+	}
+
+	return best
+}
+
+func (p CarrierPolicy) isBetter(quote, best *Quote) bool {
+	if p == CarrierFastest {
+		if quote.Duration != best.Duration {
+			return quote.Duration < best.Duration
+		}
+		return quote.Value < best.Value
+	}
+
+	if quote.Value != best.Value {
+		return quote.Value < best.Value
+	}
+	return quote.Duration < best.Duration
 }
 
 type Service interface {
-	CreateRent(Rent) (*Rent, error)
-	ListRents(page, perPage int64) ([]*Rent, int64, error)
-	UpdateRent(id string, data Rent) (*Rent, error)
-	DeleteRent(id string) error
+	CreateRent(ctx context.Context, data Rent) (*Rent, error)
+	ListRents(filter RentFilter, page, perPage int64) ([]*Rent, int64, error)
+	UpdateRent(ctx context.Context, id string, data Rent) (*Rent, error)
+	DeleteRent(ctx context.Context, id string) error
 	GetRent(id string) (*Rent, error)
+	TransitionRent(ctx context.Context, id string, status Status) (*Rent, error)
+	ReturnItems(ctx context.Context, id string, returns []ItemReturn) (*Rent, error)
+	ExtendRent(ctx context.Context, id string, endDate time.Time) (*Rent, error)
+	DeductDeposit(ctx context.Context, id string, deduction Deduction) (*Rent, error)
+	RefundDeposit(ctx context.Context, id string, value Money) (*Rent, error)
+	GetRentHistory(id string) ([]*RentChange, error)
+	CreateEstimate(Rent) (*Estimate, error)
+	GetEstimate(id string) (*Estimate, error)
+	ConvertEstimate(ctx context.Context, id string, override *CreditOverride) (*Rent, error)
+	ListStuckSagas(olderThan time.Duration) ([]*Saga, error)
+	GetOccupancy(equipmentID string, from, to time.Time) (*Occupancy, error)
+	GetExposure(customerID string) (*Exposure, error)
+}
+
+type RentFilter struct {
+	CustomerID  string    `json:"customer_id"`
+	EquipmentID string    `json:"equipment_id"`
+	CarrierID   string    `json:"carrier_id"`
+	Status      Status    `json:"status"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Search      string    `json:"search"`
+	Overdue     bool      `json:"overdue"`
+	Active      bool      `json:"active"`
+	Sort        string    `json:"sort"`
 }
 
 type DeliveryService interface {
 	GetQuote(origin, dest, carrier string, items []*Item) (*Quote, error)
+	GetQuotes(origin, dest string, items []*Item) ([]*Quote, error)
 }
 
 type InventoryService interface {
-	ReduceStock(items []*Item)
-	RestoreStock(items []*Item)
+	ReduceStock(rent *Rent, items []*Item) error
+	RestoreStock(rent *Rent, items []*Item) error
+	ExtendBooking(rent *Rent) error
+	GetEquipment(id string) (*Equipment, error)
+	WriteOffStock(rent *Rent, items []*Item) error
 }
 
 type service struct {
-	validator  Validator
-	repository Repository
-	delivery   DeliveryService
-	inventory  InventoryService
+	validator        Validator
+	repository       Repository
+	delivery         DeliveryService
+	inventory        InventoryService
+	credit           CreditService
+	lateFeeRate      float64
+	estimateValidity time.Duration
 }
 
 func NewService(
@@ -223,44 +693,142 @@ func NewService(
 	repository Repository,
 	delivery DeliveryService,
 	inventory InventoryService,
+	credit CreditService,
+	lateFeeRate float64,
+	estimateValidity time.Duration,
 ) Service {
-	return &service{validator, repository, delivery, inventory}
+	return &service{validator, repository, delivery, inventory, credit, lateFeeRate, estimateValidity}
 }
 
-func (s *service) ListRents(page, perPage int64) ([]*Rent, int64, error) {
-	return s.repository.ListRents(page, perPage)
+func (s *service) ListRents(filter RentFilter, page, perPage int64) ([]*Rent, int64, error) {
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, 0, NewError(
+			http.StatusBadRequest,
+			"invalid status",
+			fmt.Sprintf("unknown status %s", filter.Status),
+		)
+	}
+
+	if _, ok := rentSortFields[strings.TrimPrefix(filter.Sort, "-")]; filter.Sort != "" && !ok {
+		return nil, 0, NewError(
+			http.StatusBadRequest,
+			"invalid sort",
+			fmt.Sprintf("rents cannot be sorted by %s", filter.Sort),
+		)
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, 0, NewError(
+			http.StatusBadRequest,
+			"invalid period",
+			"the end of the period must be after its start",
+		)
+	}
+
+	rents, total, err := s.repository.ListRents(filter, page, perPage)
+	if err != nil {
+		return nil, 0, NewError(
+			http.StatusInternalServerError,
+			"error listing rents",
+			"something went wrong listing rents",
+		)
+	}
+
+	return rents, total, nil
 }
 
-func (s *service) CreateRent(data Rent) (*Rent, error) {
-	if err := s.validator.Validate(data); err != nil {
+func (s *service) CreateRent(ctx context.Context, data Rent) (*Rent, error) {
+	if err := s.prepareRent(&data); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCredit(&data); err != nil {
 		return nil, err
 	}
 
-	if data.CarrierID != "" {
-		origin := "rua monte alegre do sul, mogi guacu, sp"
+	return s.saveRent(ctx, data)
+}
+
+// prepareRent validates the rent and quotes its delivery, everything a rent
+// goes through before being saved.
+func (s *service) prepareRent(data *Rent) error {
+	if err := s.validator.Validate(*data); err != nil {
+		return err
+	}
+
+	origin := "rua monte alegre do sul, mogi guacu, sp"
+
+	if data.CarrierPolicy != "" {
+		quotes, err := s.delivery.GetQuotes(origin, data.DeliveryAddress, data.Items)
+		if err != nil {
+			return err
+		}
+
+		quote := data.CarrierPolicy.Pick(quotes)
+		if quote == nil {
+			return NewError(
+				http.StatusUnprocessableEntity,
+				"no carrier available",
+				"none of the carriers can deliver to the address",
+			)
+		}
+
+		data.CarrierID = quote.Carrier
+		data.DeliveryValue = quote.Value
+		data.Quotes = quotes
+	} else if data.CarrierID != "" {
 		quote, err := s.delivery.GetQuote(origin, data.DeliveryAddress, data.CarrierID, data.Items)
 
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		data.DeliveryValue = quote.Value
 	}
 
-	rent, err := s.repository.CreateRent(data)
+	return nil
+}
+
+func (s *service) saveRent(ctx context.Context, data Rent) (*Rent, error) {
+	data.Status = StatusDraft
+	data.LateFeeRate = s.lateFeeRate
+
+	if data.Deposit != nil {
+		data.Deposit = &Deposit{Amount: data.Deposit.Amount, PaymentMethodID: data.Deposit.PaymentMethodID, Status: DepositHeld}
+	}
+
+	saga := &Saga{Name: "create_rent"}
+	err := s.runSaga(saga,
+		sagaStep{
+			name: "create_rent",
+			action: func() error {
+				rent, err := s.repository.CreateRent(ctx, data, EventRentCreated)
+				if err != nil {
+					return NewError(
+						http.StatusInternalServerError,
+						"error creating rent",
+						"something went wrong creating rent",
+					)
+				}
+
+				data = *rent
+				saga.RentID = rent.ID
+				return nil
+			},
+			compensate: func() error {
+				return s.repository.DeleteRent(ContextWithAction(ctx, ActionCompensate), data.ID, EventRentCancelled)
+			},
+		},
+	)
+
 	if err != nil {
-		return nil, NewError(
-			http.StatusInternalServerError,
-			"error creating rent",
-			"something went wrong creating rent",
-		)
+		return nil, err
 	}
 
-	s.inventory.ReduceStock(rent.Items)
-	return rent, nil
+	return &data, nil
 }
 
-func (s *service) UpdateRent(id string, data Rent) (*Rent, error) {
+func (s *service) UpdateRent(ctx context.Context, id string, data Rent) (*Rent, error) {
 	curr, err := s.repository.GetRent(id)
 	if err != nil {
 		return nil, NewError(
@@ -270,26 +838,59 @@ func (s *service) UpdateRent(id string, data Rent) (*Rent, error) {
 		)
 	}
 
-	s.inventory.RestoreStock(curr.Items)
+	if !curr.GetStatus().IsEditable() {
+		return nil, NewError(
+			http.StatusConflict,
+			"rent cannot be changed",
+			fmt.Sprintf("rents can only be changed while in %s", StatusDraft),
+		)
+	}
+
+	if data.Version != curr.Version {
+		return nil, errVersionConflict
+	}
 
 	if err := s.validator.Validate(data); err != nil {
 		return nil, err
 	}
 
-	rent, err := s.repository.UpdateRent(id, data)
+	data.Status = curr.GetStatus()
+	data.LateFeeRate = curr.LateFeeRate
+	data.Quotes = curr.Quotes
+	data.BilledCycles = curr.BilledCycles
+
+	// only the amount and payment method of the deposit can be changed here
+	if data.Deposit != nil {
+		data.Deposit = &Deposit{Amount: data.Deposit.Amount, PaymentMethodID: data.Deposit.PaymentMethodID, Status: DepositHeld}
+	}
+
+	// carriers are only picked by policy when creating the rent
+	if data.CarrierID == "" {
+		data.CarrierID = curr.CarrierID
+	}
+
+	// overrides are kept until another one is authorized
+	if data.CreditOverride == nil {
+		data.CreditOverride = curr.CreditOverride
+	}
+
+	if err := s.checkCredit(&data); err != nil {
+		return nil, err
+	}
+
+	err = s.runSaga(
+		&Saga{Name: "update_rent", RentID: id},
+		s.updateStep(ctx, curr, &data, EventRentUpdated, "could not update rent"),
+	)
+
 	if err != nil {
-		return nil, NewError(
-			http.StatusInternalServerError,
-			"error updating rent",
-			"could not update rent",
-		)
+		return nil, err
 	}
 
-	s.inventory.ReduceStock(rent.Items)
-	return rent, nil
+	return &data, nil
 }
 
-func (s *service) DeleteRent(id string) error {
+func (s *service) DeleteRent(ctx context.Context, id string) error {
 	rent, err := s.repository.GetRent(id)
 	if err != nil {
 		return NewError(
@@ -298,8 +899,31 @@ func (s *service) DeleteRent(id string) error {
 			"could not find rent",
 		)
 	}
-	s.inventory.RestoreStock(rent.Items)
-	return s.repository.DeleteRent(id)
+
+	if !rent.GetStatus().IsDeletable() {
+		return NewError(
+			http.StatusConflict,
+			"rent cannot be deleted",
+			"cancel the rent before deleting it",
+		)
+	}
+
+	return s.runSaga(
+		&Saga{Name: "delete_rent", RentID: id},
+		sagaStep{
+			name: "delete_rent",
+			action: func() error {
+				if err := s.repository.DeleteRent(ctx, id, EventRentCancelled); err != nil {
+					return NewError(
+						http.StatusInternalServerError,
+						"error deleting rent",
+						"could not delete rent",
+					)
+				}
+				return nil
+			},
+		},
+	)
 }
 
 func (s *service) GetRent(id string) (*Rent, error) {
@@ -313,3 +937,610 @@ func (s *service) GetRent(id string) (*Rent, error) {
 	}
 	return rent, nil
 }
+
+func (s *service) TransitionRent(ctx context.Context, id string, status Status) (*Rent, error) {
+	rent, err := s.repository.GetRent(id)
+	if err != nil {
+		return nil, NewError(
+			http.StatusNotFound,
+			"rent not found",
+			"could not find rent",
+		)
+	}
+
+	curr := rent.GetStatus()
+	if !curr.CanTransitionTo(status) {
+		return nil, NewTransitionError(curr, status)
+	}
+
+	// the customer may owe more than when the rent was drafted
+	if !curr.isOwing() && status.isOwing() {
+		if err := s.checkCredit(rent); err != nil {
+			return nil, err
+		}
+	}
+
+	prev := rent.clone()
+	steps := make([]sagaStep, 0)
+
+	if !curr.HoldsStock() && status.HoldsStock() {
+		// availability might have changed since the rent was drafted
+		if err := s.validator.Validate(*rent); err != nil {
+			return nil, err
+		}
+
+		for _, item := range rent.Items {
+			steps = append(steps, s.reduceStockStep(rent, item))
+		}
+	}
+
+	if curr.HoldsStock() && !status.HoldsStock() {
+		steps = append(steps, s.restoreStockStep(rent, rent.GetOutstandingItems()))
+	}
+
+	if status == StatusReturned {
+		// pieces not returned one by one are given back all at once
+		for _, item := range rent.Items {
+			if qty := item.GetOutstandingQty(); qty > 0 {
+				item.Returns = append(item.Returns, &Return{
+					Qty:       qty,
+					Date:      time.Now(),
+					Condition: ConditionGood,
+				})
+			}
+		}
+	}
+
+	event := EventRentUpdated
+	if status == StatusCancelled {
+		event = EventRentCancelled
+	}
+
+	rent.Status = status
+	steps = append(steps, s.updateStep(ctx, prev, rent, event, "could not change rent status"))
+
+	if err := s.runSaga(&Saga{Name: "transition_rent", RentID: id}, steps...); err != nil {
+		return nil, err
+	}
+
+	return rent, nil
+}
+
+func (s *service) ReturnItems(ctx context.Context, id string, returns []ItemReturn) (*Rent, error) {
+	rent, err := s.repository.GetRent(id)
+	if err != nil {
+		return nil, NewError(
+			http.StatusNotFound,
+			"rent not found",
+			"could not find rent",
+		)
+	}
+
+	status := rent.GetStatus()
+	if status != StatusActive && status != StatusPartiallyReturned {
+		return nil, NewError(
+			http.StatusConflict,
+			"rent cannot receive returns",
+			fmt.Sprintf("only %s or %s rents can receive returns", StatusActive, StatusPartiallyReturned),
+		)
+	}
+
+	prev := rent.clone()
+	restore := make([]*Item, 0, len(returns))
+	lost := make([]*Item, 0)
+
+	for i, ret := range returns {
+		if err := s.validator.Validate(ret); err != nil {
+			return nil, err
+		}
+
+		item := rent.GetItem(ret.ItemID)
+		if item == nil {
+			return nil, NewError(
+				http.StatusBadRequest,
+				"item not found",
+				fmt.Sprintf("returns[%d] item not found in rent", i),
+			)
+		}
+
+		if ret.Qty > item.GetOutstandingQty() {
+			return nil, NewError(
+				http.StatusBadRequest,
+				"invalid return quantity",
+				fmt.Sprintf("returns[%d] qty exceeds the %d outstanding pieces", i, item.GetOutstandingQty()),
+			)
+		}
+
+		if ret.Date.IsZero() {
+			ret.Date = time.Now()
+		}
+
+		charge := Money(0)
+		switch ret.Condition {
+		case ConditionDamaged:
+			charge = ret.Charge
+		case ConditionLost:
+			equipment, err := s.inventory.GetEquipment(item.EquipmentID)
+			if err != nil {
+				return nil, NewError(
+					http.StatusInternalServerError,
+					"error fetching equipment",
+					fmt.Sprintf("returns[%d] could not get the replacement value of the equipment", i),
+				)
+			}
+			charge = equipment.ReplaceValue.Times(ret.Qty)
+		}
+
+		item.Returns = append(item.Returns, &Return{
+			Qty:       ret.Qty,
+			Date:      ret.Date,
+			Condition: ret.Condition,
+			Charge:    charge,
+		})
+
+		// lost pieces never come back to the stock
+		returned := &Item{EquipmentID: item.EquipmentID, Qty: ret.Qty}
+		if ret.Condition == ConditionLost {
+			lost = append(lost, returned)
+		} else {
+			restore = append(restore, returned)
+		}
+	}
+
+	rent.Status = StatusPartiallyReturned
+	if rent.GetOutstandingPieces() == 0 {
+		rent.Status = StatusReturned
+	}
+
+	steps := []sagaStep{
+		s.updateStep(ctx, prev, rent, EventRentUpdated, "could not register returns"),
+		s.restoreStockStep(rent, restore),
+	}
+
+	if len(lost) > 0 {
+		steps = append(steps, s.writeOffStep(rent, lost))
+	}
+
+	if err := s.runSaga(&Saga{Name: "return_items", RentID: id}, steps...); err != nil {
+		return nil, err
+	}
+
+	return rent, nil
+}
+
+func (s *service) ExtendRent(ctx context.Context, id string, endDate time.Time) (*Rent, error) {
+	rent, err := s.repository.GetRent(id)
+	if err != nil {
+		return nil, NewError(
+			http.StatusNotFound,
+			"rent not found",
+			"could not find rent",
+		)
+	}
+
+	if !rent.GetStatus().HoldsStock() {
+		return nil, NewError(
+			http.StatusConflict,
+			"rent cannot be extended",
+			fmt.Sprintf("%s rents cannot be extended", rent.GetStatus()),
+		)
+	}
+
+	extension := rent.NewExtension(endDate)
+	if extension.QtyDays < 1 {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid end date",
+			"the new end date must be at least one day after the current one",
+		)
+	}
+
+	// only the additional days need to be available, the rent already
+	// holds its pieces until the current end date
+	period := *rent
+	period.StartDate = rent.EndDate
+	period.EndDate = endDate
+	period.Items = rent.GetOutstandingItems()
+
+	if err := s.validator.Validate(period); err != nil {
+		return nil, err
+	}
+
+	prev := rent.clone()
+	extension.CreatedAt = time.Now()
+	rent.Extensions = append(rent.Extensions, extension)
+	rent.EndDate = endDate
+
+	err = s.runSaga(
+		&Saga{Name: "extend_rent", RentID: id},
+		s.updateStep(ctx, prev, rent, EventRentUpdated, "could not extend rent"),
+		sagaStep{
+			name: "extend_booking",
+			action: func() error {
+				if err := s.inventory.ExtendBooking(rent); err != nil {
+					return NewError(
+						http.StatusInternalServerError,
+						"error extending booking",
+						"could not extend the booking of the rent",
+					)
+				}
+				return nil
+			},
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rent, nil
+}
+
+// DeductDeposit records a deduction from the deposit, like for damaged or
+// missing pieces, to be retained when the deposit is refunded.
+func (s *service) DeductDeposit(ctx context.Context, id string, deduction Deduction) (*Rent, error) {
+	rent, err := s.repository.GetRent(id)
+	if err != nil {
+		return nil, NewError(
+			http.StatusNotFound,
+			"rent not found",
+			"could not find rent",
+		)
+	}
+
+	if rent.Deposit == nil || !rent.Deposit.IsHeld() {
+		return nil, NewError(
+			http.StatusConflict,
+			"deposit not held",
+			"the rent has no deposit held to deduct from",
+		)
+	}
+
+	switch rent.GetStatus() {
+	case StatusActive, StatusPartiallyReturned, StatusReturned:
+	default:
+		return nil, NewError(
+			http.StatusConflict,
+			"deposit cannot be deducted",
+			fmt.Sprintf("%s rents cannot have deductions", rent.GetStatus()),
+		)
+	}
+
+	if err := s.validator.Validate(deduction); err != nil {
+		return nil, err
+	}
+
+	if deduction.Value > rent.Deposit.GetBalance() {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid deduction",
+			fmt.Sprintf("the deduction exceeds the %s left in the deposit", rent.Deposit.GetBalance()),
+		)
+	}
+
+	prev := rent.clone()
+	deduction.CreatedAt = time.Now()
+	rent.Deposit.Deductions = append(rent.Deposit.Deductions, &deduction)
+
+	err = s.runSaga(
+		&Saga{Name: "deduct_deposit", RentID: id},
+		s.updateStep(ctx, prev, rent, EventRentUpdated, "could not deduct deposit"),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rent, nil
+}
+
+// RefundDeposit settles the deposit of a returned rent, refunding value and
+// retaining the rest. A zero value refunds all that was not deducted.
+func (s *service) RefundDeposit(ctx context.Context, id string, value Money) (*Rent, error) {
+	rent, err := s.repository.GetRent(id)
+	if err != nil {
+		return nil, NewError(
+			http.StatusNotFound,
+			"rent not found",
+			"could not find rent",
+		)
+	}
+
+	if rent.Deposit == nil || !rent.Deposit.IsHeld() {
+		return nil, NewError(
+			http.StatusConflict,
+			"deposit not held",
+			"the rent has no deposit held to refund",
+		)
+	}
+
+	switch rent.GetStatus() {
+	case StatusReturned, StatusClosed, StatusCancelled:
+	default:
+		return nil, NewError(
+			http.StatusConflict,
+			"deposit cannot be refunded",
+			fmt.Sprintf("deposits can only be refunded once the rent is %s", StatusReturned),
+		)
+	}
+
+	balance := rent.Deposit.GetBalance()
+	if value == 0 {
+		value = balance
+	}
+
+	if value < 0 || value > balance {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid refund",
+			fmt.Sprintf("the refund must be between 0 and the %s left in the deposit", balance),
+		)
+	}
+
+	prev := rent.clone()
+	rent.Deposit.settle(value)
+
+	err = s.runSaga(
+		&Saga{Name: "refund_deposit", RentID: id},
+		s.updateStep(ctx, prev, rent, EventRentUpdated, "could not refund deposit"),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rent, nil
+}
+
+func (s *service) GetRentHistory(id string) ([]*RentChange, error) {
+	changes, err := s.repository.ListRentChanges(id)
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error listing history",
+			"something went wrong listing the rent history",
+		)
+	}
+	return changes, nil
+}
+
+// ListStuckSagas returns the sagas that failed to compensate or have not
+// moved for longer than olderThan.
+func (s *service) ListStuckSagas(olderThan time.Duration) ([]*Saga, error) {
+	sagas, err := s.repository.ListStuckSagas(time.Now().Add(-olderThan))
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error listing sagas",
+			"something went wrong listing sagas",
+		)
+	}
+	return sagas, nil
+}
+
+func (s *service) CreateEstimate(data Rent) (*Estimate, error) {
+	// Overrides are only authorized when the estimate is converted.
+	data.CreditOverride = nil
+	if err := s.prepareRent(&data); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	estimate, err := s.repository.CreateEstimate(Estimate{
+		Rent:      &data,
+		Total:     data.GetTotal(),
+		ExpiresAt: now.Add(s.estimateValidity),
+		CreatedAt: now,
+	})
+
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error creating estimate",
+			"something went wrong creating estimate",
+		)
+	}
+
+	return estimate, nil
+}
+
+func (s *service) GetEstimate(id string) (*Estimate, error) {
+	estimate, err := s.repository.GetEstimate(id)
+	if err != nil {
+		return nil, NewError(
+			http.StatusNotFound,
+			"estimate not found",
+			"could not find estimate",
+		)
+	}
+	return estimate, nil
+}
+
+// ConvertEstimate creates a rent out of the estimate. The equipment prices
+// and delivery are checked again, and the estimate is refused if its total
+// does not hold anymore, so the customer never pays other than agreed. Going
+// over the credit limit takes an override authorized on the conversion.
+func (s *service) ConvertEstimate(ctx context.Context, id string, override *CreditOverride) (*Rent, error) {
+	estimate, err := s.GetEstimate(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if estimate.RentID != "" {
+		return nil, NewError(
+			http.StatusConflict,
+			"estimate already converted",
+			fmt.Sprintf("estimate was converted into rent %s", estimate.RentID),
+		)
+	}
+
+	if estimate.IsExpired(time.Now()) {
+		return nil, NewError(
+			http.StatusConflict,
+			"estimate expired",
+			fmt.Sprintf("estimate expired on %s", estimate.ExpiresAt.Local().Format("2006-01-02")),
+		)
+	}
+
+	data := *estimate.Rent
+	data.CreditOverride = override
+	for i, item := range data.Items {
+		equipment, err := s.inventory.GetEquipment(item.EquipmentID)
+		if err != nil {
+			return nil, NewError(
+				http.StatusBadRequest,
+				"equipment not found",
+				fmt.Sprintf("Items[%d] equipment not found", i),
+			)
+		}
+		item.Equipment = equipment
+	}
+
+	if err := s.prepareRent(&data); err != nil {
+		return nil, err
+	}
+
+	if data.GetTotal() != estimate.Total {
+		return nil, NewError(
+			http.StatusConflict,
+			"estimate prices changed",
+			fmt.Sprintf("estimate total was %s but is now %s", estimate.Total, data.GetTotal()),
+		)
+	}
+
+	if err := s.checkCredit(&data); err != nil {
+		return nil, err
+	}
+
+	rent, err := s.saveRent(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate.RentID = rent.ID
+	if _, err := s.repository.UpdateEstimate(id, *estimate); err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error updating estimate",
+			"rent was created but the estimate could not be updated",
+		)
+	}
+
+	return rent, nil
+}
+
+// maxOccupancyDays limits the period of an occupancy, computed day by day.
+const maxOccupancyDays = 366
+
+// GetOccupancy shows when the pieces of the equipment are out during the
+// period, including the rents overdue since before it.
+func (s *service) GetOccupancy(equipmentID string, from, to time.Time) (*Occupancy, error) {
+	if to.Before(from) {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid period",
+			"the end of the period must be after its start",
+		)
+	}
+
+	if to.Sub(from) > maxOccupancyDays*24*time.Hour {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid period",
+			fmt.Sprintf("the period must be up to %d days long", maxOccupancyDays),
+		)
+	}
+
+	equipment, err := s.inventory.GetEquipment(equipmentID)
+	if err != nil {
+		return nil, NewError(
+			http.StatusNotFound,
+			"equipment not found",
+			"could not find the equipment you're looking for",
+		)
+	}
+
+	rents := make([]*Rent, 0)
+	seen := make(map[string]bool)
+
+	for _, filter := range []RentFilter{
+		{EquipmentID: equipmentID, From: from, To: to},
+		{EquipmentID: equipmentID, Overdue: true},
+	} {
+		found, _, err := s.repository.ListRents(filter, 0, 0)
+		if err != nil {
+			return nil, NewError(
+				http.StatusInternalServerError,
+				"error listing rents",
+				"something went wrong listing rents",
+			)
+		}
+
+		for _, rent := range found {
+			if !seen[rent.ID] {
+				seen[rent.ID] = true
+				rents = append(rents, rent)
+			}
+		}
+	}
+
+	return NewOccupancy(equipment, rents, from, to, time.Now()), nil
+}
+
+// GetExposure shows what the customer still owes against the credit limit.
+func (s *service) GetExposure(customerID string) (*Exposure, error) {
+	limit, err := s.credit.GetCreditLimit(customerID)
+	if err != nil {
+		return nil, NewError(
+			http.StatusNotFound,
+			"customer not found",
+			"could not find the customer",
+		)
+	}
+
+	rents, _, err := s.repository.ListRents(RentFilter{CustomerID: customerID}, 0, 0)
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error fetching rents",
+			"something went wrong while fetching the customer rents",
+		)
+	}
+
+	invoices, err := s.credit.ListUnpaidInvoices(customerID)
+	if err != nil {
+		return nil, NewError(
+			http.StatusInternalServerError,
+			"error fetching invoices",
+			"something went wrong while fetching the customer invoices",
+		)
+	}
+
+	return NewExposure(customerID, limit, rents, invoices), nil
+}
+
+// checkCredit refuses rents taking the customer over the credit limit, unless
+// a user authorized going over it.
+func (s *service) checkCredit(data *Rent) error {
+	exposure, err := s.GetExposure(data.CustomerID)
+	if err != nil {
+		return err
+	}
+
+	if !exposure.Exceeds(data) {
+		return nil
+	}
+
+	if data.CreditOverride == nil || data.CreditOverride.AuthorizedBy == nil {
+		return NewError(
+			http.StatusUnprocessableEntity,
+			"credit limit exceeded",
+			fmt.Sprintf(
+				"the rent of %s goes over the credit limit of %s, with %s already owed",
+				data.GetRemaining(), exposure.CreditLimit, exposure.Total,
+			),
+		)
+	}
+
+	return nil
+}
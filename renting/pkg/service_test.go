@@ -0,0 +1,229 @@
+package pkg_test
+
+import (
+	"testing"
+	"time"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestExtension(t *testing.T) {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	equipment := &pkg.Equipment{
+		RentingValues: []*pkg.RentingValue{
+			{PeriodID: "weekly", Value: pkg.NewMoney(70), Period: &pkg.Period{ID: "weekly", QtyDays: 7}},
+			{PeriodID: "monthly", Value: pkg.NewMoney(300)},
+		},
+	}
+
+	t.Run("prices the additional days", func(t *testing.T) {
+		rent := &pkg.Rent{
+			PeriodID:  "weekly",
+			StartDate: start,
+			EndDate:   start.AddDate(0, 0, 7),
+			Items: []*pkg.Item{
+				{ID: "1", Equipment: equipment, Qty: 2},
+			},
+		}
+
+		extension := rent.NewExtension(start.AddDate(0, 0, 10))
+		if extension.QtyDays != 3 {
+			t.Errorf("expected 3 days, got %d", extension.QtyDays)
+		}
+		if extension.Value != pkg.NewMoney(60) {
+			t.Errorf("expected value 60, got %s", extension.Value)
+		}
+	})
+
+	t.Run("charges only outstanding pieces", func(t *testing.T) {
+		rent := &pkg.Rent{
+			PeriodID:  "weekly",
+			StartDate: start,
+			EndDate:   start.AddDate(0, 0, 7),
+			Items: []*pkg.Item{
+				{ID: "1", Equipment: equipment, Qty: 2, Returns: []*pkg.Return{{Qty: 1}}},
+			},
+		}
+
+		extension := rent.NewExtension(start.AddDate(0, 0, 14))
+		if extension.Value != pkg.NewMoney(70) {
+			t.Errorf("expected value 70, got %s", extension.Value)
+		}
+	})
+
+	t.Run("falls back to the original rent length", func(t *testing.T) {
+		rent := &pkg.Rent{
+			PeriodID:  "monthly",
+			StartDate: start,
+			EndDate:   start.AddDate(0, 0, 30),
+			Items: []*pkg.Item{
+				{ID: "1", Equipment: equipment, Qty: 1},
+			},
+			Extensions: []*pkg.Extension{
+				{PreviousEndDate: start.AddDate(0, 0, 30)},
+			},
+		}
+
+		extension := rent.NewExtension(start.AddDate(0, 0, 45))
+		if extension.Value != pkg.NewMoney(150) {
+			t.Errorf("expected value 150, got %s", extension.Value)
+		}
+	})
+
+	t.Run("adds up to the total and the remaining", func(t *testing.T) {
+		rent := &pkg.Rent{
+			PeriodID:  "weekly",
+			StartDate: start,
+			EndDate:   start.AddDate(0, 0, 7),
+			PaidValue: pkg.NewMoney(100),
+			Items: []*pkg.Item{
+				{ID: "1", Equipment: equipment, Qty: 2},
+			},
+		}
+
+		extension := rent.NewExtension(start.AddDate(0, 0, 10))
+		rent.Extensions = append(rent.Extensions, extension)
+		rent.EndDate = extension.EndDate
+
+		if total := rent.GetTotal(); total != pkg.NewMoney(200) {
+			t.Errorf("expected total of 200, got %s", total)
+		}
+
+		if remaining := rent.GetRemaining(); remaining != pkg.NewMoney(100) {
+			t.Errorf("expected remaining 100, got %s", remaining)
+		}
+	})
+}
+
+func TestLateFees(t *testing.T) {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	now := end.AddDate(0, 0, 4)
+
+	equipment := &pkg.Equipment{
+		RentingValues: []*pkg.RentingValue{
+			{PeriodID: "weekly", Value: pkg.NewMoney(70), Period: &pkg.Period{ID: "weekly", QtyDays: 7}},
+		},
+	}
+
+	t.Run("charges outstanding pieces while overdue", func(t *testing.T) {
+		rent := &pkg.Rent{
+			Status:      pkg.StatusActive,
+			PeriodID:    "weekly",
+			StartDate:   start,
+			EndDate:     end,
+			LateFeeRate: 1,
+			Items: []*pkg.Item{
+				{ID: "1", Equipment: equipment, Qty: 2},
+			},
+		}
+
+		if !rent.IsOverdue(now) {
+			t.Error("expected rent to be overdue")
+		}
+		if fees := rent.GetLateFees(now); fees != pkg.NewMoney(80) {
+			t.Errorf("expected fees 80, got %s", fees)
+		}
+	})
+
+	t.Run("charges late returns", func(t *testing.T) {
+		rent := &pkg.Rent{
+			Status:      pkg.StatusReturned,
+			PeriodID:    "weekly",
+			StartDate:   start,
+			EndDate:     end,
+			LateFeeRate: 1,
+			Items: []*pkg.Item{
+				{ID: "1", Equipment: equipment, Qty: 2, Returns: []*pkg.Return{
+					{Qty: 1, Date: end.Add(12 * time.Hour)},
+					{Qty: 1, Date: end.AddDate(0, 0, 2)},
+				}},
+			},
+		}
+
+		if rent.IsOverdue(now) {
+			t.Error("expected returned rent not to be overdue")
+		}
+		if fees := rent.GetLateFees(now); fees != pkg.NewMoney(20) {
+			t.Errorf("expected fees 20, got %s", fees)
+		}
+	})
+
+	t.Run("equipment rate overrides the rent's", func(t *testing.T) {
+		rent := &pkg.Rent{
+			Status:      pkg.StatusActive,
+			PeriodID:    "weekly",
+			StartDate:   start,
+			EndDate:     end,
+			LateFeeRate: 1,
+			Items: []*pkg.Item{
+				{ID: "1", Equipment: &pkg.Equipment{
+					LateFeeRate:   2,
+					RentingValues: equipment.RentingValues,
+				}, Qty: 1},
+			},
+		}
+
+		if fees := rent.GetLateFees(now); fees != pkg.NewMoney(80) {
+			t.Errorf("expected fees 80, got %s", fees)
+		}
+	})
+}
+
+func TestCarrierPolicy(t *testing.T) {
+	quotes := []*pkg.Quote{
+		{Carrier: "local", Value: pkg.NewMoney(50), Duration: 3600},
+		{Carrier: "express", Value: pkg.NewMoney(80), Duration: 1800},
+		{Carrier: "slow", Value: pkg.NewMoney(50), Duration: 7200},
+	}
+
+	t.Run("cheapest", func(t *testing.T) {
+		if quote := pkg.CarrierCheapest.Pick(quotes); quote.Carrier != "local" {
+			t.Errorf("expected local, got %s", quote.Carrier)
+		}
+	})
+
+	t.Run("fastest", func(t *testing.T) {
+		if quote := pkg.CarrierFastest.Pick(quotes); quote.Carrier != "express" {
+			t.Errorf("expected express, got %s", quote.Carrier)
+		}
+	})
+
+	t.Run("no quotes", func(t *testing.T) {
+		if quote := pkg.CarrierCheapest.Pick(nil); quote != nil {
+			t.Errorf("expected no quote, got %v", quote)
+		}
+	})
+}
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("splits total with increment", func(t *testing.T) {
+		condition := &pkg.PaymentCondition{Increment: 10, Installments: []int32{30, 60}}
+		installments := condition.Schedule(pkg.NewMoney(200), start)
+
+		if len(installments) != 2 {
+			t.Fatalf("expected 2 installments, got %d", len(installments))
+		}
+
+		for i, installment := range installments {
+			if installment.Value != pkg.NewMoney(110) {
+				t.Errorf("expected installment %d to be 110, got %s", i+1, installment.Value)
+			}
+		}
+
+		if !installments[1].DueDate.Equal(start.AddDate(0, 0, 60)) {
+			t.Errorf("expected due date %v, got %v", start.AddDate(0, 0, 60), installments[1].DueDate)
+		}
+	})
+
+	t.Run("rounding remainder goes to the last installment", func(t *testing.T) {
+		condition := &pkg.PaymentCondition{Installments: []int32{30, 60, 90}}
+		installments := condition.Schedule(pkg.NewMoney(100), start)
+
+		if installments[2].Value != pkg.NewMoney(33.34) {
+			t.Errorf("expected last installment to be 33.34, got %s", installments[2].Value)
+		}
+	})
+}
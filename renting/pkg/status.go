@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type Status string
+
+const (
+	StatusDraft             Status = "draft"
+	StatusReserved          Status = "reserved"
+	StatusActive            Status = "active"
+	StatusPartiallyReturned Status = "partially_returned"
+	StatusReturned          Status = "returned"
+	StatusClosed            Status = "closed"
+	StatusCancelled         Status = "cancelled"
+)
+
+// transitions lists the statuses a rent can move to from its current one.
+// Statuses missing from the map are final.
+var transitions = map[Status][]Status{
+	StatusDraft:             {StatusReserved, StatusCancelled},
+	StatusReserved:          {StatusActive, StatusCancelled},
+	StatusActive:            {StatusReturned},
+	StatusPartiallyReturned: {StatusReturned},
+	StatusReturned:          {StatusClosed},
+}
+
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusDraft, StatusReserved, StatusActive, StatusPartiallyReturned,
+		StatusReturned, StatusClosed, StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, status := range transitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// HoldsStock tells whether rents in this status have their items taken out of
+// the inventory.
+func (s Status) HoldsStock() bool {
+	switch s {
+	case StatusReserved, StatusActive, StatusPartiallyReturned:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s Status) IsEditable() bool {
+	return s == StatusDraft
+}
+
+func (s Status) IsDeletable() bool {
+	return s == StatusDraft || s == StatusCancelled
+}
+
+func NewTransitionError(from, to Status) error {
+	return NewError(
+		http.StatusConflict,
+		"invalid status transition",
+		fmt.Sprintf("cannot change rent from %s to %s", from, to),
+	)
+}
@@ -0,0 +1,50 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestStatus(t *testing.T) {
+	t.Run("allowed transitions", func(t *testing.T) {
+		allowed := [][2]pkg.Status{
+			{pkg.StatusDraft, pkg.StatusReserved},
+			{pkg.StatusDraft, pkg.StatusCancelled},
+			{pkg.StatusReserved, pkg.StatusActive},
+			{pkg.StatusReserved, pkg.StatusCancelled},
+			{pkg.StatusActive, pkg.StatusReturned},
+			{pkg.StatusPartiallyReturned, pkg.StatusReturned},
+			{pkg.StatusReturned, pkg.StatusClosed},
+		}
+
+		for _, transition := range allowed {
+			if !transition[0].CanTransitionTo(transition[1]) {
+				t.Errorf("expected %s -> %s to be allowed", transition[0], transition[1])
+			}
+		}
+	})
+
+	t.Run("forbidden transitions", func(t *testing.T) {
+		forbidden := [][2]pkg.Status{
+			{pkg.StatusDraft, pkg.StatusActive},
+			{pkg.StatusActive, pkg.StatusCancelled},
+			{pkg.StatusReturned, pkg.StatusActive},
+			{pkg.StatusClosed, pkg.StatusDraft},
+			{pkg.StatusCancelled, pkg.StatusReserved},
+		}
+
+		for _, transition := range forbidden {
+			if transition[0].CanTransitionTo(transition[1]) {
+				t.Errorf("expected %s -> %s to be forbidden", transition[0], transition[1])
+			}
+		}
+	})
+
+	t.Run("legacy rents are reserved", func(t *testing.T) {
+		rent := &pkg.Rent{}
+		if rent.GetStatus() != pkg.StatusReserved {
+			t.Errorf("expected status %s, got %s", pkg.StatusReserved, rent.GetStatus())
+		}
+	})
+}
@@ -1,49 +1,190 @@
 package pkg
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-kit/kit/auth/jwt"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/julienschmidt/httprouter"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"reconcip.com.br/microservices/renting/proto"
 )
 
-func NewHTTPServer(endpoints Set) http.Handler {
+func NewHTTPServer(endpoints Set, contracts ContractRenderer) http.Handler {
 	router := httprouter.New()
 
+	options := httptransport.ServerBefore(
+		jwt.HTTPToContext(),
+	)
+
 	router.Handler(http.MethodPost, "/", httptransport.NewServer(
 		endpoints.Create,
 		decodeCreateRentRequest,
 		httptransport.EncodeJSONResponse,
+		options,
 	))
 
 	router.Handler(http.MethodGet, "/", httptransport.NewServer(
 		endpoints.List,
 		decodeListRequest,
 		httptransport.EncodeJSONResponse,
+		options,
 	))
 
 	router.Handler(http.MethodPut, "/:id", httptransport.NewServer(
 		endpoints.Update,
 		decodeUpdateRequest,
 		httptransport.EncodeJSONResponse,
+		options,
 	))
 
 	router.Handler(http.MethodDelete, "/:id", httptransport.NewServer(
 		endpoints.Delete,
 		URLParamDecoder("id"),
 		encodeDeleteResponse,
+		options,
 	))
 
 	router.Handler(http.MethodGet, "/:id", httptransport.NewServer(
 		endpoints.Get,
 		URLParamDecoder("id"),
 		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	router.Handler(http.MethodGet, "/:id/contract", httptransport.NewServer(
+		endpoints.Get,
+		URLParamDecoder("id"),
+		ContractEncoder(contracts),
+		options,
+	))
+
+	router.Handler(http.MethodGet, "/:id/history", httptransport.NewServer(
+		endpoints.History,
+		URLParamDecoder("id"),
+		httptransport.EncodeJSONResponse,
+		options,
 	))
 
-	return router
+	router.Handler(http.MethodPost, "/:id/returns", httptransport.NewServer(
+		endpoints.Return,
+		decodeReturnRequest,
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	router.Handler(http.MethodPost, "/:id/extend", httptransport.NewServer(
+		endpoints.Extend,
+		decodeExtendRequest,
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	router.Handler(http.MethodPost, "/:id/deposit/deductions", httptransport.NewServer(
+		endpoints.Deduct,
+		decodeDeductRequest,
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	router.Handler(http.MethodPost, "/:id/deposit/refund", httptransport.NewServer(
+		endpoints.Refund,
+		decodeRefundRequest,
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	transitions := map[string]Status{
+		"reserve": StatusReserved,
+		"deliver": StatusActive,
+		"return":  StatusReturned,
+		"close":   StatusClosed,
+		"cancel":  StatusCancelled,
+	}
+
+	for action, status := range transitions {
+		router.Handler(http.MethodPost, "/:id/"+action, httptransport.NewServer(
+			endpoints.Transition,
+			TransitionDecoder(status),
+			httptransport.EncodeJSONResponse,
+			options,
+		))
+	}
+
+	// routes starting with a static segment get a router of their own, since
+	// httprouter does not allow them next to /:id
+	static := httprouter.New()
+
+	static.Handler(http.MethodPost, "/estimates", httptransport.NewServer(
+		endpoints.CreateEstimate,
+		decodeCreateRentRequest,
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	static.Handler(http.MethodGet, "/estimates/:id", httptransport.NewServer(
+		endpoints.GetEstimate,
+		URLParamDecoder("id"),
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	static.Handler(http.MethodPost, "/estimates/:id/convert", httptransport.NewServer(
+		endpoints.ConvertEstimate,
+		decodeConvertEstimateRequest,
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	static.Handler(http.MethodGet, "/sagas/stuck", httptransport.NewServer(
+		endpoints.StuckSagas,
+		decodeStuckSagasRequest,
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	static.Handler(http.MethodGet, "/equipment/:id/occupancy", httptransport.NewServer(
+		endpoints.Occupancy,
+		decodeOccupancyRequest,
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	// calendar apps subscribing to the feed cannot send headers, so the feed
+	// token comes in the query
+	static.Handler(http.MethodGet, "/equipment/:id/occupancy.ics", httptransport.NewServer(
+		endpoints.OccupancyFeed,
+		decodeOccupancyRequest,
+		encodeOccupancyICS,
+		options,
+		httptransport.ServerBefore(queryTokenToContext),
+	))
+
+	static.Handler(http.MethodGet, "/customers/:id/exposure", httptransport.NewServer(
+		endpoints.Exposure,
+		URLParamDecoder("id"),
+		httptransport.EncodeJSONResponse,
+		options,
+	))
+
+	mux := http.NewServeMux()
+	mux.Handle("/estimates", static)
+	mux.Handle("/estimates/", static)
+	mux.Handle("/sagas/", static)
+	mux.Handle("/equipment/", static)
+	mux.Handle("/customers/", static)
+	mux.Handle("/", router)
+
+	return mux
 }
 
 func decodeCreateRentRequest(ctx context.Context, r *http.Request) (any, error) {
@@ -70,7 +211,61 @@ func decodeListRequest(ctx context.Context, r *http.Request) (any, error) {
 		perPage = 50
 	}
 
-	return Pagination{page - 1, perPage}, nil
+	filter := RentFilter{
+		CustomerID:  params.Get("customer"),
+		EquipmentID: params.Get("equipment"),
+		CarrierID:   params.Get("carrier"),
+		Status:      Status(params.Get("status")),
+		Search:      params.Get("search"),
+		Sort:        params.Get("sort"),
+	}
+
+	filter.Overdue, _ = strconv.ParseBool(params.Get("overdue"))
+	filter.Active, _ = strconv.ParseBool(params.Get("active"))
+
+	for param, date := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := params.Get(param); value != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				return nil, NewError(
+					http.StatusBadRequest,
+					"invalid date",
+					fmt.Sprintf("%s must be formatted as YYYY-MM-DD", param),
+				)
+			}
+			*date = parsed
+		}
+	}
+
+	// the whole last day is part of the period
+	if !filter.To.IsZero() {
+		filter.To = filter.To.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	return ListRequest{
+		Pagination: Pagination{page - 1, perPage},
+		Filter:     filter,
+	}, nil
+}
+
+// decodeStuckSagasRequest reads how long a saga must be idle to be taken as
+// stuck, five minutes when not given.
+func decodeStuckSagasRequest(ctx context.Context, r *http.Request) (any, error) {
+	olderThan := 5 * time.Minute
+
+	if param := r.URL.Query().Get("older_than"); param != "" {
+		duration, err := time.ParseDuration(param)
+		if err != nil {
+			return nil, NewError(
+				http.StatusBadRequest,
+				"invalid duration",
+				"older_than must be a duration like 10m",
+			)
+		}
+		olderThan = duration
+	}
+
+	return olderThan, nil
 }
 
 func decodeUpdateRequest(ctx context.Context, r *http.Request) (any, error) {
@@ -83,10 +278,30 @@ func decodeUpdateRequest(ctx context.Context, r *http.Request) (any, error) {
 		)
 	}
 
+	if version, ok := ifMatchVersion(r); ok {
+		rent.Version = version
+	}
+
 	params := httprouter.ParamsFromContext(r.Context())
 	return UpdateRequest{params.ByName("id"), rent}, nil
 }
 
+// ifMatchVersion reads the version sent in the If-Match header. Without it,
+// or with a wildcard, the version in the body is used.
+func ifMatchVersion(r *http.Request) (int, bool) {
+	etag := strings.TrimPrefix(r.Header.Get("If-Match"), "W/")
+	if etag == "" || etag == "*" {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(strings.Trim(etag, `"`))
+	if err != nil {
+		return -1, true
+	}
+
+	return version, true
+}
+
 func URLParamDecoder(param string) httptransport.DecodeRequestFunc {
 	return func(ctx context.Context, r *http.Request) (any, error) {
 		params := httprouter.ParamsFromContext(r.Context())
@@ -94,7 +309,329 @@ func URLParamDecoder(param string) httptransport.DecodeRequestFunc {
 	}
 }
 
+func decodeReturnRequest(ctx context.Context, r *http.Request) (any, error) {
+	var req ReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid input data",
+			"check your input and try again",
+		)
+	}
+
+	params := httprouter.ParamsFromContext(r.Context())
+	req.ID = params.ByName("id")
+
+	return req, nil
+}
+
+func decodeConvertEstimateRequest(ctx context.Context, r *http.Request) (any, error) {
+	var req ConvertEstimateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid input data",
+			"check your input and try again",
+		)
+	}
+
+	params := httprouter.ParamsFromContext(r.Context())
+	req.ID = params.ByName("id")
+
+	return req, nil
+}
+
+func decodeExtendRequest(ctx context.Context, r *http.Request) (any, error) {
+	var req ExtendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid input data",
+			"check your input and try again",
+		)
+	}
+
+	params := httprouter.ParamsFromContext(r.Context())
+	req.ID = params.ByName("id")
+
+	return req, nil
+}
+
+func decodeDeductRequest(ctx context.Context, r *http.Request) (any, error) {
+	var req DeductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req.Deduction); err != nil {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid input data",
+			"check your input and try again",
+		)
+	}
+
+	params := httprouter.ParamsFromContext(r.Context())
+	req.ID = params.ByName("id")
+
+	return req, nil
+}
+
+// decodeRefundRequest reads the value to refund, refunding all that is left
+// of the deposit when the body has none.
+func decodeRefundRequest(ctx context.Context, r *http.Request) (any, error) {
+	var req RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return nil, NewError(
+			http.StatusBadRequest,
+			"invalid input data",
+			"check your input and try again",
+		)
+	}
+
+	params := httprouter.ParamsFromContext(r.Context())
+	req.ID = params.ByName("id")
+
+	return req, nil
+}
+
+func TransitionDecoder(status Status) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (any, error) {
+		params := httprouter.ParamsFromContext(r.Context())
+		return TransitionRequest{params.ByName("id"), status}, nil
+	}
+}
+
+func ContractEncoder(contracts ContractRenderer) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r any) error {
+		rent := r.(*Rent)
+
+		var buf bytes.Buffer
+		if err := contracts.Render(&buf, rent); err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"contract-%s.pdf\"", rent.ID))
+
+		_, err := buf.WriteTo(w)
+		return err
+	}
+}
+
+// decodeOccupancyRequest reads the period of the occupancy, a month from
+// today when not given.
+func decodeOccupancyRequest(ctx context.Context, r *http.Request) (any, error) {
+	params := r.URL.Query()
+	now := time.Now()
+
+	req := OccupancyRequest{
+		EquipmentID: httprouter.ParamsFromContext(r.Context()).ByName("id"),
+		From:        time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local),
+	}
+
+	for param, date := range map[string]*time.Time{"from": &req.From, "to": &req.To} {
+		if value := params.Get(param); value != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				return nil, NewError(
+					http.StatusBadRequest,
+					"invalid date",
+					fmt.Sprintf("%s must be formatted as YYYY-MM-DD", param),
+				)
+			}
+			*date = parsed
+		}
+	}
+
+	if req.To.IsZero() {
+		req.To = req.From.AddDate(0, 1, 0)
+	}
+
+	// the whole last day is part of the period
+	req.To = req.To.AddDate(0, 0, 1).Add(-time.Nanosecond)
+
+	return req, nil
+}
+
+func queryTokenToContext(ctx context.Context, r *http.Request) context.Context {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return context.WithValue(ctx, jwt.JWTContextKey, token)
+	}
+	return ctx
+}
+
+func encodeOccupancyICS(ctx context.Context, w http.ResponseWriter, r any) error {
+	occupancy := r.(*Occupancy)
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"occupancy-%s.ics\"", occupancy.Equipment.ID))
+
+	return occupancy.WriteICS(w, time.Now())
+}
+
 func encodeDeleteResponse(ctx context.Context, w http.ResponseWriter, r any) error {
 	w.WriteHeader(http.StatusNoContent)
 	return nil
 }
+
+type grpcServer struct {
+	proto.UnimplementedRentingServer
+	getRent         grpctransport.Handler
+	listByCustomer  grpctransport.Handler
+	listByEquipment grpctransport.Handler
+	listActive      grpctransport.Handler
+}
+
+// NewGRPCServer exposes rents to the other services, which are trusted and
+// so not asked for a token.
+func NewGRPCServer(endpoints Set) proto.RentingServer {
+	return &grpcServer{
+		getRent: grpctransport.NewServer(
+			endpoints.Get,
+			decodeGRPCGetRequest,
+			encodeGRPCRentReply,
+		),
+		listByCustomer: grpctransport.NewServer(
+			endpoints.List,
+			decodeGRPCRentsByCustomerRequest,
+			encodeGRPCRentsReply,
+		),
+		listByEquipment: grpctransport.NewServer(
+			endpoints.List,
+			decodeGRPCRentsByEquipmentRequest,
+			encodeGRPCRentsReply,
+		),
+		listActive: grpctransport.NewServer(
+			endpoints.List,
+			decodeGRPCActiveRentsRequest,
+			encodeGRPCRentsReply,
+		),
+	}
+}
+
+func (s *grpcServer) GetRent(ctx context.Context, r *proto.GetRequest) (*proto.RentReply, error) {
+	_, reply, err := s.getRent.ServeGRPC(ctx, r)
+	if err != nil {
+		return &proto.RentReply{Err: replyError(err)}, nil
+	}
+	return reply.(*proto.RentReply), nil
+}
+
+func (s *grpcServer) ListRentsByCustomer(ctx context.Context, r *proto.RentsByCustomerRequest) (*proto.RentsReply, error) {
+	return s.serveList(ctx, s.listByCustomer, r)
+}
+
+func (s *grpcServer) ListRentsByEquipment(ctx context.Context, r *proto.RentsByEquipmentRequest) (*proto.RentsReply, error) {
+	return s.serveList(ctx, s.listByEquipment, r)
+}
+
+func (s *grpcServer) ListActiveRents(ctx context.Context, r *proto.ActiveRentsRequest) (*proto.RentsReply, error) {
+	return s.serveList(ctx, s.listActive, r)
+}
+
+func (s *grpcServer) serveList(ctx context.Context, handler grpctransport.Handler, r any) (*proto.RentsReply, error) {
+	_, reply, err := handler.ServeGRPC(ctx, r)
+	if err != nil {
+		return &proto.RentsReply{Err: replyError(err)}, nil
+	}
+	return reply.(*proto.RentsReply), nil
+}
+
+func decodeGRPCGetRequest(ctx context.Context, r any) (any, error) {
+	req := r.(*proto.GetRequest)
+	return req.GetId(), nil
+}
+
+func decodeGRPCRentsByCustomerRequest(ctx context.Context, r any) (any, error) {
+	req := r.(*proto.RentsByCustomerRequest)
+
+	return ListRequest{
+		Pagination: grpcPagination(req.GetPage(), req.GetPerPage()),
+		Filter:     RentFilter{CustomerID: req.GetCustomerId()},
+	}, nil
+}
+
+// decodeGRPCRentsByEquipmentRequest lists the rents of the equipment
+// overlapping the period, when one is given.
+func decodeGRPCRentsByEquipmentRequest(ctx context.Context, r any) (any, error) {
+	req := r.(*proto.RentsByEquipmentRequest)
+	filter := RentFilter{EquipmentID: req.GetEquipmentId()}
+
+	if req.GetFrom() != nil {
+		filter.From = req.GetFrom().AsTime()
+	}
+
+	if req.GetTo() != nil {
+		filter.To = req.GetTo().AsTime()
+	}
+
+	return ListRequest{
+		Pagination: grpcPagination(req.GetPage(), req.GetPerPage()),
+		Filter:     filter,
+	}, nil
+}
+
+func decodeGRPCActiveRentsRequest(ctx context.Context, r any) (any, error) {
+	req := r.(*proto.ActiveRentsRequest)
+
+	return ListRequest{
+		Pagination: grpcPagination(req.GetPage(), req.GetPerPage()),
+		Filter:     RentFilter{Active: true},
+	}, nil
+}
+
+// grpcPagination counts pages from one, like the HTTP API, listing 50 rents
+// a page when not told otherwise.
+func grpcPagination(page, perPage int64) Pagination {
+	if page < 1 {
+		page = 1
+	}
+
+	if perPage < 1 {
+		perPage = 50
+	}
+
+	return Pagination{page - 1, perPage}
+}
+
+func encodeGRPCRentReply(ctx context.Context, r any) (any, error) {
+	return &proto.RentReply{Rent: encodeRent(r.(*Rent))}, nil
+}
+
+func encodeGRPCRentsReply(ctx context.Context, r any) (any, error) {
+	result := r.(ListResult)
+	reply := &proto.RentsReply{
+		Rents:      make([]*proto.Rent, len(result.Items)),
+		TotalPages: result.TotalPages,
+		TotalItems: result.TotalItems,
+	}
+
+	for i, item := range result.Items {
+		reply.Rents[i] = encodeRent(item.(*Rent))
+	}
+
+	return reply, nil
+}
+
+func encodeRent(rent *Rent) *proto.Rent {
+	items := make([]*proto.RentItem, len(rent.Items))
+	for i, item := range rent.Items {
+		items[i] = &proto.RentItem{
+			Id:          item.ID,
+			EquipmentId: item.EquipmentID,
+			Qty:         int64(item.Qty),
+			ReturnedQty: int64(item.GetReturnedQty()),
+		}
+	}
+
+	return &proto.Rent{
+		Id:                 rent.ID,
+		Status:             string(rent.GetStatus()),
+		CustomerId:         rent.CustomerID,
+		CarrierId:          rent.CarrierID,
+		PaymentConditionId: rent.PaymentConditionID,
+		DeliveryAddress:    rent.DeliveryAddress,
+		StartDate:          timestamppb.New(rent.StartDate),
+		EndDate:            timestamppb.New(rent.EndDate),
+		Total:              rent.GetTotal().Float64(),
+		Remaining:          rent.GetRemaining().Float64(),
+		Items:              items,
+	}
+}
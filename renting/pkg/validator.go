@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-playground/validator"
@@ -20,6 +21,13 @@ type ValidationRule interface {
 	Valid(string) bool
 }
 
+// StructRule validates a whole struct, for rules that depend on more than
+// one field.
+type StructRule interface {
+	Struct() any
+	Validate(validator.StructLevel)
+}
+
 type ValidationError struct {
 	errors map[string]string
 }
@@ -37,12 +45,13 @@ func (e ValidationError) Error() string {
 }
 
 type validate struct {
-	rules     []ValidationRule
-	validator *validator.Validate
+	rules       []ValidationRule
+	structRules []StructRule
+	validator   *validator.Validate
 }
 
-func NewValidator(rules []ValidationRule) *validate {
-	validate := &validate{rules, validator.New()}
+func NewValidator(rules []ValidationRule, structRules ...StructRule) *validate {
+	validate := &validate{rules, structRules, validator.New()}
 	validate.registerRules()
 
 	return validate
@@ -57,6 +66,10 @@ func (v *validate) registerRules() {
 		}(rule)
 		v.validator.RegisterValidation(rule.Tag(), validationFunc)
 	}
+
+	for _, rule := range v.structRules {
+		v.validator.RegisterStructValidation(rule.Validate, rule.Struct())
+	}
 }
 
 func (v *validate) Validate(data any) error {
@@ -93,6 +106,8 @@ func getErrorMessage(error validator.FieldError) string {
 		return "invalid customer"
 	case "equipment":
 		return "invalid equipment"
+	case "availability":
+		return fmt.Sprintf("only %s available for the rent period", error.Param())
 	default:
 		return "something is not right about this field"
 	}
@@ -185,3 +200,49 @@ func (r customerRule) Valid(value string) bool {
 
 	return err == nil
 }
+
+type availabilityRule struct {
+	cc *grpc.ClientConn
+}
+
+func NewAvailabilityRule(cc *grpc.ClientConn) availabilityRule {
+	return availabilityRule{cc}
+}
+
+func (r availabilityRule) Struct() any {
+	return Rent{}
+}
+
+// Validate checks that the pieces of each equipment in the rent, summed across
+// items, are free for the whole rent period.
+func (r availabilityRule) Validate(sl validator.StructLevel) {
+	rent := sl.Current().Interface().(Rent)
+	if rent.StartDate.IsZero() || rent.EndDate.IsZero() {
+		return
+	}
+
+	requested := make(map[string]int)
+	for _, item := range rent.Items {
+		requested[item.EquipmentID] += item.Qty
+	}
+
+	endpoint := getAvailabilityEndpoint(r.cc)
+	available := make(map[string]int)
+
+	for equipmentID := range requested {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		qty, err := endpoint(ctx, AvailabilityRequest{equipmentID, rent.StartDate, rent.EndDate, rent.ID})
+		cancel()
+
+		if err == nil {
+			available[equipmentID] = qty.(int)
+		}
+	}
+
+	for i, item := range rent.Items {
+		qty := available[item.EquipmentID]
+		if requested[item.EquipmentID] > qty {
+			sl.ReportError(item.Qty, fmt.Sprintf("Items[%d].Qty", i), "Qty", "availability", strconv.Itoa(qty))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package pkg_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"reconcip.com.br/microservices/renting/pkg"
+)
+
+func TestOptimisticConcurrency(t *testing.T) {
+	t.Run("increments version", func(t *testing.T) {
+		svc, rent := newTestRent(t)
+
+		data := *rent
+		data.Discount = pkg.NewMoney(5)
+
+		updated, err := svc.UpdateRent(context.Background(), rent.ID, data)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		if updated.Version != rent.Version+1 {
+			t.Errorf("expected version %d, got %d", rent.Version+1, updated.Version)
+		}
+
+		if etag := updated.Headers().Get("ETag"); etag != `"1"` {
+			t.Errorf(`expected etag "1", got %s`, etag)
+		}
+	})
+
+	t.Run("rejects stale version", func(t *testing.T) {
+		svc, rent := newTestRent(t)
+
+		first := *rent
+		first.Discount = pkg.NewMoney(5)
+
+		if _, err := svc.UpdateRent(context.Background(), rent.ID, first); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		second := *rent
+		second.Discount = pkg.NewMoney(10)
+
+		_, err := svc.UpdateRent(context.Background(), rent.ID, second)
+		if err == nil {
+			t.Fatal("expected conflict, got nothing")
+		}
+
+		if err.(pkg.Error).StatusCode() != http.StatusPreconditionFailed {
+			t.Errorf("expected status %d, got %d", http.StatusPreconditionFailed, err.(pkg.Error).StatusCode())
+		}
+	})
+
+	t.Run("honors if-match", func(t *testing.T) {
+		svc, rent := newTestRent(t)
+		server := pkg.NewHTTPServer(pkg.CreateEndpoints(svc), nil)
+
+		put := func(ifMatch string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(rent)
+
+			req := httptest.NewRequest(http.MethodPut, "/"+rent.ID, bytes.NewReader(body))
+			req.Header.Set("If-Match", ifMatch)
+
+			res := httptest.NewRecorder()
+			server.ServeHTTP(res, req)
+
+			return res
+		}
+
+		if res := put(`"5"`); res.Code != http.StatusPreconditionFailed {
+			t.Errorf("expected status %d, got %d", http.StatusPreconditionFailed, res.Code)
+		}
+
+		res := put(`"0"`)
+		if res.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, res.Code, res.Body)
+		}
+
+		if etag := res.Header().Get("ETag"); etag != `"1"` {
+			t.Errorf(`expected etag "1", got %s`, etag)
+		}
+	})
+}